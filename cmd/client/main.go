@@ -1,19 +1,48 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 
 	"github.com/LealKevin/terminus/internal/client"
+	"github.com/LealKevin/terminus/internal/config"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
-	conn, err := client.ServerConnection()
+	cfg, err := config.LoadClient(os.Args[1:], os.Getenv, os.Stdout)
+	if errors.Is(err, config.ErrPrintConfig) || errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	theme, err := client.LoadTheme(cfg.Client.Theme)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	name, secret := cfg.Client.Name, cfg.Client.Secret
+	if name == "" {
+		name = client.DefaultName()
+	}
+	if secret == "" {
+		if secret, err = client.DefaultSecret(); err != nil {
+			fmt.Fprintln(os.Stderr, "error reading the player secret:", err)
+			os.Exit(1)
+		}
+	}
+	conn, err := client.ServerConnection(cfg.Client.ServerAddr, name, secret)
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-	p := tea.NewProgram(client.NewModel(*conn))
+	p := tea.NewProgram(client.NewModel(*conn, theme))
 	if _, err := p.Run(); err != nil {
 		fmt.Println("Error running program:", err.Error())
 		os.Exit(1)
@@ -2,55 +2,308 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
-	"math/rand"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/LealKevin/terminus/internal/app"
+	"github.com/LealKevin/terminus/internal/config"
+	"github.com/LealKevin/terminus/internal/domain"
+	"github.com/LealKevin/terminus/internal/infra/adminapi"
+	"github.com/LealKevin/terminus/internal/infra/datafile"
+	"github.com/LealKevin/terminus/internal/infra/db"
+	"github.com/LealKevin/terminus/internal/infra/mapfile"
 	"github.com/LealKevin/terminus/internal/infra/server"
 	"github.com/LealKevin/terminus/internal/infra/store"
+	"github.com/LealKevin/terminus/internal/metrics"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func main() {
+	cfg, err := config.LoadServer(os.Args[1:], os.Getenv, os.Stdout)
+	if errors.Is(err, config.ErrPrintConfig) || errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	slog.SetDefault(cfg.Log.NewLogger(os.Stderr))
+
+	playerStore, closeStore, err := openPlayerStore(cfg.Store)
+	if err != nil {
+		fatal("error opening the player store", "backend", cfg.Store.Backend, "err", err)
+	}
+	defer closeStore()
+
+	worlds, err := loadWorlds(cfg.Game)
+	if err != nil {
+		fatal("error loading worlds", "err", err)
+	}
+
+	data, err := loadData(cfg.Game, worlds)
+	if err != nil {
+		fatal("error loading game data", "err", err)
+	}
+
 	worldMemoryStore := store.NewWorldMemoryStore()
-	playerMemoryStore := store.NewPlayerMemoryStore()
+	for _, w := range worlds {
+		worldMemoryStore.SaveWorld(w)
+		slog.Info("loaded world", "world", w.ID, "name", w.Name, "width", w.Width, "height", w.Height)
+	}
 	mobMemoryStore := store.NewMobMemoryStore()
-	handler := app.NewHandler(worldMemoryStore, playerMemoryStore, mobMemoryStore)
-	server := server.NewServer(":4200", handler)
+	handler := app.NewHandler(worldMemoryStore, playerStore, mobMemoryStore)
+	handler.SpawnWorldID = cfg.Game.Worlds[0]
+	handler.WorldIDs = cfg.Game.Worlds
+	handler.AttackCooldown = cfg.Game.AttackCooldown.Std()
+	handler.TickBudget = cfg.Game.TickRate.Std()
+	handler.SaveInterval = cfg.Game.SaveInterval.Std()
+	if handler.SaveInterval > 0 && !handler.Durable() {
+		slog.Info("not saving players periodically: the store backend does not keep them across restarts", "backend", cfg.Store.Backend)
+	}
+	handler.Spawn = app.SpawnSettings{
+		MobCap:  cfg.Game.Spawn.MobCap,
+		MobType: cfg.Game.Spawn.MobType,
+		MobName: cfg.Game.Spawn.MobName,
+	}
+	data.apply(handler)
+	handler.ReloadData = func() error {
+		data, err := loadData(cfg.Game, worlds)
+		if err != nil {
+			return err
+		}
+		data.apply(handler)
+		return nil
+	}
+	if err := setupAdmin(handler, cfg.Admin); err != nil {
+		fatal("error setting up admin", "err", err)
+	}
+	seed := cfg.Game.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	handler.SetSeed(seed)
+	slog.Info("combat seed", "seed", seed)
+
+	server := server.NewServer(cfg.Server.Listen, handler)
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
-	go StartGameLoop(ctx, handler)
+	go handler.Run(ctx, cfg.Game.TickRate.Std())
+	if cfg.Server.AdminListen != "" {
+		api := adminapi.NewServer(cfg.Server.AdminListen, cfg.Admin.APIToken, handler)
+		api.Pprof = cfg.Server.Pprof
+		if !handler.Durable() {
+			slog.Info("POST /api/save answers 501: the store backend does not keep players across restarts", "backend", cfg.Store.Backend)
+		}
+		go api.Start(ctx)
+	}
+	if cfg.Server.MetricsListen != "" {
+		go serveMetrics(ctx, cfg.Server.MetricsListen)
+	}
 	server.Start(ctx)
+	if handler.Durable() {
+		if n, err := handler.SaveState(); err != nil {
+			slog.Error("error saving players on shutdown", "err", err)
+		} else {
+			slog.Info("saved players on shutdown", "count", n)
+		}
+	}
+}
+
+// openPlayerStore returns the player store of the configured backend and
+// the function releasing it. Worlds come from the map files and mobs live
+// in memory whatever the backend.
+func openPlayerStore(cfg config.StoreConfig) (domain.PlayerStore, func(), error) {
+	if cfg.Backend != config.BackendPostgres {
+		return store.NewPlayerMemoryStore(), func() {}, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	pool, err := pgxpool.New(ctx, cfg.DSN)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, nil, err
+	}
+	return store.NewCachedPlayerStore(store.NewPlayerPgStore(db.New(pool), pool)), pool.Close, nil
+}
+
+// loadWorlds reads the map directory and keeps the worlds listed in the
+// configuration, which must not have portals leading outside of that set.
+func loadWorlds(game config.GameConfig) (map[string]*domain.World, error) {
+	all, err := mapfile.LoadDir(game.MapsDir)
+	if err != nil {
+		return nil, err
+	}
+	worlds := make(map[string]*domain.World)
+	for _, id := range game.Worlds {
+		w, ok := all[id]
+		if !ok {
+			return nil, fmt.Errorf("world %q has no map file in %s", id, game.MapsDir)
+		}
+		worlds[id] = w
+	}
+	if err := mapfile.ValidatePortals(worlds); err != nil {
+		return nil, err
+	}
+	return worlds, nil
+}
+
+// gameData is what the data directory defines, loaded at start up and on
+// /reload.
+type gameData struct {
+	abilities []*domain.Ability
+	bosses    []*domain.Boss
+	items     []*domain.Item
+	quests    []*domain.Quest
+	npcs      []*domain.NPC
+}
+
+// loadData reads the data files and checks them against the worlds.
+func loadData(game config.GameConfig, worlds map[string]*domain.World) (*gameData, error) {
+	var d gameData
+	var err error
+	if d.abilities, err = datafile.LoadAbilities(game.DataDir); err != nil {
+		return nil, fmt.Errorf("loading abilities: %w", err)
+	}
+	if d.bosses, err = datafile.LoadBosses(game.DataDir); err != nil {
+		return nil, fmt.Errorf("loading bosses: %w", err)
+	}
+	if err := checkArenas(worlds, d.bosses); err != nil {
+		return nil, fmt.Errorf("loading worlds: %w", err)
+	}
+	if d.items, err = datafile.LoadItems(game.DataDir); err != nil {
+		return nil, fmt.Errorf("loading items: %w", err)
+	}
+	if d.quests, err = datafile.LoadQuests(game.DataDir, d.items); err != nil {
+		return nil, fmt.Errorf("loading quests: %w", err)
+	}
+	if d.npcs, err = datafile.LoadNPCs(game.DataDir, d.items, d.quests); err != nil {
+		return nil, fmt.Errorf("loading npcs: %w", err)
+	}
+	if err := checkNPCs(worlds, d.npcs); err != nil {
+		return nil, fmt.Errorf("loading worlds: %w", err)
+	}
+	if err := checkQuests(worlds, d.npcs, d.quests); err != nil {
+		return nil, fmt.Errorf("loading quests: %w", err)
+	}
+	return &d, nil
+}
+
+func (d *gameData) apply(h *app.Handler) {
+	h.Abilities = d.abilities
+	h.Bosses = d.bosses
+	h.Items = d.items
+	h.NPCs = d.npcs
+	h.Quests = d.quests
+	slog.Info("loaded game data", "abilities", len(d.abilities), "bosses", len(d.bosses),
+		"items", len(d.items), "npcs", len(d.npcs), "quests", len(d.quests))
+}
+
+// fatal logs msg with args as an error and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// setupAdmin hands out the configured roles and opens the audit log.
+func setupAdmin(h *app.Handler, admin config.AdminConfig) error {
+	h.Roles = make(map[string]domain.Role, len(admin.Roles))
+	for token, name := range admin.Roles {
+		role, err := domain.ParseRole(name)
+		if err != nil {
+			return fmt.Errorf("role of a login token: %w", err)
+		}
+		h.Roles[token] = role
+	}
+	h.AdminToken = admin.Token
+	if admin.AuditLog != "" {
+		f, err := os.OpenFile(admin.AuditLog, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			return fmt.Errorf("opening audit log: %w", err)
+		}
+		h.Audit = log.New(f, "", log.LstdFlags)
+	}
+	return nil
 }
 
-func StartGameLoop(ctx context.Context, h *app.Handler) {
-	ticker := time.NewTicker(time.Second * 1 / 2)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ticker.C:
-			mobsCount := h.Mobs.CountMobsInWorld("world1")
-			if mobsCount < 5 {
-				for i := 0; i < 5-mobsCount; i++ {
-					h.HandleMobSpawn(ctx, "world1", "Goblin", "Goblin")
-				}
+// checkArenas makes sure every arena is guarded by a known boss.
+func checkArenas(worlds map[string]*domain.World, bosses []*domain.Boss) error {
+	known := make(map[string]bool)
+	for _, b := range bosses {
+		known[b.Type] = true
+	}
+	for _, w := range worlds {
+		for _, a := range w.Arenas {
+			if !known[a.Boss] {
+				return fmt.Errorf("%s: arena %s: %w %q", w.ID, a.ID, domain.ErrUnknownBoss, a.Boss)
 			}
+		}
+	}
+	return nil
+}
 
-			for _, mob := range h.Mobs.GetMobsByWorld("world1") {
-				if rand.Float32() < 0.5 {
-					h.HandleMobMove(ctx, mob.ID)
-				}
+// checkNPCs makes sure every NPC placed on a map is defined.
+func checkNPCs(worlds map[string]*domain.World, npcs []*domain.NPC) error {
+	known := make(map[string]bool)
+	for _, n := range npcs {
+		known[n.ID] = true
+	}
+	for _, w := range worlds {
+		for _, n := range w.NPCs {
+			if !known[n.NPC] {
+				return fmt.Errorf("%s: %s: %w %q", w.ID, n.ID, domain.ErrUnknownNPC, n.NPC)
 			}
+		}
+	}
+	return nil
+}
 
-			err := h.BroadcastMobsUpdate("world1")
-			if err != nil {
-				log.Printf("Failed to broadcast mob update: %v", err)
+// checkQuests makes sure the quests only send players to known worlds and
+// NPCs.
+func checkQuests(worlds map[string]*domain.World, npcs []*domain.NPC, quests []*domain.Quest) error {
+	known := make(map[string]bool)
+	for _, n := range npcs {
+		known[n.ID] = true
+	}
+	for _, q := range quests {
+		for i, o := range q.Objectives {
+			switch {
+			case o.Kind == domain.ObjectiveTalk && !known[o.Target]:
+				return fmt.Errorf("quest %s: objective %d: %w %q", q.ID, i+1, domain.ErrUnknownNPC, o.Target)
+			case o.Kind == domain.ObjectiveReach && worlds[o.Target] == nil:
+				return fmt.Errorf("quest %s: objective %d: unknown world %q", q.ID, i+1, o.Target)
+			case o.Kind == domain.ObjectiveReach && !worlds[o.Target].InBounds(o.X, o.Y):
+				return fmt.Errorf("quest %s: objective %d: %d,%d is outside of %s", q.ID, i+1, o.X, o.Y, o.Target)
 			}
-		case <-ctx.Done():
-			return
 		}
 	}
+	return nil
+}
+
+// serveMetrics serves the Prometheus metrics on /metrics until ctx is done.
+func serveMetrics(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	slog.Info("metrics listening", "addr", addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("metrics server stopped", "err", err)
+	}
 }
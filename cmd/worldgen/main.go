@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/LealKevin/terminus/internal/domain"
+	"github.com/LealKevin/terminus/internal/infra/db"
+	"github.com/LealKevin/terminus/internal/infra/mapfile"
+	"github.com/LealKevin/terminus/internal/infra/store"
+	"github.com/LealKevin/terminus/internal/worldgen"
+	"github.com/jackc/pgx/v5"
+)
+
+type portalFlags []domain.Portal
+
+func (p *portalFlags) String() string {
+	return fmt.Sprint(*p)
+}
+
+// Set parses "world:x,y", the destination of a portal.
+func (p *portalFlags) Set(v string) error {
+	world, pos, ok := strings.Cut(v, ":")
+	if !ok {
+		return fmt.Errorf("expected world:x,y")
+	}
+	var x, y int
+	if _, err := fmt.Sscanf(pos, "%d,%d", &x, &y); err != nil {
+		return fmt.Errorf("expected world:x,y: %w", err)
+	}
+	*p = append(*p, domain.Portal{ToWorld: world, ToX: x, ToY: y})
+	return nil
+}
+
+func main() {
+	var (
+		opts    worldgen.Options
+		portals portalFlags
+		out     string
+		dsn     string
+	)
+	flag.StringVar(&opts.ID, "id", "", "world id, also the map file name")
+	flag.StringVar(&opts.Name, "name", "", "display name of the world")
+	flag.StringVar(&opts.Algorithm, "algo", worldgen.AlgorithmRooms, "generator: "+strings.Join(worldgen.Algorithms, ", "))
+	flag.IntVar(&opts.Width, "width", 80, "world width")
+	flag.IntVar(&opts.Height, "height", 40, "world height")
+	flag.Int64Var(&opts.Seed, "seed", time.Now().UnixNano(), "random seed")
+	flag.IntVar(&opts.SpawnPoints, "spawns", 3, "number of player spawn points")
+	flag.Var(&portals, "portal", "add a portal leading to world:x,y (repeatable)")
+	flag.StringVar(&out, "out", "", "maps directory to write <id>.map into, - for stdout")
+	flag.StringVar(&dsn, "dsn", "", "postgres connection string to save the world to")
+	flag.Parse()
+	opts.Portals = portals
+
+	if out == "" && dsn == "" {
+		out = "-"
+	}
+
+	world, err := worldgen.Generate(opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "generating world:", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "generated %s world %s (%dx%d) with seed %d\n", opts.Algorithm, world.ID, world.Width, world.Height, opts.Seed)
+
+	if out != "" {
+		if err := writeMap(out, world); err != nil {
+			fmt.Fprintln(os.Stderr, "writing map:", err)
+			os.Exit(1)
+		}
+	}
+	if dsn != "" {
+		if err := saveToPostgres(dsn, world); err != nil {
+			fmt.Fprintln(os.Stderr, "saving world:", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func writeMap(out string, world *domain.World) error {
+	if out == "-" {
+		return mapfile.Write(os.Stdout, world)
+	}
+	path := filepath.Join(out, world.ID+mapfile.Ext)
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := mapfile.Write(f, world); err != nil {
+		f.Close()
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "wrote %s\n", path)
+	return f.Close()
+}
+
+// saveToPostgres stores the layout of the world. Spawn points and portals only
+// live in map files for now.
+func saveToPostgres(dsn string, world *domain.World) error {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	worlds := store.NewWorldPgStore(db.New(conn))
+	saved, err := worlds.CreateWorld(ctx, world.ID, world.Width, world.Height, world.Layout.String())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "saved world as %s\n", saved.ID)
+	return nil
+}
@@ -0,0 +1,227 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+// ManaRegenPerTick is the mana players recover every game tick.
+const ManaRegenPerTick = 1
+
+// abilityInfo is an ability as described to clients for their hotbar.
+type abilityInfo struct {
+	ID         string       `json:"id"`
+	Name       string       `json:"name"`
+	Level      int          `json:"level"`
+	ManaCost   int          `json:"manaCost"`
+	CooldownMs int64        `json:"cooldownMs"`
+	Range      int          `json:"range"`
+	Shape      domain.Shape `json:"shape"`
+	Size       int          `json:"size"`
+}
+
+type abilitiesMsg struct {
+	Type      string        `json:"type"`
+	Abilities []abilityInfo `json:"abilities"`
+}
+
+func (h *Handler) ability(id string) *domain.Ability {
+	for _, a := range h.Abilities {
+		if a.ID == id {
+			return a
+		}
+	}
+	return nil
+}
+
+// HandleSendAbilities sends the definitions of every ability, including the
+// ones the player has not learned yet.
+func (h *Handler) HandleSendAbilities(cc *clientConn) error {
+	msg := abilitiesMsg{Type: "abilities", Abilities: []abilityInfo{}}
+	for _, a := range h.Abilities {
+		msg.Abilities = append(msg.Abilities, abilityInfo{
+			ID:         a.ID,
+			Name:       a.Name,
+			Level:      a.Level,
+			ManaCost:   a.ManaCost,
+			CooldownMs: a.Cooldown.Milliseconds(),
+			Range:      a.Range,
+			Shape:      a.Shape,
+			Size:       a.Size,
+		})
+	}
+	return cc.sendJson(msg)
+}
+
+// HandleUseAbility casts the ability abilityID. Targeted abilities are cast
+// on the mob targetID, or on the nearest one in reach when it is empty.
+// Aimed abilities are cast towards targetID when given, or in direction dir.
+func (h *Handler) HandleUseAbility(ctx context.Context, cc *clientConn, p *domain.Player, abilityID, targetID, dir string) error {
+	if p == nil {
+		return fmt.Errorf("player not found")
+	}
+	world := h.Worlds.GetWorld(p.WorldID)
+	if world == nil {
+		return fmt.Errorf("world %s not found", p.WorldID)
+	}
+	a := h.ability(abilityID)
+	if a == nil {
+		return h.sendAbilityError(cc, abilityID, domain.ErrUnknownAbility)
+	}
+	if !p.KnowsAbility(a) {
+		return h.sendAbilityError(cc, a.Name, domain.ErrAbilityLocked)
+	}
+	if a.Hostile() && world.RegionAt(p.X, p.Y) == domain.RegionSafe {
+		return h.sendAbilityError(cc, a.Name, domain.ErrSafeZone)
+	}
+
+	caster := domain.Point{X: p.X, Y: p.Y}
+	center := caster
+	var target *domain.Mob
+	if targetID != "" {
+		target = h.Mobs.GetMob(targetID)
+		if target != nil && target.WorldID != p.WorldID {
+			target = nil
+		}
+	}
+	switch {
+	case a.Targeted():
+		if target == nil && targetID == "" {
+			target = h.nearestMobInReach(world, p, a.Range)
+		}
+		if target == nil {
+			return h.sendAbilityError(cc, a.Name, domain.ErrNoAbilityTarget)
+		}
+		center = domain.Point{X: target.X, Y: target.Y}
+		if !inReach(world, p, center, a.Range) {
+			return h.sendAbilityError(cc, a.Name, domain.ErrTargetOutOfReach)
+		}
+	case a.Aimed():
+		if target != nil {
+			dir = domain.Heading(caster, domain.Point{X: target.X, Y: target.Y})
+		}
+		if _, ok := domain.Step(caster, dir); !ok {
+			return h.sendAbilityError(cc, a.Name, domain.ErrNoAbilityTarget)
+		}
+	}
+
+	unlock := h.lockPlayers(p.ID)
+	err := p.UseAbility(a, h.Clock.Now())
+	unlock()
+	if err != nil {
+		if errors.Is(err, domain.ErrAbilityCooldown) {
+			err = fmt.Errorf("%w (%.1fs)", err, p.AbilityCooldownLeft(a, h.Clock.Now()).Seconds())
+		}
+		return h.sendAbilityError(cc, a.Name, err)
+	}
+
+	cells := make(map[domain.Point]bool)
+	for _, c := range world.Area(a, caster, center, dir) {
+		cells[c] = true
+	}
+
+	var notes []string
+	for _, mob := range h.Mobs.GetMobsByWorld(world.ID) {
+		if !cells[domain.Point{X: mob.X, Y: mob.Y}] {
+			continue
+		}
+		if err := h.applyAbilityToMob(cc, world, p, a, mob, &notes); err != nil {
+			return err
+		}
+	}
+	for _, player := range h.playersInWorld(world.ID) {
+		if !cells[domain.Point{X: player.X, Y: player.Y}] {
+			continue
+		}
+		notes = append(notes, applyAbilityToPlayer(p, a, player)...)
+		if player.ID != p.ID {
+			h.Player.SavePlayer(player)
+			if other := h.connForPlayer(player.ID); other != nil {
+				other.sendJson(serverMsg{Type: "playerUpdate", Msg: p.ID + " used " + a.Name + " on you", Player: player})
+			}
+		}
+	}
+	h.Player.SavePlayer(p)
+
+	msg := "You use " + a.Name
+	if len(notes) > 0 {
+		msg += ": " + strings.Join(notes, ", ")
+	}
+	return cc.sendJson(serverMsg{Type: "playerUpdate", Msg: msg, Player: p})
+}
+
+// applyAbilityToMob applies the hostile effects of a to mob, reporting hits
+// and kills to the caster. Other outcomes are appended to notes.
+func (h *Handler) applyAbilityToMob(cc *clientConn, world *domain.World, p *domain.Player, a *domain.Ability, mob *domain.Mob, notes *[]string) error {
+	for _, e := range a.Effects {
+		if !e.Hostile() {
+			continue
+		}
+		switch e.Kind {
+		case domain.EffectDamage:
+			damage := mob.TakeDamage(e.Amount)
+			if err := cc.sendJson(playerHitMob(mob, a.Name, domain.AttackHit, damage)); err != nil {
+				return err
+			}
+		case domain.EffectKnockback:
+			dir := domain.Heading(domain.Point{X: p.X, Y: p.Y}, domain.Point{X: mob.X, Y: mob.Y})
+			if n := mob.Push(dir, e.Amount, world, h.getOccupiedPositions(world.ID)); n > 0 {
+				*notes = append(*notes, fmt.Sprintf("%s is knocked back %d", mob.Name, n))
+			}
+		case domain.EffectStatus:
+			mob.Statuses.Apply(domain.Status{Kind: e.Status, Ticks: e.Ticks, Power: e.Power})
+			*notes = append(*notes, fmt.Sprintf("%s is afflicted with %s", mob.Name, e.Status))
+		}
+		if !mob.IsAlive() {
+			*notes = append(*notes, h.killMob(p, mob))
+			return nil
+		}
+	}
+	mob.Provoke(p.ID)
+	h.Mobs.SaveMob(mob)
+	return nil
+}
+
+// applyAbilityToPlayer applies the friendly effects of a cast by caster to
+// player and describes them.
+func applyAbilityToPlayer(caster *domain.Player, a *domain.Ability, player *domain.Player) []string {
+	who := player.ID
+	if player.ID == caster.ID {
+		who = "you"
+	}
+	var notes []string
+	for _, e := range a.Effects {
+		if e.Hostile() {
+			continue
+		}
+		switch e.Kind {
+		case domain.EffectHeal:
+			notes = append(notes, fmt.Sprintf("healed %s for %d", who, player.Heal(e.Amount)))
+		case domain.EffectStatus:
+			player.Statuses.Apply(domain.Status{Kind: e.Status, Ticks: e.Ticks, Power: e.Power})
+			notes = append(notes, fmt.Sprintf("%s gained %s", who, e.Status))
+		}
+	}
+	return notes
+}
+
+func (h *Handler) sendAbilityError(cc *clientConn, name string, err error) error {
+	return cc.sendJson(serverMsg{Type: "error", Msg: fmt.Sprintf("%s: %v", name, err)})
+}
+
+// RegenMana restores ManaRegenPerTick mana to every player in the world.
+func (h *Handler) RegenMana(ctx context.Context, worldID string) {
+	for _, player := range h.playersInWorld(worldID) {
+		if !player.RegenMana(ManaRegenPerTick) {
+			continue
+		}
+		h.Player.SavePlayer(player)
+		if cc := h.connForPlayer(player.ID); cc != nil {
+			cc.sendJson(serverMsg{Type: "playerUpdate", Player: player})
+		}
+	}
+}
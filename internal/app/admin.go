@@ -0,0 +1,367 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+// MaxSpawnCount is the most mobs a single /spawn brings in.
+const MaxSpawnCount = 10
+
+// adminCommand is a slash command run server side by players holding at
+// least role.
+type adminCommand struct {
+	role    domain.Role
+	usage   string
+	minArgs int
+	// run carries out the command and returns what to tell the caller.
+	run func(h *Handler, ctx context.Context, cc *clientConn, p *domain.Player, args []string) (string, error)
+}
+
+var adminCommands = map[string]adminCommand{
+	"kick":     {role: domain.RoleModerator, usage: "/kick <player> [reason]", minArgs: 1, run: (*Handler).adminKick},
+	"ban":      {role: domain.RoleModerator, usage: "/ban <player> [reason]", minArgs: 1, run: (*Handler).adminBan},
+	"tp":       {role: domain.RoleModerator, usage: "/tp [player] <player | x,y [world]>", minArgs: 1, run: (*Handler).adminTeleport},
+	"heal":     {role: domain.RoleModerator, usage: "/heal [player]", run: (*Handler).adminHeal},
+	"announce": {role: domain.RoleModerator, usage: "/announce <message>", minArgs: 1, run: (*Handler).adminAnnounce},
+	"spawn":    {role: domain.RoleAdmin, usage: "/spawn <mob type> [count]", minArgs: 1, run: (*Handler).adminSpawn},
+	"reload":   {role: domain.RoleAdmin, usage: "/reload", run: (*Handler).adminReload},
+	"role":     {role: domain.RoleAdmin, usage: "/role <player> <player|moderator|admin>", minArgs: 2, run: (*Handler).adminRole},
+}
+
+// HandleCommand runs the slash command line typed by the player, checking
+// their role first. Every attempt is recorded in the audit log.
+func (h *Handler) HandleCommand(ctx context.Context, cc *clientConn, p *domain.Player, line string) error {
+	if p == nil {
+		return fmt.Errorf("player not found")
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return cc.sendJson(serverMsg{Type: "error", Msg: "empty command"})
+	}
+	name, args := strings.TrimPrefix(fields[0], "/"), fields[1:]
+	if name == "login" {
+		return h.login(cc, p, args)
+	}
+
+	cmd, ok := adminCommands[name]
+	if !ok {
+		return cc.sendJson(serverMsg{Type: "error", Msg: fmt.Sprintf("unknown command /%s", name)})
+	}
+	if !p.Role.Allows(cmd.role) {
+		h.audit(p, line, "denied")
+		return cc.sendJson(serverMsg{Type: "error", Msg: fmt.Sprintf("/%s needs the %s role", name, cmd.role)})
+	}
+	if len(args) < cmd.minArgs {
+		return cc.sendJson(serverMsg{Type: "error", Msg: "Usage: " + cmd.usage})
+	}
+
+	msg, err := cmd.run(h, ctx, cc, p, args)
+	if err != nil {
+		h.audit(p, line, "failed: "+err.Error())
+		return cc.sendJson(serverMsg{Type: "error", Msg: err.Error()})
+	}
+	h.audit(p, line, msg)
+	return cc.sendJson(serverMsg{Type: "success", Msg: msg})
+}
+
+// audit records that p ran the command line and how it went.
+func (h *Handler) audit(p *domain.Player, line, outcome string) {
+	role := p.Role
+	if role == "" {
+		role = domain.RolePlayer
+	}
+	h.Audit.Printf("%s (%s) %q: %s", p.ID, role, line, outcome)
+}
+
+// login gives the player the role of the token they know: admin for the
+// admin token, or the role Roles gives the token. The token never makes it
+// to the audit log.
+func (h *Handler) login(cc *clientConn, p *domain.Player, args []string) error {
+	if len(args) != 1 {
+		return cc.sendJson(serverMsg{Type: "error", Msg: "Usage: /login <token>"})
+	}
+	role, ok := h.tokenRole(args[0])
+	if !ok {
+		h.audit(p, "/login", "failed: wrong token")
+		return cc.sendJson(serverMsg{Type: "error", Msg: "wrong token"})
+	}
+	p.Role = role
+	h.Player.SavePlayer(p)
+	h.audit(p, "/login", "logged in as "+string(role))
+	return cc.sendJson(serverMsg{Type: "playerUpdate", Msg: "You are now " + string(role), Player: p})
+}
+
+// tokenRole returns the role token logs in as. Every token is compared in
+// constant time, so how long it takes does not tell which one came close.
+func (h *Handler) tokenRole(token string) (domain.Role, bool) {
+	var role domain.Role
+	found := false
+	try := func(secret string, r domain.Role) {
+		if secret != "" && subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1 && !found {
+			role, found = r, true
+		}
+	}
+	try(h.AdminToken, domain.RoleAdmin)
+	for secret, r := range h.Roles {
+		try(secret, r)
+	}
+	return role, found
+}
+
+// onlinePlayer returns the connected player id and their connection.
+func (h *Handler) onlinePlayer(id string) (*domain.Player, *clientConn, error) {
+	conn := h.connForPlayer(id)
+	p := h.Player.GetPlayer(id)
+	if conn == nil || p == nil {
+		return nil, nil, fmt.Errorf("no player %q online", id)
+	}
+	return p, conn, nil
+}
+
+func (h *Handler) adminKick(ctx context.Context, cc *clientConn, p *domain.Player, args []string) (string, error) {
+	if err := h.Kick(args[0], "You were kicked by "+p.ID+reason(args[1:])); err != nil {
+		return "", err
+	}
+	return "Kicked " + args[0], nil
+}
+
+func (h *Handler) adminBan(ctx context.Context, cc *clientConn, p *domain.Player, args []string) (string, error) {
+	_, conn, err := h.onlinePlayer(args[0])
+	if err != nil {
+		return "", err
+	}
+	host := remoteHost(conn.conn.RemoteAddr())
+	h.banMu.Lock()
+	h.bans[host] = args[0] + reason(args[1:])
+	h.banMu.Unlock()
+	h.kick(conn, "You were banned by "+p.ID+reason(args[1:]))
+	return fmt.Sprintf("Banned %s (%s)", args[0], host), nil
+}
+
+// kick tells the player why and closes their connection, which ends their
+// session like any disconnection.
+func (h *Handler) kick(conn *clientConn, why string) {
+	conn.sendJson(serverMsg{Type: "error", Msg: why})
+	conn.conn.Close()
+}
+
+// banned returns why connections from addr are refused, if they are.
+func (h *Handler) banned(addr net.Addr) (string, bool) {
+	h.banMu.Lock()
+	defer h.banMu.Unlock()
+	why, ok := h.bans[remoteHost(addr)]
+	return why, ok
+}
+
+func remoteHost(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+func reason(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	return ": " + strings.Join(words, " ")
+}
+
+// adminTeleport moves the caller, or the player named first, to another
+// player or to x,y of their world or the world named last.
+func (h *Handler) adminTeleport(ctx context.Context, cc *clientConn, p *domain.Player, args []string) (string, error) {
+	subject, subjectConn := p, cc
+	if _, _, err := parseCell(args[0]); err != nil && len(args) > 1 {
+		other, conn, err := h.onlinePlayer(args[0])
+		if err != nil {
+			return "", err
+		}
+		subject, subjectConn, args = other, conn, args[1:]
+	}
+
+	worldID := subject.WorldID
+	x, y, err := parseCell(args[0])
+	switch {
+	case err == nil && len(args) > 1:
+		worldID = args[1]
+	case err != nil:
+		dest, _, err := h.onlinePlayer(args[0])
+		if err != nil {
+			return "", err
+		}
+		worldID, x, y = dest.WorldID, dest.X, dest.Y
+	}
+
+	world := h.Worlds.GetWorld(worldID)
+	if world == nil {
+		return "", fmt.Errorf("no world %q", worldID)
+	}
+	x, y, err = h.freeCellNear(world, x, y)
+	if err != nil {
+		return "", err
+	}
+	if err := h.teleport(subjectConn, subject, world, x, y); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Teleported %s to (%d, %d) in %s", subject.ID, x, y, world.ID), nil
+}
+
+// parseCell reads coordinates written "x,y".
+func parseCell(s string) (int, int, error) {
+	xs, ys, ok := strings.Cut(s, ",")
+	x, errX := strconv.Atoi(xs)
+	y, errY := strconv.Atoi(ys)
+	if !ok || errX != nil || errY != nil {
+		return 0, 0, fmt.Errorf("%q is not x,y", s)
+	}
+	return x, y, nil
+}
+
+// freeCellNear returns the walkable cell free of players, mobs and NPCs
+// closest to x,y, looking up to 3 cells away.
+func (h *Handler) freeCellNear(world *domain.World, x, y int) (int, int, error) {
+	occupied := h.getOccupiedPositions(world.ID)
+	for _, player := range h.playersInWorld(world.ID) {
+		occupied[fmt.Sprintf("%d,%d", player.X, player.Y)] = true
+	}
+	for r := 0; r <= 3; r++ {
+		for dy := -r; dy <= r; dy++ {
+			for dx := -r; dx <= r; dx++ {
+				if max(abs(dx), abs(dy)) != r {
+					continue
+				}
+				cx, cy := x+dx, y+dy
+				if !world.InBounds(cx, cy) || occupied[fmt.Sprintf("%d,%d", cx, cy)] || world.NPCAt(cx, cy) != nil {
+					continue
+				}
+				if t := world.TileAt(cx, cy); t.Walkable && !t.IsHazard() {
+					return cx, cy, nil
+				}
+			}
+		}
+	}
+	return 0, 0, fmt.Errorf("no free cell near (%d, %d) in %s", x, y, world.ID)
+}
+
+// teleport puts the player on x,y of world, sending them the world first
+// when they change worlds. A trade in progress is cancelled as when walking
+// away.
+func (h *Handler) teleport(cc *clientConn, player *domain.Player, world *domain.World, x, y int) error {
+	h.cancelTrade(player.ID, player.ID+" was teleported")
+	changed := player.WorldID != world.ID
+	player.WorldID, player.X, player.Y = world.ID, x, y
+	h.Player.SavePlayer(player)
+	h.Log.Info("player teleported", "player", player.ID, "x", x, "y", y, "world", world.ID)
+
+	h.see(player, world)
+	if changed {
+		if err := cc.sendJson(serverMsg{Type: "world", World: exploredWorld(world, player)}); err != nil {
+			return err
+		}
+	}
+	if err := cc.sendJson(serverMsg{Type: "playerUpdate", Msg: "You were teleported", Player: player}); err != nil {
+		return err
+	}
+	return h.sendView(cc, player, world)
+}
+
+func (h *Handler) adminHeal(ctx context.Context, cc *clientConn, p *domain.Player, args []string) (string, error) {
+	target, conn := p, cc
+	if len(args) > 0 {
+		var err error
+		if target, conn, err = h.onlinePlayer(args[0]); err != nil {
+			return "", err
+		}
+	}
+	if !target.IsAlive() {
+		return "", fmt.Errorf("%s is dead", target.ID)
+	}
+	target.Health, target.Mana = target.MaxHealth, target.MaxMana
+	target.Statuses = nil
+	h.Player.SavePlayer(target)
+	msg := ""
+	if target != p {
+		msg = "You were healed by " + p.ID
+	}
+	conn.sendJson(serverMsg{Type: "playerUpdate", Msg: msg, Player: target})
+	return "Healed " + target.ID, nil
+}
+
+func (h *Handler) adminAnnounce(ctx context.Context, cc *clientConn, p *domain.Player, args []string) (string, error) {
+	n := h.Announce(strings.Join(args, " "))
+	return fmt.Sprintf("Announced to %d players", n), nil
+}
+
+// adminSpawn brings mobs of a built-in type, or bosses with their stats
+// but outside of any arena, around the caller.
+func (h *Handler) adminSpawn(ctx context.Context, cc *clientConn, p *domain.Player, args []string) (string, error) {
+	mobType := args[0]
+	boss := h.boss(mobType)
+	if boss == nil && !domain.KnownMobType(mobType) {
+		return "", fmt.Errorf("unknown mob type %q", mobType)
+	}
+	count := 1
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 || n > MaxSpawnCount {
+			return "", fmt.Errorf("count must be between 1 and %d", MaxSpawnCount)
+		}
+		count = n
+	}
+	world := h.Worlds.GetWorld(p.WorldID)
+	if world == nil {
+		return "", fmt.Errorf("world %s not found", p.WorldID)
+	}
+
+	zone := domain.SpawnZone{MobType: mobType, MinX: p.X - 3, MinY: p.Y - 3, MaxX: p.X + 3, MaxY: p.Y + 3}
+	spawned := 0
+	for i := 0; i < count; i++ {
+		mobID := fmt.Sprintf("mob-%d-%d", h.Mobs.CountMobsInWorld(world.ID)+1, h.rand(world.ID).Intn(10000))
+		mob, err := world.SpawnMobInZone(zone, mobType, mobID, h.getOccupiedPositions(world.ID), h.rand(world.ID))
+		if err != nil {
+			break
+		}
+		if boss != nil {
+			mob = world.SpawnBoss(domain.Arena{Spawn: domain.Point{X: mob.X, Y: mob.Y}}, boss, mobID)
+		}
+		h.Mobs.CreateMob(mob)
+		spawned++
+	}
+	if spawned == 0 {
+		return "", fmt.Errorf("no room to spawn %s here", mobType)
+	}
+	return fmt.Sprintf("Spawned %d %s", spawned, mobType), nil
+}
+
+func (h *Handler) adminReload(ctx context.Context, cc *clientConn, p *domain.Player, args []string) (string, error) {
+	if h.ReloadData == nil {
+		return "", fmt.Errorf("this server cannot reload its data")
+	}
+	if err := h.ReloadData(); err != nil {
+		return "", fmt.Errorf("reload failed, keeping the current data: %w", err)
+	}
+	return "Reloaded the game data", nil
+}
+
+func (h *Handler) adminRole(ctx context.Context, cc *clientConn, p *domain.Player, args []string) (string, error) {
+	role, err := domain.ParseRole(args[1])
+	if err != nil {
+		return "", err
+	}
+	target, conn, err := h.onlinePlayer(args[0])
+	if err != nil {
+		return "", err
+	}
+	target.Role = role
+	h.Player.SavePlayer(target)
+	conn.sendJson(serverMsg{Type: "playerUpdate", Msg: fmt.Sprintf("%s made you %s", p.ID, role), Player: target})
+	return fmt.Sprintf("%s is now %s", target.ID, role), nil
+}
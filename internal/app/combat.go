@@ -0,0 +1,223 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+// Kinds of combat events.
+const (
+	combatHit   = "hit"
+	combatMiss  = "miss"
+	combatCrit  = "crit"
+	combatKill  = "kill"
+	combatDeath = "death"
+)
+
+// combatEvent reports one attack to a player involved in it. Health and
+// MaxHealth are the target's after the attack, so clients can show it
+// without waiting for the next update.
+type combatEvent struct {
+	Type      string `json:"type"`
+	Kind      string `json:"kind"`
+	Msg       string `json:"msg"`
+	Attacker  string `json:"attacker"`
+	Target    string `json:"target"`
+	TargetID  string `json:"targetID"`
+	Damage    int    `json:"damage"`
+	Health    int    `json:"health"`
+	MaxHealth int    `json:"maxHealth"`
+}
+
+func newCombatEvent(kind, attacker, target, targetID string, damage, health, maxHealth int) combatEvent {
+	return combatEvent{
+		Type:      "combatEvent",
+		Kind:      kind,
+		Attacker:  attacker,
+		Target:    target,
+		TargetID:  targetID,
+		Damage:    damage,
+		Health:    health,
+		MaxHealth: maxHealth,
+	}
+}
+
+// playerHitMob builds the event for an attack of the player on mob, made
+// with the named ability or with a basic attack when ability is empty.
+func playerHitMob(mob *domain.Mob, ability string, roll domain.AttackRoll, damage int) combatEvent {
+	ev := newCombatEvent(roll.String(), "You", mob.Name, mob.ID, damage, mob.Health, mob.MaxHealth)
+	subject, hit, miss, kill, glance := "You", "hit", "miss", "kill", "Your attack glances"
+	if ability != "" {
+		subject, hit, miss, kill = "Your "+ability, "hits", "misses", "kills"
+		glance = subject + " glances"
+	}
+	if roll == domain.AttackCrit {
+		hit = "critically " + hit
+	}
+	switch {
+	case roll == domain.AttackMiss:
+		ev.Msg = fmt.Sprintf("%s %s %s", subject, miss, mob.Name)
+	case !mob.IsAlive():
+		ev.Kind = combatKill
+		ev.Msg = fmt.Sprintf("%s %s %s for %d and %s it", subject, hit, mob.Name, damage, kill)
+	case damage == 0:
+		ev.Kind = combatMiss
+		ev.Msg = fmt.Sprintf("%s off %s", glance, mob.Name)
+	default:
+		ev.Msg = fmt.Sprintf("%s %s %s for %d", subject, hit, mob.Name, damage)
+	}
+	return ev
+}
+
+// killMob removes a mob the player killed and shares its experience, gold
+// and loot with the party members nearby, counting it towards the quests of
+// each. It returns the message describing the killer's reward; the killer
+// is saved by the caller, the other members here.
+func (h *Handler) killMob(p *domain.Player, mob *domain.Mob) string {
+	h.Mobs.DeleteMob(mob.ID)
+	sharers := h.killSharers(p, mob)
+	xp, gold := domain.Split(mob.XPReward(), mob.GoldReward(), len(sharers))
+
+	var loot *domain.Item
+	var looter *domain.Player
+	if id, ok := mob.Loot(); ok {
+		if loot = h.item(id); loot != nil {
+			looter = sharers[h.nextLooter(p.ID, len(sharers))]
+		}
+	}
+
+	var msg string
+	for _, member := range sharers {
+		unlock := h.lockPlayers(member.ID)
+		member.Gold += gold
+		if looter == member {
+			if member.Inventory == nil {
+				member.Inventory = make(map[string]int)
+			}
+			member.Inventory[loot.ID]++
+		}
+		unlock()
+
+		reward := fmt.Sprintf("+%d XP, +%d gold", xp, gold)
+		if looter == member {
+			reward += ", " + loot.Name
+		} else if looter != nil && member == p {
+			reward += fmt.Sprintf(", %s goes to %s", loot.Name, looter.ID)
+		}
+		h.questEvent(member, domain.QuestEvent{Kind: domain.ObjectiveKill, Target: mob.Type})
+		if member.GainXP(xp) {
+			reward += fmt.Sprintf(", you reached level %d!", member.Level)
+		}
+		if member == p {
+			msg = reward
+			continue
+		}
+
+		h.Player.SavePlayer(member)
+		if cc := h.connForPlayer(member.ID); cc != nil {
+			cc.sendJson(serverMsg{
+				Type:   "playerUpdate",
+				Msg:    fmt.Sprintf("%s killed %s: %s", p.ID, mob.Name, reward),
+				Player: member,
+			})
+		}
+	}
+	return msg
+}
+
+// nearestMobInReach returns the closest mob within reach of the player, or
+// nil when there is none.
+func (h *Handler) nearestMobInReach(world *domain.World, p *domain.Player, reach int) *domain.Mob {
+	var nearest *domain.Mob
+	minDist := reach + 1
+	for _, mob := range h.Mobs.GetMobsByWorld(world.ID) {
+		dist := abs(p.X-mob.X) + abs(p.Y-mob.Y)
+		if dist < minDist && inReach(world, p, domain.Point{X: mob.X, Y: mob.Y}, reach) {
+			minDist = dist
+			nearest = mob
+		}
+	}
+	return nearest
+}
+
+// inReach reports whether to is within reach of the player and in their line
+// of sight, with the same Manhattan distance as attacks.
+func inReach(world *domain.World, p *domain.Player, to domain.Point, reach int) bool {
+	if abs(p.X-to.X)+abs(p.Y-to.Y) > reach {
+		return false
+	}
+	return world.CanSee(domain.Point{X: p.X, Y: p.Y}, to)
+}
+
+// HandleMobAttack makes mob attack a living player next to it, if any, and
+// reports whether it did. Hits inflict the mob's on-hit status effect and
+// players killed by the attack respawn. Stunned mobs and mobs fleeing or
+// heading home do not attack, and players in safe zones cannot be attacked.
+func (h *Handler) HandleMobAttack(ctx context.Context, mob *domain.Mob) bool {
+	var target *domain.Player
+	if mob.Stunned() || !mob.Engaged() {
+		return false
+	}
+	world := h.Worlds.GetWorld(mob.WorldID)
+	if world == nil {
+		return false
+	}
+	for _, player := range h.playersInWorld(mob.WorldID) {
+		if player.IsAlive() && mob.Adjacent(player) && world.RegionAt(player.X, player.Y) != domain.RegionSafe {
+			target = player
+			break
+		}
+	}
+	if target == nil {
+		return false
+	}
+
+	roll := h.rollAttack(mob.WorldID)
+	damage := mob.AttackTarget(target, roll)
+	onHit, _ := mob.OnHit()
+	h.mobHitPlayer(mob.Name, "", mob.WorldID, target, roll, damage, onHit)
+	return true
+}
+
+// mobHitPlayer reports an attack of the mob named attacker on target, made
+// with weapon or in melee when weapon is empty. A damaging hit inflicts
+// onHit when its kind is set, and a killed player respawns.
+func (h *Handler) mobHitPlayer(attacker, weapon, worldID string, target *domain.Player, roll domain.AttackRoll, damage int, onHit domain.Status) {
+	ev := newCombatEvent(roll.String(), attacker, "you", target.ID, damage, target.Health, target.MaxHealth)
+	subject, verb, glance := attacker, "hits", attacker+"'s attack"
+	if weapon != "" {
+		subject = attacker + "'s " + weapon
+		glance = subject
+	}
+	if roll == domain.AttackCrit {
+		verb = "critically hits"
+	}
+	switch {
+	case roll == domain.AttackMiss:
+		ev.Msg = fmt.Sprintf("%s misses you", subject)
+	case !target.IsAlive():
+		ev.Kind = combatDeath
+		ev.Msg = fmt.Sprintf("%s %s you for %d, you died", subject, verb, damage)
+		if world := h.Worlds.GetWorld(worldID); world != nil {
+			h.respawnPlayer(world, target)
+		}
+		h.Log.Info("player killed", "player", target.ID, "by", attacker, "world", worldID)
+	case damage == 0:
+		ev.Kind = combatMiss
+		ev.Msg = fmt.Sprintf("%s glances off you", glance)
+	default:
+		ev.Msg = fmt.Sprintf("%s %s you for %d", subject, verb, damage)
+		if onHit.Kind != "" {
+			target.Statuses.Apply(onHit)
+			ev.Msg += fmt.Sprintf(" and inflicts %s", onHit.Kind)
+		}
+	}
+	h.Player.SavePlayer(target)
+
+	if cc := h.connForPlayer(target.ID); cc != nil {
+		cc.sendJson(ev)
+		cc.sendJson(serverMsg{Type: "playerUpdate", Player: target})
+	}
+}
@@ -0,0 +1,272 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+// encounter is the state of the boss fight of an arena. Encounters are only
+// touched by the game loop.
+type encounter struct {
+	arena domain.Arena
+	boss  *domain.Boss
+	// brain keeps the boss to the arena.
+	brain domain.Brain
+	// bossID is the boss mob, empty while the boss is dead and respawnIn
+	// ticks away from coming back.
+	bossID    string
+	respawnIn int
+	active    bool
+	// phase is the current phase, ticks the ticks spent in it and casts
+	// the abilities cast during it, which take turns.
+	phase int
+	ticks int
+	casts int
+	// adds are the mobs the boss called in.
+	adds []string
+	// locked holds the original glyphs of the doors locked by the fight.
+	locked map[domain.Point]byte
+}
+
+func (h *Handler) boss(bossType string) *domain.Boss {
+	for _, b := range h.Bosses {
+		if b.Type == bossType {
+			return b
+		}
+	}
+	return nil
+}
+
+// TickEncounters runs the boss fights of the world's arenas: bosses spawn
+// and respawn, fights start when a player enters an arena, which then locks,
+// move through the boss's phases as it loses health, and reset when every
+// player inside has left or died.
+func (h *Handler) TickEncounters(ctx context.Context, worldID string) {
+	world := h.Worlds.GetWorld(worldID)
+	if world == nil {
+		return
+	}
+	for _, arena := range world.Arenas {
+		enc := h.encounters[arena.ID]
+		if enc == nil {
+			boss := h.boss(arena.Boss)
+			if boss == nil {
+				continue
+			}
+			enc = &encounter{arena: arena, boss: boss, brain: boss.Brain(arena), locked: make(map[domain.Point]byte)}
+			h.encounters[arena.ID] = enc
+		}
+		h.tickEncounter(world, enc)
+	}
+}
+
+func (h *Handler) tickEncounter(world *domain.World, enc *encounter) {
+	if enc.bossID == "" {
+		if enc.respawnIn > 0 {
+			enc.respawnIn--
+			return
+		}
+		h.spawnBoss(world, enc)
+		return
+	}
+
+	boss := h.Mobs.GetMob(enc.bossID)
+	if boss == nil {
+		h.endEncounter(world, enc, enc.boss.Name+" has been defeated!")
+		h.Log.Info("boss defeated", "boss", enc.boss.Type, "arena", enc.arena.ID, "world", world.ID)
+		enc.bossID, enc.respawnIn = "", enc.boss.RespawnTicks
+		return
+	}
+
+	inside := h.playersInArena(world, enc.arena)
+	if !enc.active {
+		if len(inside) == 0 {
+			return
+		}
+		enc.active = true
+		h.announce(world, enc.arena, enc.boss.Phases[0].Announce)
+		h.Log.Info("encounter started", "boss", enc.boss.Type, "arena", enc.arena.ID, "world", world.ID)
+	}
+	if len(inside) == 0 {
+		h.endEncounter(world, enc, "The fight against "+enc.boss.Name+" resets")
+		h.resetBoss(world, enc, boss)
+		h.Log.Info("encounter reset", "boss", enc.boss.Type, "arena", enc.arena.ID, "world", world.ID)
+		return
+	}
+	h.lockArena(world, enc)
+
+	if phase := enc.boss.PhaseFor(boss.Health, boss.MaxHealth); phase > enc.phase {
+		for p := enc.phase + 1; p <= phase; p++ {
+			h.announce(world, enc.arena, enc.boss.Phases[p].Announce)
+			h.spawnAdds(world, enc, enc.boss.Phases[p].Adds, inside)
+		}
+		enc.phase, enc.ticks, enc.casts = phase, 0, 0
+	}
+
+	enc.ticks++
+	ph := enc.boss.Phases[enc.phase]
+	if ph.CastEvery > 0 && enc.ticks%ph.CastEvery == 0 && !boss.Stunned() {
+		a := enc.boss.Abilities[ph.Abilities[enc.casts%len(ph.Abilities)]]
+		enc.casts++
+		h.castBossAbility(world, enc.arena, boss, a, inside)
+	}
+}
+
+func (h *Handler) spawnBoss(world *domain.World, enc *encounter) {
+	mob := world.SpawnBoss(enc.arena, enc.boss, fmt.Sprintf("boss-%s-%d", enc.arena.ID, h.rand(world.ID).Intn(10000)))
+	h.Mobs.CreateMob(mob)
+	enc.bossID = mob.ID
+	h.Log.Info("spawned boss", "mob", mob.ID, "x", mob.X, "y", mob.Y, "arena", enc.arena.ID, "world", world.ID)
+}
+
+// brainFor returns the brain of mob: the one of its encounter for the boss
+// of an arena, the one of its type otherwise.
+func (h *Handler) brainFor(mob *domain.Mob) domain.Brain {
+	if enc := h.encounters[mob.Zone]; enc != nil && enc.bossID == mob.ID {
+		return enc.brain
+	}
+	return domain.BrainFor(mob.Type)
+}
+
+// endEncounter unlocks the arena, dismisses the adds and announces why the
+// fight is over.
+func (h *Handler) endEncounter(world *domain.World, enc *encounter, msg string) {
+	for p, glyph := range enc.locked {
+		world.SetGlyph(p.X, p.Y, glyph)
+		h.broadcastTile(world, p.X, p.Y)
+	}
+	clear(enc.locked)
+	for _, id := range enc.adds {
+		h.Mobs.DeleteMob(id)
+	}
+	enc.adds = nil
+	enc.active = false
+	enc.phase, enc.ticks, enc.casts = 0, 0, 0
+	h.broadcastToWorld(world.ID, serverMsg{Type: "success", Msg: msg}, nil)
+}
+
+// resetBoss puts the boss back on its spawn cell in full health, as if the
+// fight never happened.
+func (h *Handler) resetBoss(world *domain.World, enc *encounter, boss *domain.Mob) {
+	boss.Health = boss.MaxHealth
+	boss.Statuses = nil
+	boss.State, boss.TargetID = domain.MobIdle, ""
+	if !h.getOccupiedPositions(world.ID)[fmt.Sprintf("%d,%d", enc.arena.Spawn.X, enc.arena.Spawn.Y)] {
+		boss.X, boss.Y = enc.arena.Spawn.X, enc.arena.Spawn.Y
+	}
+	h.Mobs.SaveMob(boss)
+}
+
+// lockArena locks the doors of the arena, leaving the ones someone stands
+// in for a later tick.
+func (h *Handler) lockArena(world *domain.World, enc *encounter) {
+	occupied := h.getOccupiedPositions(world.ID)
+	for _, player := range h.playersInWorld(world.ID) {
+		occupied[fmt.Sprintf("%d,%d", player.X, player.Y)] = true
+	}
+	for _, door := range enc.arena.Doors(world) {
+		if _, ok := enc.locked[door]; ok || world.TileAt(door.X, door.Y).Locked {
+			continue
+		}
+		if occupied[fmt.Sprintf("%d,%d", door.X, door.Y)] {
+			continue
+		}
+		enc.locked[door] = world.SetGlyph(door.X, door.Y, domain.TileLockedDoor)
+		h.broadcastTile(world, door.X, door.Y)
+	}
+}
+
+func (h *Handler) playersInArena(world *domain.World, arena domain.Arena) []*domain.Player {
+	var inside []*domain.Player
+	for _, player := range h.playersInWorld(world.ID) {
+		if player.IsAlive() && arena.Contains(player.X, player.Y) {
+			inside = append(inside, player)
+		}
+	}
+	return inside
+}
+
+// announce tells the players in the arena about the fight.
+func (h *Handler) announce(world *domain.World, arena domain.Arena, msg string) {
+	if msg == "" {
+		return
+	}
+	h.broadcastToWorld(world.ID, serverMsg{Type: "success", Msg: msg}, func(p *domain.Player) bool {
+		return arena.Contains(p.X, p.Y)
+	})
+}
+
+// spawnAdds brings the adds of a phase into the arena, already after the
+// players inside.
+func (h *Handler) spawnAdds(world *domain.World, enc *encounter, adds []domain.BossAdd, inside []*domain.Player) {
+	a := enc.arena
+	for _, add := range adds {
+		zone := domain.SpawnZone{ID: a.ID, MobType: add.MobType, MinX: a.MinX, MinY: a.MinY, MaxX: a.MaxX, MaxY: a.MaxY}
+		for i := 0; i < add.Count; i++ {
+			mobID := fmt.Sprintf("mob-%d-%d", h.Mobs.CountMobsInWorld(world.ID)+1, h.rand(world.ID).Intn(10000))
+			mob, err := world.SpawnMobInZone(zone, add.MobType, mobID, h.getOccupiedPositions(world.ID), h.rand(world.ID))
+			if err != nil {
+				h.Log.Warn("failed to spawn add", "arena", a.ID, "world", world.ID, "err", err)
+				continue
+			}
+			mob.Provoke(inside[i%len(inside)].ID)
+			h.Mobs.CreateMob(mob)
+			enc.adds = append(enc.adds, mob.ID)
+		}
+	}
+}
+
+// castBossAbility makes the boss cast a at the nearest player inside the
+// arena. Hostile effects hit the players in its area and the others apply
+// to the boss itself. Knockbacks only push mobs and are ignored.
+func (h *Handler) castBossAbility(world *domain.World, arena domain.Arena, boss *domain.Mob, a *domain.Ability, inside []*domain.Player) {
+	caster := domain.Point{X: boss.X, Y: boss.Y}
+	center, dir := caster, ""
+	if a.Targeted() || a.Aimed() {
+		var target *domain.Player
+		for _, player := range inside {
+			to := domain.Point{X: player.X, Y: player.Y}
+			if world.CanSee(caster, to) && (target == nil || abs(to.X-caster.X)+abs(to.Y-caster.Y) < abs(target.X-caster.X)+abs(target.Y-caster.Y)) {
+				target = player
+			}
+		}
+		if target == nil {
+			return
+		}
+		center = domain.Point{X: target.X, Y: target.Y}
+		dir = domain.Heading(caster, center)
+	}
+	h.announce(world, arena, fmt.Sprintf("%s casts %s", boss.Name, a.Name))
+
+	var onHit domain.Status
+	for _, e := range a.Effects {
+		switch {
+		case e.Hostile() && e.Kind == domain.EffectStatus && onHit.Kind == "":
+			onHit = domain.Status{Kind: e.Status, Ticks: e.Ticks, Power: e.Power}
+		case e.Kind == domain.EffectHeal:
+			boss.Health = min(boss.MaxHealth, boss.Health+e.Amount)
+		case e.Kind == domain.EffectStatus && !e.Hostile():
+			boss.Statuses.Apply(domain.Status{Kind: e.Status, Ticks: e.Ticks, Power: e.Power})
+		}
+	}
+	h.Mobs.SaveMob(boss)
+
+	cells := make(map[domain.Point]bool)
+	for _, c := range world.Area(a, caster, center, dir) {
+		cells[c] = true
+	}
+	for _, player := range inside {
+		if !cells[domain.Point{X: player.X, Y: player.Y}] {
+			continue
+		}
+		damage := 0
+		for _, e := range a.Effects {
+			if e.Kind == domain.EffectDamage {
+				damage += player.TakeDamage(e.Amount)
+			}
+		}
+		h.mobHitPlayer(boss.Name, a.Name, world.ID, player, domain.AttackHit, damage, onHit)
+	}
+}
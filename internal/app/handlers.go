@@ -4,11 +4,15 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
-	"math/rand"
+	"log/slog"
 	"net"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/LealKevin/terminus/internal/domain"
 )
@@ -19,6 +23,11 @@ type clientConn struct {
 	enc      *json.Encoder
 	mu       sync.Mutex
 	playerID string
+	// since is when the connection was opened.
+	since time.Time
+	// log carries the remote address of the connection, and the player id
+	// once they joined.
+	log *slog.Logger
 }
 
 type ClientMsg struct {
@@ -26,6 +35,16 @@ type ClientMsg struct {
 	Type      string `json:"type"`
 	Message   string `json:"message"`
 	Direction string `json:"direction"`
+	// Target is the id of the mob or player an attack is aimed at, or of
+	// the mob a shot is aimed at, empty attacking the nearest one, or the
+	// id of the player a party, trade or duel command names.
+	Target string `json:"target,omitempty"`
+	// Count is how many of an item, or how much gold, a trade offer puts
+	// up.
+	Count int `json:"count,omitempty"`
+	// Secret proves a join message comes from the player named in
+	// PlayerID.
+	Secret string `json:"secret,omitempty"`
 }
 
 type Handler struct {
@@ -33,24 +52,153 @@ type Handler struct {
 	Player domain.PlayerStore
 	Mobs   domain.MobStore
 
+	// SpawnWorldID is the world new connections are placed in, and
+	// WorldIDs the worlds loaded, in configuration order.
+	SpawnWorldID string
+	WorldIDs     []string
+	// AttackCooldown is the minimum time between two attacks of a player.
+	AttackCooldown time.Duration
+	// Abilities are the abilities players can learn, in hotbar order.
+	Abilities []*domain.Ability
+	// Bosses are the boss archetypes spawned in the arenas of the worlds.
+	Bosses []*domain.Boss
+	// NPCs are the characters placed on the maps, and Items what their
+	// shops trade.
+	NPCs  []*domain.NPC
+	Items []*domain.Item
+	// Quests are the quests NPCs offer, in journal order.
+	Quests []*domain.Quest
+
+	// Roles are the roles /login gives for each secret token, and
+	// AdminToken the secret /login trades for the admin role, empty to
+	// disable it. Audit records every admin command run.
+	Roles      map[string]domain.Role
+	AdminToken string
+	Audit      *log.Logger
+	// Log is the logger of the app subsystem.
+	Log *slog.Logger
+	// ReloadData reloads the game data files for /reload. The data is
+	// swapped in as a whole; requests in flight may still see the old one.
+	ReloadData func() error
+
+	// Clock is the time of the simulation, the wall clock unless a test
+	// steps it by hand.
+	Clock domain.Clock
+	// Spawn keeps the worlds without spawn zones populated, and
+	// SaveInterval is how often Step saves the online players, zero to
+	// never save them on its own; lastSave is when it last did.
+	Spawn        SpawnSettings
+	SaveInterval time.Duration
+	lastSave     time.Time
+
+	// rngs are the generators behind every random choice, by world id,
+	// seeded from seed by SetSeed so that a seeded server replays the same
+	// simulation.
+	seed  int64
+	rngs  map[string]*lockedRNG
+	rngMu sync.Mutex
+
+	// projectiles are the arrows and spells in flight, oldest first.
+	projectiles []*domain.Projectile
+	projSeq     int
+	projMu      sync.Mutex
+
+	// encounters are the boss fights, by arena id.
+	encounters map[string]*encounter
+
+	// dialogues are the conversations with NPCs, by player id.
+	dialogues  map[string]*dialogue
+	dialogueMu sync.Mutex
+
+	// parties are the player parties by id, also indexed by member id in
+	// partyByMember, and invites the pending invites by invitee id, holding
+	// the inviter id.
+	parties       map[string]*domain.Party
+	partyByMember map[string]*domain.Party
+	invites       map[string]string
+	partySeq      int
+	partyMu       sync.Mutex
+
+	// trades are the open trades by player id, both sides sharing one, and
+	// tradeRequests the pending requests by requested player id, holding
+	// the requester id.
+	trades        map[string]*domain.Trade
+	tradeRequests map[string]string
+	tradeSeq      int
+	tradeMu       sync.Mutex
+
+	// duels are the agreed duels by player id, both duelists sharing one,
+	// and duelRequests the pending challenges by challenged player id,
+	// holding the challenger id.
+	duels        map[string]*domain.Duel
+	duelRequests map[string]string
+	duelSeq      int
+	duelMu       sync.Mutex
+
+	// bans are the reasons connections are refused, by remote host.
+	bans  map[string]string
+	banMu sync.Mutex
+
+	// playerLocks guard the gold, inventory, quests, ability cooldowns and
+	// explored cells of each player by id, which trades, party loot, quest
+	// rewards and the game loop change from other goroutines than the
+	// player's own, and saving reads; lockPlayers takes them.
+	playerLocks  map[string]*sync.Mutex
+	playerLockMu sync.Mutex
+
+	// TickBudget is the time a game loop tick may take, its interval; the
+	// ticks taking longer are overruns.
+	TickBudget time.Duration
+
+	// tickCount counts the game loop ticks, which took tickTotal in all,
+	// tickLast the latest and tickMax the longest; tickOverruns counts
+	// those over budget. tickWindow holds the phase timings of the latest
+	// ticks, up to TickWindow of them, tickNext being the slot to fill.
+	tickCount    int64
+	tickTotal    time.Duration
+	tickLast     time.Duration
+	tickMax      time.Duration
+	tickOverruns int64
+	tickWindow   []tickSample
+	tickNext     int
+	tickMu       sync.Mutex
+
 	connections map[*clientConn]bool
 	connMutex   sync.RWMutex
 }
 
 func NewHandler(worldStore domain.WorldStore, playerStore domain.PlayerStore, mobStore domain.MobStore) *Handler {
 	return &Handler{
-		Worlds:      worldStore,
-		Player:      playerStore,
-		Mobs:        mobStore,
-		connections: make(map[*clientConn]bool),
+		Worlds:         worldStore,
+		Player:         playerStore,
+		Mobs:           mobStore,
+		SpawnWorldID:   "world1",
+		AttackCooldown: domain.DefaultAttackCooldown,
+		Clock:          domain.SystemClock{},
+		seed:           time.Now().UnixNano(),
+		rngs:           make(map[string]*lockedRNG),
+		connections:    make(map[*clientConn]bool),
+		encounters:     make(map[string]*encounter),
+		dialogues:      make(map[string]*dialogue),
+		parties:        make(map[string]*domain.Party),
+		partyByMember:  make(map[string]*domain.Party),
+		invites:        make(map[string]string),
+		trades:         make(map[string]*domain.Trade),
+		tradeRequests:  make(map[string]string),
+		duels:          make(map[string]*domain.Duel),
+		duelRequests:   make(map[string]string),
+		bans:           make(map[string]string),
+		playerLocks:    make(map[string]*sync.Mutex),
+		Audit:          log.New(log.Writer(), "audit: ", log.LstdFlags),
+		Log:            slog.Default().With("subsystem", "app"),
 	}
 }
 
 func (h *Handler) wrapConnection(c net.Conn) *clientConn {
-	w := bufio.NewWriter(c)
+	w := bufio.NewWriter(countingWriter{c})
 	enc := json.NewEncoder(w)
 	enc.SetEscapeHTML(false)
-	return &clientConn{conn: c, w: w, enc: enc}
+	return &clientConn{conn: c, w: w, enc: enc, since: time.Now(), log: h.Log.With("conn", c.RemoteAddr().String())}
 }
 
 func (h *Handler) addConnection(conn *clientConn) {
@@ -58,13 +206,34 @@ func (h *Handler) addConnection(conn *clientConn) {
 	defer h.connMutex.Unlock()
 
 	h.connections[conn] = true
+	connectedClients.Inc()
+}
+
+// addPlayerConnection adds conn as the connection of playerID, unless
+// another connection already plays them.
+func (h *Handler) addPlayerConnection(conn *clientConn, playerID string) bool {
+	h.connMutex.Lock()
+	defer h.connMutex.Unlock()
+
+	for other := range h.connections {
+		if other.playerID == playerID {
+			return false
+		}
+	}
+	conn.playerID = playerID
+	h.connections[conn] = true
+	connectedClients.Inc()
+	return true
 }
 
 func (h *Handler) removeConn(conn *clientConn) {
 	h.connMutex.Lock()
 	defer h.connMutex.Unlock()
 
-	delete(h.connections, conn)
+	if h.connections[conn] {
+		delete(h.connections, conn)
+		connectedClients.Dec()
+	}
 }
 
 func (cc *clientConn) sendJson(v any) error {
@@ -74,7 +243,11 @@ func (cc *clientConn) sendJson(v any) error {
 	if err := cc.enc.Encode(v); err != nil {
 		return err
 	}
-	return cc.w.Flush()
+	if err := cc.w.Flush(); err != nil {
+		return err
+	}
+	messagesSent.With(messageType(v)).Inc()
+	return nil
 }
 
 func (h *Handler) HandleConnection(ctx context.Context, conn net.Conn) {
@@ -82,27 +255,34 @@ func (h *Handler) HandleConnection(ctx context.Context, conn net.Conn) {
 
 	defer conn.Close()
 
-	fmt.Printf("New connection from: %v \n", conn.RemoteAddr().String())
-
-	h.addConnection(cc)
-	defer h.removeConn(cc)
+	cc.log.Info("new connection")
+	if why, banned := h.banned(conn.RemoteAddr()); banned {
+		cc.log.Warn("refused banned connection", "reason", why)
+		h.sendError(conn, fmt.Errorf("you are banned (%s)", why))
+		return
+	}
 
-	world := h.Worlds.GetWorld("world1")
-	y, x, err := domain.FindRandomSpawnPosition(world, h.getOccupiedPositions(world.ID))
+	reader := bufio.NewReader(cc.conn)
+	p, err := h.join(cc, reader)
 	if err != nil {
-		h.sendError(conn, fmt.Errorf("unable to find spawn position: %v", err))
+		cc.log.Info("refused join", "err", err)
+		h.sendError(conn, err)
 		return
 	}
-	playerID := fmt.Sprintf("player-%d", rand.Intn(10000))
-	p := h.Player.GetPlayer(playerID)
-	if p == nil {
-		p = domain.NewPlayer(fmt.Sprintf("player-%d", rand.Intn(10000)), x, y)
-	}
-
-	h.Player.SavePlayer(p)
-	cc.playerID = p.ID
+	defer h.removeConn(cc)
 
-	reader := bufio.NewReader(cc.conn)
+	cc.log = cc.log.With("player", p.ID)
+	cc.log.Info("player joined", "world", p.WorldID)
+	cc.sendJson(serverMsg{Type: "joined", Msg: "Welcome, " + p.ID, Player: p})
+	if h.Durable() {
+		// Deferred first to run last, once leaving has settled trades and duels.
+		defer func() {
+			if err := h.savePlayers(p); err != nil {
+				cc.log.Error("error saving player", "err", err)
+			}
+		}()
+	}
+	defer h.disconnect(p.ID)
 
 	for {
 		select {
@@ -112,15 +292,19 @@ func (h *Handler) HandleConnection(ctx context.Context, conn net.Conn) {
 
 		default:
 			line, err := reader.ReadBytes('\n')
+			if errors.Is(err, io.EOF) {
+				cc.log.Info("connection closed")
+				return
+			}
 			if err != nil {
-				log.Printf("error reading from connection: %v, addr: %v", err, cc.conn.RemoteAddr().String())
+				cc.log.Warn("error reading from connection", "err", err)
 				return
 			}
 
 			var msg ClientMsg
 			err = json.Unmarshal(line, &msg)
 			if err != nil {
-				log.Printf("error unmarshaling json: %v", err)
+				cc.log.Warn("error unmarshaling message", "err", err)
 				return
 			}
 
@@ -129,7 +313,62 @@ func (h *Handler) HandleConnection(ctx context.Context, conn net.Conn) {
 	}
 }
 
+// joinTimeout is how long a new connection has to send its join message.
+const joinTimeout = 30 * time.Second
+
+// join reads the join message a connection starts with, naming the player in
+// PlayerID with their Secret. It returns that player, loaded from the store
+// or created at a spawn point of SpawnWorldID, once the connection is added
+// for them.
+func (h *Handler) join(cc *clientConn, reader *bufio.Reader) (*domain.Player, error) {
+	cc.conn.SetReadDeadline(time.Now().Add(joinTimeout))
+	defer cc.conn.SetReadDeadline(time.Time{})
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("expected a join message: %w", err)
+	}
+	var msg ClientMsg
+	if err := json.Unmarshal(line, &msg); err != nil || msg.Type != "join" {
+		return nil, fmt.Errorf("expected a join message")
+	}
+	if err := domain.ValidatePlayerName(msg.PlayerID); err != nil {
+		return nil, err
+	}
+
+	p, err := h.Player.LoadPlayer(msg.PlayerID)
+	if err != nil {
+		cc.log.Error("error loading player", "player", msg.PlayerID, "err", err)
+		return nil, fmt.Errorf("unable to load your player, try again later")
+	}
+	if p == nil || h.Worlds.GetWorld(p.WorldID) == nil {
+		world := h.Worlds.GetWorld(h.SpawnWorldID)
+		x, y, err := world.FindPlayerSpawnPosition(h.getOccupiedPositions(world.ID), h.rand(world.ID))
+		if err != nil {
+			return nil, fmt.Errorf("unable to find spawn position: %v", err)
+		}
+		if p == nil {
+			p = domain.NewPlayer(msg.PlayerID, x, y)
+		}
+		p.WorldID, p.X, p.Y = world.ID, x, y
+	}
+
+	// Claimed before checking the secret, so that a wrong one cannot
+	// touch a player someone is playing.
+	if !h.addPlayerConnection(cc, p.ID) {
+		return nil, fmt.Errorf("%s is already playing", p.ID)
+	}
+	if err := p.Authenticate(msg.Secret); err != nil {
+		h.removeConn(cc)
+		return nil, err
+	}
+	h.Player.SavePlayer(p)
+	return p, nil
+}
+
 func (h *Handler) HandleMessage(ctx context.Context, msg ClientMsg, cc *clientConn) {
+	received := msg.Type
+	defer func() { messagesReceived.With(received).Inc() }()
+
 	player := h.Player.GetPlayer(cc.playerID)
 	if player == nil {
 		err := fmt.Errorf("player not found")
@@ -144,7 +383,7 @@ func (h *Handler) HandleMessage(ctx context.Context, msg ClientMsg, cc *clientCo
 
 		if player == nil {
 			err := fmt.Errorf("player not found")
-			log.Printf("error retrieving player: %v", err)
+			cc.log.Error("error retrieving player", "err", err)
 			cc.sendJson(serverMsg{
 				Type: "error",
 				Msg:  err.Error(),
@@ -157,28 +396,161 @@ func (h *Handler) HandleMessage(ctx context.Context, msg ClientMsg, cc *clientCo
 			Player: player,
 		})
 		if err != nil {
-			log.Printf("error sending player data: %v", err)
-		} else {
-			log.Printf("Player %s sent successfully", player.ID)
+			cc.log.Error("error sending player data", "err", err)
 		}
 
 	case "move":
 		err := h.HandlePlayerMove(ctx, cc, player, msg.Direction)
 		if err != nil {
-			log.Printf("error handling player move: %v", err)
+			cc.log.Error("error handling player move", "err", err)
 		}
 
 	case "getWorld":
 		err := h.HandleSendWorld(ctx, cc, msg.Message)
 		if err != nil {
-			log.Printf("error sending world: %v", err)
+			cc.log.Error("error sending world", "err", err)
+		}
+
+	case "toggleDoor":
+		err := h.HandleToggleDoor(ctx, cc, player, msg.Direction)
+		if err != nil {
+			cc.log.Error("error toggling door", "err", err)
+		}
+
+	case "getAbilities":
+		err := h.HandleSendAbilities(cc)
+		if err != nil {
+			cc.log.Error("error sending abilities", "err", err)
+		}
+
+	case "useAbility":
+		err := h.HandleUseAbility(ctx, cc, player, msg.Message, msg.Target, msg.Direction)
+		if err != nil {
+			cc.log.Error("error handling ability", "err", err)
 		}
 
 	case "attack":
-		err := h.HandlerPlayerAttack(ctx, player, cc)
+		err := h.HandlerPlayerAttack(ctx, player, cc, msg.Target)
 		if err != nil {
-			log.Printf("error handling player attack: %v", err)
+			cc.log.Error("error handling player attack", "err", err)
 		}
+
+	case "shoot":
+		err := h.HandlePlayerShoot(ctx, cc, player, msg.Target, msg.Direction)
+		if err != nil {
+			cc.log.Error("error handling player shot", "err", err)
+		}
+
+	case "interact":
+		err := h.HandleInteract(ctx, cc, player, msg.Target)
+		if err != nil {
+			cc.log.Error("error handling interaction", "err", err)
+		}
+
+	case "choose":
+		err := h.HandleChoose(ctx, cc, player, msg.Message)
+		if err != nil {
+			cc.log.Error("error handling dialogue choice", "err", err)
+		}
+
+	case "getQuests":
+		err := h.HandleSendQuests(cc, player)
+		if err != nil {
+			cc.log.Error("error sending quests", "err", err)
+		}
+
+	case "buy":
+		err := h.HandleBuy(ctx, cc, player, msg.Message)
+		if err != nil {
+			cc.log.Error("error handling purchase", "err", err)
+		}
+
+	case "sell":
+		err := h.HandleSell(ctx, cc, player, msg.Message)
+		if err != nil {
+			cc.log.Error("error handling sale", "err", err)
+		}
+
+	case "partyInvite":
+		err := h.HandlePartyInvite(cc, player, msg.Target)
+		if err != nil {
+			cc.log.Error("error handling party invite", "err", err)
+		}
+
+	case "partyAccept":
+		err := h.HandlePartyAccept(cc, player)
+		if err != nil {
+			cc.log.Error("error accepting party invite", "err", err)
+		}
+
+	case "partyDecline":
+		err := h.HandlePartyDecline(cc, player)
+		if err != nil {
+			cc.log.Error("error declining party invite", "err", err)
+		}
+
+	case "partyLeave":
+		err := h.HandlePartyLeave(cc, player)
+		if err != nil {
+			cc.log.Error("error leaving party", "err", err)
+		}
+
+	case "partyKick":
+		err := h.HandlePartyKick(cc, player, msg.Target)
+		if err != nil {
+			cc.log.Error("error handling party kick", "err", err)
+		}
+
+	case "partyChat":
+		err := h.HandlePartyChat(cc, player, msg.Message)
+		if err != nil {
+			cc.log.Error("error handling party chat", "err", err)
+		}
+
+	case "tradeRequest":
+		err := h.HandleTradeRequest(cc, player, msg.Target)
+		if err != nil {
+			cc.log.Error("error handling trade request", "err", err)
+		}
+
+	case "tradeOffer":
+		err := h.HandleTradeOffer(cc, player, msg.Message, msg.Count)
+		if err != nil {
+			cc.log.Error("error handling trade offer", "err", err)
+		}
+
+	case "tradeConfirm":
+		err := h.HandleTradeConfirm(cc, player)
+		if err != nil {
+			cc.log.Error("error confirming trade", "err", err)
+		}
+
+	case "tradeCancel":
+		err := h.HandleTradeCancel(cc, player)
+		if err != nil {
+			cc.log.Error("error cancelling trade", "err", err)
+		}
+
+	case "command":
+		err := h.HandleCommand(ctx, cc, player, msg.Message)
+		if err != nil {
+			cc.log.Error("error handling command", "err", err)
+		}
+
+	case "duel":
+		err := h.HandleDuelRequest(cc, player, msg.Target)
+		if err != nil {
+			cc.log.Error("error handling duel request", "err", err)
+		}
+
+	case "who":
+		err := h.HandleWho(cc, player)
+		if err != nil {
+			cc.log.Error("error listing players", "err", err)
+		}
+
+	default:
+		received = "unknown"
 	}
 }
 
@@ -214,15 +586,28 @@ func (h *Handler) sendSuccess(conn net.Conn, msg string) error {
 }
 
 func (h *Handler) HandleSendWorld(ctx context.Context, cc *clientConn, worldID string) error {
-	fmt.Printf("Sending world %s\n", worldID)
+	cc.log.Debug("sending world", "world", worldID)
 	world := h.Worlds.GetWorld(worldID)
+	if world == nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: "world not found"})
+	}
+	player := h.Player.GetPlayer(cc.playerID)
+	if player == nil {
+		return fmt.Errorf("player not found")
+	}
 
 	response := serverMsg{
 		Type:  "world",
-		World: world,
+		World: exploredWorld(world, player),
 	}
 
-	return cc.sendJson(response)
+	if err := cc.sendJson(response); err != nil {
+		return err
+	}
+	if player.WorldID != world.ID {
+		return nil
+	}
+	return h.sendView(cc, player, world)
 }
 
 func (h *Handler) HandlePlayerMove(ctx context.Context, cc *clientConn, player *domain.Player, dir string) error {
@@ -235,62 +620,136 @@ func (h *Handler) HandlePlayerMove(ctx context.Context, cc *clientConn, player *
 		})
 	}
 	err := player.Move(dir, world)
+	if errors.Is(err, domain.ErrDoorOpened) {
+		door, _ := domain.Step(domain.Point{X: player.X, Y: player.Y}, dir)
+		h.broadcastTile(world, door.X, door.Y)
+		return cc.sendJson(serverMsg{Type: "success", Msg: err.Error()})
+	}
+	if errors.Is(err, domain.ErrSlowed) {
+		return cc.sendJson(serverMsg{Type: "success", Msg: err.Error()})
+	}
 	if err != nil {
 		return cc.sendJson(serverMsg{
 			Type: "error",
 			Msg:  err.Error(),
 		})
 	}
+
+	h.cancelTrade(player.ID, player.ID+" moved away")
+	if portal := world.PortalAt(player.X, player.Y); portal != nil {
+		return h.usePortal(cc, player, portal)
+	}
+	h.questEvent(player, domain.QuestEvent{Kind: domain.ObjectiveReach, Target: world.ID, X: player.X, Y: player.Y})
 	h.Player.SavePlayer(player)
 
-	return cc.sendJson(serverMsg{
+	err = cc.sendJson(serverMsg{
+		Type:   "playerUpdate",
+		Player: player,
+	})
+	if err != nil {
+		return err
+	}
+	return h.sendView(cc, player, world)
+}
+
+func (h *Handler) usePortal(cc *clientConn, player *domain.Player, portal *domain.Portal) error {
+	target := h.Worlds.GetWorld(portal.ToWorld)
+	if target == nil {
+		return fmt.Errorf("portal leads to unknown world %s", portal.ToWorld)
+	}
+	player.WorldID = target.ID
+	player.X, player.Y = portal.ToX, portal.ToY
+	h.Player.SavePlayer(player)
+	cc.log.Info("player entered world", "world", target.ID, "x", player.X, "y", player.Y)
+
+	h.see(player, target)
+	if err := cc.sendJson(serverMsg{Type: "world", World: exploredWorld(target, player)}); err != nil {
+		return err
+	}
+	h.questEvent(player, domain.QuestEvent{Kind: domain.ObjectiveReach, Target: target.ID, X: player.X, Y: player.Y})
+	err := cc.sendJson(serverMsg{
 		Type:   "playerUpdate",
-		Msg:    "Player moved successfully",
+		Msg:    "Entered " + target.Name,
 		Player: player,
 	})
+	if err != nil {
+		return err
+	}
+	return h.sendView(cc, player, target)
 }
 
-func (h *Handler) HandlerPlayerAttack(ctx context.Context, p *domain.Player, cc *clientConn) error {
+// HandlerPlayerAttack attacks the mob or player targetID, or the nearest
+// mob the player can see within range when targetID is empty, falling back
+// on the nearest player they may fight. Nobody attacks from a safe zone.
+func (h *Handler) HandlerPlayerAttack(ctx context.Context, p *domain.Player, cc *clientConn, targetID string) error {
 	if p == nil {
 		return fmt.Errorf("player not found")
 	}
-	mob, err := h.nearestMob(p, p.WorldID, p.Range)
-	if err != nil {
+	world := h.Worlds.GetWorld(p.WorldID)
+	if world == nil {
+		return fmt.Errorf("world %s not found", p.WorldID)
+	}
+	if world.RegionAt(p.X, p.Y) == domain.RegionSafe {
+		return cc.sendJson(serverMsg{Type: "error", Msg: domain.ErrSafeZone.Error()})
+	}
+
+	var mob *domain.Mob
+	if targetID != "" {
+		if target := h.Player.GetPlayer(targetID); target != nil && target.ID != p.ID {
+			if target.WorldID != p.WorldID {
+				return cc.sendJson(serverMsg{Type: "error", Msg: "target is gone"})
+			}
+			return h.attackPlayer(cc, world, p, target)
+		}
+		mob = h.Mobs.GetMob(targetID)
+		if mob == nil || mob.WorldID != p.WorldID {
+			return cc.sendJson(serverMsg{Type: "error", Msg: "target is gone"})
+		}
+		if !inReach(world, p, domain.Point{X: mob.X, Y: mob.Y}, p.Range) {
+			return cc.sendJson(serverMsg{Type: "error", Msg: mob.Name + " is out of range or out of sight"})
+		}
+	} else {
+		mob = h.nearestMobInReach(world, p, p.Range)
+		if mob == nil {
+			if target := h.nearestHostileInReach(world, p, p.Range); target != nil {
+				return h.attackPlayer(cc, world, p, target)
+			}
+			return cc.sendJson(serverMsg{
+				Type: "error",
+				Msg:  "no mob in range to attack",
+			})
+		}
+	}
+
+	roll := h.rollAttack(world.ID)
+	damage, err := p.AttackMob(mob, roll, h.Clock.Now(), h.AttackCooldown)
+	if errors.Is(err, domain.ErrAttackCooldown) {
 		return cc.sendJson(serverMsg{
 			Type: "error",
-			Msg:  "no mob in range to attack",
+			Msg:  fmt.Sprintf("You can attack again in %.1fs", p.CooldownLeft(h.Clock.Now()).Seconds()),
 		})
 	}
-
-	p.AttackMob(mob)
+	if err != nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: err.Error()})
+	}
+	if err := cc.sendJson(playerHitMob(mob, "", roll, damage)); err != nil {
+		return err
+	}
 	if mob.IsAlive() {
+		mob.Provoke(p.ID)
 		h.Mobs.SaveMob(mob)
-	} else {
-		h.Mobs.DeleteMob(mob.ID)
+		h.Player.SavePlayer(p)
+		return nil
 	}
-	h.Player.SavePlayer(p)
-
-	return nil
-}
-
-func (h *Handler) nearestMob(p *domain.Player, worldID string, attackRange int) (*domain.Mob, error) {
-	mobs := h.Mobs.GetMobsByWorld(worldID)
 
-	var nearest *domain.Mob
-	minDist := attackRange + 1
+	msg := h.killMob(p, mob)
+	h.Player.SavePlayer(p)
 
-	for _, mob := range mobs {
-		// Manhattan distance
-		dist := abs(p.X-mob.X) + abs(p.Y-mob.Y)
-		if dist < minDist {
-			minDist = dist
-			nearest = mob
-		}
-	}
-	if nearest == nil {
-		return nil, fmt.Errorf("no mob found")
-	}
-	return nearest, nil
+	return cc.sendJson(serverMsg{
+		Type:   "playerUpdate",
+		Msg:    msg,
+		Player: p,
+	})
 }
 
 func abs(x int) int {
@@ -306,20 +765,52 @@ func (h *Handler) HandleMobSpawn(ctx context.Context, worldID string, mobType st
 		return nil, fmt.Errorf("world not found")
 	}
 
-	mobID := fmt.Sprintf("mob-%d-%d", h.Mobs.CountMobsInWorld(worldID)+1, rand.Intn(10000))
+	mobID := fmt.Sprintf("mob-%d-%d", h.Mobs.CountMobsInWorld(worldID)+1, h.rand(worldID).Intn(10000))
 	occupiedPositions := h.getOccupiedPositions(worldID)
 
-	mob, err := world.SpawnMob(mobType, name, mobID, occupiedPositions)
+	mob, err := world.SpawnMob(mobType, name, mobID, occupiedPositions, h.rand(worldID))
 	if err != nil {
 		return nil, err
 	}
 
 	h.Mobs.CreateMob(mob)
-	log.Printf("Spawned mob %s of type %s at (%d, %d) in world %s", mob.ID, mob.Type, mob.X, mob.Y, world.ID)
+	h.Log.Debug("spawned mob", "mob", mob.ID, "type", mob.Type, "x", mob.X, "y", mob.Y, "world", world.ID)
 
 	return mob, nil
 }
 
+func (h *Handler) HandleZoneSpawn(ctx context.Context, worldID string, zone domain.SpawnZone) (*domain.Mob, error) {
+	world := h.Worlds.GetWorld(worldID)
+	if world == nil {
+		return nil, fmt.Errorf("world not found")
+	}
+
+	mobID := fmt.Sprintf("mob-%d-%d", h.Mobs.CountMobsInWorld(worldID)+1, h.rand(worldID).Intn(10000))
+	mob, err := world.SpawnMobInZone(zone, zone.MobType, mobID, h.getOccupiedPositions(worldID), h.rand(worldID))
+	if err != nil {
+		return nil, err
+	}
+
+	h.Mobs.CreateMob(mob)
+	h.Log.Debug("spawned mob", "mob", mob.ID, "type", mob.Type, "x", mob.X, "y", mob.Y, "world", worldID, "zone", zone.ID)
+
+	return mob, nil
+}
+
+func (h *Handler) CountMobsInZone(worldID, zoneID string) int {
+	count := 0
+	for _, mob := range h.Mobs.GetMobsByWorld(worldID) {
+		if mob.Zone == zoneID {
+			count++
+		}
+	}
+	return count
+}
+
+// HandleMobMove lets the mob's brain decide what it does this tick. A mob
+// chasing a player attacks or shoots them when it can, and otherwise steps
+// where its brain says; fleeing or returning mobs never fight back. Brains
+// draw from the combat generator, so a seeded server replays the same moves.
 func (h *Handler) HandleMobMove(ctx context.Context, mobID string) error {
 	mob := h.Mobs.GetMob(mobID)
 	if mob == nil {
@@ -330,15 +821,20 @@ func (h *Handler) HandleMobMove(ctx context.Context, mobID string) error {
 		return fmt.Errorf("world not found")
 	}
 
-	directions := []string{"N", "S", "E", "W", "NE", "NW", "SE", "SW"}
-	direction := directions[rand.Intn(len(directions))]
+	senses := domain.NewSenses(world, h.playersInWorld(world.ID), h.Mobs.GetMobsByWorld(world.ID))
+	direction := h.brainFor(mob).Think(mob, senses, h.rand(mob.WorldID))
+	if mob.State == domain.MobChase && (h.HandleMobAttack(ctx, mob) || h.HandleMobShoot(ctx, mob)) {
+		h.Mobs.SaveMob(mob)
+		return nil
+	}
 
-	err := mob.Move(direction, world)
-	if err != nil {
-		return err
+	if direction != "" {
+		if err := mob.Move(direction, world); err != nil {
+			return err
+		}
+		h.Log.Debug("mob moved", "mob", mob.ID, "x", mob.X, "y", mob.Y, "world", world.ID)
 	}
 	h.Mobs.SaveMob(mob)
-	log.Printf("Mob %s moved to (%d, %d) in world %s", mob.ID, mob.X, mob.Y, world.ID)
 	return nil
 }
 
@@ -348,7 +844,7 @@ func (h *Handler) HandleMobDespawn(ctx context.Context, mobID string) error {
 		return fmt.Errorf("mob not found")
 	}
 	h.Mobs.DeleteMob(mobID)
-	log.Printf("Mob %s despawned from world %s", mob.ID, mob.WorldID)
+	h.Log.Debug("mob despawned", "mob", mob.ID, "world", mob.WorldID)
 	return nil
 }
 
@@ -367,24 +863,63 @@ func (h *Handler) getOccupiedPositions(worldID string) map[string]bool {
 type MobUpdate struct {
 	Type string        `json:"type"`
 	Mobs []*domain.Mob `json:"mobs"`
+	// View is the player's field of view, and Tiles holds the glyphs of
+	// its visible cells in row-major order.
+	View  *domain.FOV `json:"view,omitempty"`
+	Tiles []byte      `json:"tiles,omitempty"`
+	// Projectiles are the projectiles in flight inside the view.
+	Projectiles []*domain.Projectile `json:"projectiles,omitempty"`
+	// NPCs are the NPCs standing inside the view, and Players the other
+	// players.
+	NPCs    []npcInfo    `json:"npcs,omitempty"`
+	Players []playerInfo `json:"players,omitempty"`
 }
 
+// BroadcastMobsUpdate sends each player in the world its field of view along
+// with the mobs inside it.
 func (h *Handler) BroadcastMobsUpdate(worldID string) error {
-	h.connMutex.RLock()
-	defer h.connMutex.RUnlock()
-
+	world := h.Worlds.GetWorld(worldID)
+	if world == nil {
+		return fmt.Errorf("world not found")
+	}
 	mobs := h.Mobs.GetMobsByWorld(worldID)
-	fmt.Printf("Broadcasting mobs update: %+v\n", mobs)
+	worldMobs.With(worldID).Set(float64(len(mobs)))
+	defer broadcastDuration.With(worldID).Since(time.Now())
 
-	response := MobUpdate{
-		Type: "mobsUpdate",
-		Mobs: mobs,
+	// The views are built off a snapshot of the connections: viewFor looks
+	// up the players in view, which takes connMutex again.
+	var failedConns []*clientConn
+	for _, conn := range h.connectionList() {
+		player := h.Player.GetPlayer(conn.playerID)
+		if player == nil || player.WorldID != worldID {
+			continue
+		}
+		if err := conn.sendJson(h.viewFor(player, world, mobs)); err != nil {
+			failedConns = append(failedConns, conn)
+		}
 	}
 
-	var failedConns []*clientConn
+	for _, conn := range failedConns {
+		h.removeConn(conn)
+	}
+	failedBroadcasts.With("view").Add(float64(len(failedConns)))
+	return nil
+}
 
-	for conn := range h.connections {
-		err := conn.sendJson(response)
+// broadcastToWorld sends v to every connection whose player is in worldID and
+// passes include, if set, and drops the connections that fail. include is
+// called without connMutex held, so it may look up connections.
+func (h *Handler) broadcastToWorld(worldID string, v any, include func(*domain.Player) bool) {
+	var failedConns []*clientConn
+	for _, conn := range h.connectionList() {
+		player := h.Player.GetPlayer(conn.playerID)
+		if player == nil || player.WorldID != worldID {
+			continue
+		}
+		if include != nil && !include(player) {
+			continue
+		}
+		err := conn.sendJson(v)
 		if err != nil {
 			failedConns = append(failedConns, conn)
 		}
@@ -393,5 +928,46 @@ func (h *Handler) BroadcastMobsUpdate(worldID string) error {
 	for _, conn := range failedConns {
 		h.removeConn(conn)
 	}
+	failedBroadcasts.With("world").Add(float64(len(failedConns)))
+}
+
+// lockPlayers locks the gold, inventory, quests, ability cooldowns and
+// explored cells of the players ids, in id order so that two callers
+// locking the same players never wait on each other, and returns the
+// function unlocking them.
+func (h *Handler) lockPlayers(ids ...string) func() {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	h.playerLockMu.Lock()
+	locks := make([]*sync.Mutex, len(ids))
+	for i, id := range ids {
+		if h.playerLocks[id] == nil {
+			h.playerLocks[id] = new(sync.Mutex)
+		}
+		locks[i] = h.playerLocks[id]
+	}
+	h.playerLockMu.Unlock()
+
+	for _, l := range locks {
+		l.Lock()
+	}
+	return func() {
+		for _, l := range slices.Backward(locks) {
+			l.Unlock()
+		}
+	}
+}
+
+func (h *Handler) connForPlayer(playerID string) *clientConn {
+	h.connMutex.RLock()
+	defer h.connMutex.RUnlock()
+
+	for conn := range h.connections {
+		if conn.playerID == playerID {
+			return conn
+		}
+	}
 	return nil
 }
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/LealKevin/terminus/internal/domain"
+	"github.com/LealKevin/terminus/internal/infra/store"
+)
+
+// joinAs opens a connection to h and joins as name, returning the first
+// message the server answered with and the client end of the connection.
+func joinAs(t *testing.T, h *Handler, name, secret string) (serverMsg, net.Conn) {
+	t.Helper()
+	server, client := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+	go h.HandleConnection(context.Background(), server)
+
+	if err := json.NewEncoder(client).Encode(ClientMsg{Type: "join", PlayerID: name, Secret: secret}); err != nil {
+		t.Fatal(err)
+	}
+	var msg serverMsg
+	if err := json.NewDecoder(client).Decode(&msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg, client
+}
+
+func TestJoinKeepsThePlayerOfAName(t *testing.T) {
+	worlds := store.NewWorldMemoryStore()
+	worlds.SaveWorld(testRoom())
+	h := NewHandler(worlds, store.NewPlayerMemoryStore(), store.NewMobMemoryStore())
+	h.WorldIDs = []string{"room"}
+	h.SpawnWorldID = "room"
+	h.SetSeed(1)
+
+	msg, conn := joinAs(t, h, "alice", "s3cret")
+	if msg.Type != "joined" || msg.Player == nil || msg.Player.ID != "alice" {
+		t.Fatalf("first join answered %+v, want alice joined", msg)
+	}
+	alice := h.Player.GetPlayer("alice")
+	alice.Gold = 77
+
+	if msg, _ := joinAs(t, h, "alice", "s3cret"); msg.Type != "error" {
+		t.Errorf("joining as alice while alice plays answered %+v, want an error", msg)
+	}
+	if msg, _ := joinAs(t, h, "a", "s3cret"); msg.Type != "error" {
+		t.Errorf("joining with a one letter name answered %+v, want an error", msg)
+	}
+
+	conn.Close()
+	for deadline := time.Now().Add(time.Second); h.connForPlayer("alice") != nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("alice is still connected")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if msg, _ := joinAs(t, h, "alice", "guess"); msg.Type != "error" || msg.Msg != domain.ErrWrongSecret.Error() {
+		t.Errorf("joining as alice with another secret answered %+v, want %q", msg, domain.ErrWrongSecret)
+	}
+	msg, _ = joinAs(t, h, "alice", "s3cret")
+	if msg.Type != "joined" || h.Player.GetPlayer("alice") != alice || msg.Player.Gold != 77 {
+		t.Errorf("joining back as alice answered %+v, want the same player with 77 gold", msg)
+	}
+}
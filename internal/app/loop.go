@@ -0,0 +1,105 @@
+package app
+
+import (
+	"context"
+	"time"
+)
+
+// SpawnSettings keep the worlds without spawn zones populated with up to
+// MobCap mobs of MobType, named MobName or after their type.
+type SpawnSettings struct {
+	MobCap  int
+	MobType string
+	MobName string
+}
+
+// Run steps the simulation every interval until ctx is done.
+func (h *Handler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.Step(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Step runs one tick of the simulation: every world of WorldIDs, then the
+// duels and the parties, saving the online players every SaveInterval of
+// Clock time when the player store is durable. Run calls it at the tick
+// rate; a test can call it directly, advancing a domain.ManualClock between
+// steps, and replay the same simulation from the same seed.
+func (h *Handler) Step(ctx context.Context) {
+	timer := NewTickTimer()
+	for _, worldID := range h.WorldIDs {
+		h.stepWorld(ctx, worldID, timer)
+	}
+	h.TickDuels(ctx)
+	timer.Lap(PhaseCombat)
+	h.BroadcastParties()
+	timer.Lap(PhaseBroadcast)
+
+	now := h.Clock.Now()
+	if h.lastSave.IsZero() {
+		h.lastSave = now
+	}
+	if h.SaveInterval > 0 && h.Durable() && now.Sub(h.lastSave) >= h.SaveInterval {
+		if _, err := h.SaveState(); err != nil {
+			h.Log.Error("failed to save players", "err", err)
+		}
+		h.lastSave = now
+	}
+	timer.Lap(PhasePersistence)
+	h.RecordTick(timer)
+}
+
+func (h *Handler) stepWorld(ctx context.Context, worldID string, timer *TickTimer) {
+	world := h.Worlds.GetWorld(worldID)
+	if world == nil {
+		return
+	}
+
+	if len(world.SpawnZones) > 0 {
+		for _, zone := range world.SpawnZones {
+			mobsCount := h.CountMobsInZone(worldID, zone.ID)
+			for i := 0; i < zone.Cap-mobsCount; i++ {
+				h.HandleZoneSpawn(ctx, worldID, zone)
+			}
+		}
+	} else {
+		name := h.Spawn.MobName
+		if name == "" {
+			name = h.Spawn.MobType
+		}
+		mobsCount := h.Mobs.CountMobsInWorld(worldID)
+		if mobsCount < h.Spawn.MobCap {
+			for i := 0; i < h.Spawn.MobCap-mobsCount; i++ {
+				h.HandleMobSpawn(ctx, worldID, h.Spawn.MobType, name)
+			}
+		}
+	}
+	timer.Lap(PhaseSpawn)
+
+	h.TickEncounters(ctx, worldID)
+	timer.Lap(PhaseAI)
+	h.MoveProjectiles(ctx, worldID)
+	timer.Lap(PhaseCombat)
+
+	for _, mob := range h.Mobs.GetMobsByWorld(worldID) {
+		h.HandleMobMove(ctx, mob.ID)
+	}
+	timer.Lap(PhaseAI)
+
+	h.TickStatuses(ctx, worldID)
+	h.ApplyHazards(ctx, worldID)
+	h.RegenMana(ctx, worldID)
+	timer.Lap(PhaseCombat)
+
+	if err := h.BroadcastMobsUpdate(worldID); err != nil {
+		h.Log.Error("failed to broadcast mob update", "world", worldID, "err", err)
+	}
+	timer.Lap(PhaseBroadcast)
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"io"
+	"reflect"
+
+	"github.com/LealKevin/terminus/internal/metrics"
+)
+
+var (
+	connectedClients = metrics.NewGauge("terminus_connected_clients",
+		"Open game connections.")
+	messagesReceived = metrics.NewCounterVec("terminus_messages_received_total",
+		"Messages received from clients, by type; types the server does not handle count as unknown.", "type")
+	messagesSent = metrics.NewCounterVec("terminus_messages_sent_total",
+		"Messages sent to clients, by type.", "type")
+	bytesSent = metrics.NewCounter("terminus_bytes_sent_total",
+		"Bytes written to game connections.")
+	tickDuration = metrics.NewHistogram("terminus_tick_duration_seconds",
+		"Time taken by a game loop tick.", metrics.DefBuckets)
+	tickPhaseDuration = metrics.NewHistogramVec("terminus_tick_phase_duration_seconds",
+		"Time taken by a phase of a game loop tick, summed over the worlds.", metrics.DefBuckets, "phase")
+	tickOverruns = metrics.NewCounter("terminus_tick_overruns_total",
+		"Game loop ticks that took longer than the tick interval.")
+	broadcastDuration = metrics.NewHistogramVec("terminus_broadcast_duration_seconds",
+		"Time taken to send the players of a world their view.", metrics.DefBuckets, "world")
+	worldMobs = metrics.NewGaugeVec("terminus_mobs",
+		"Mobs in a world as of its latest broadcast.", "world")
+	failedBroadcasts = metrics.NewCounterVec("terminus_broadcast_failed_connections_total",
+		"Connections dropped because a broadcast to them failed, by broadcast: view for BroadcastMobsUpdate, world for the other world wide messages.", "broadcast")
+)
+
+// countingWriter counts the bytes written through it in bytesSent.
+type countingWriter struct {
+	w io.Writer
+}
+
+func (cw countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	bytesSent.Add(float64(n))
+	return n, err
+}
+
+// messageType returns the Type field of a message sent to clients, or
+// "unknown" for messages without one.
+func messageType(v any) string {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return "unknown"
+	}
+	field := rv.FieldByName("Type")
+	if field.Kind() != reflect.String || field.String() == "" {
+		return "unknown"
+	}
+	return field.String()
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LealKevin/terminus/internal/domain"
+	"github.com/LealKevin/terminus/internal/infra/store"
+)
+
+// testRoom returns an open floor of 30 by 11 cells walled in.
+func testRoom() *domain.World {
+	const width, height = 30, 11
+	rows := make([]string, height)
+	for y := range rows {
+		if y == 0 || y == height-1 {
+			rows[y] = strings.Repeat("#", width)
+		} else {
+			rows[y] = "#" + strings.Repeat(" ", width-2) + "#"
+		}
+	}
+	return domain.NewWorld("room", width, height, domain.ConvertLayout(strings.Join(rows, "\n")))
+}
+
+// newTestHandler returns a seeded handler stepping world, and connects
+// player to it through a pipe whose output is thrown away.
+func newTestHandler(t *testing.T, world *domain.World, player *domain.Player) *Handler {
+	t.Helper()
+	worlds := store.NewWorldMemoryStore()
+	worlds.SaveWorld(world)
+	h := NewHandler(worlds, store.NewPlayerMemoryStore(), store.NewMobMemoryStore())
+	h.WorldIDs = []string{world.ID}
+	h.SpawnWorldID = world.ID
+	h.SetSeed(1)
+	h.Clock = domain.NewManualClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	connectTestPlayer(t, h, player)
+	return h
+}
+
+// connectTestPlayer connects player to h, in the world h spawns players in,
+// through a pipe whose output is thrown away.
+func connectTestPlayer(t *testing.T, h *Handler, player *domain.Player) *clientConn {
+	t.Helper()
+	server, client := net.Pipe()
+	t.Cleanup(func() { server.Close(); client.Close() })
+	go io.Copy(io.Discard, client)
+	cc := h.wrapConnection(server)
+	cc.playerID = player.ID
+	player.WorldID = h.SpawnWorldID
+	h.Player.SavePlayer(player)
+	h.addConnection(cc)
+	return cc
+}
+
+func TestStepRunsTheBrainOfMobsInReach(t *testing.T) {
+	tests := []struct {
+		name   string
+		mob    domain.Point
+		home   domain.Point
+		health int
+		player domain.Point
+		want   domain.MobState
+	}{
+		{name: "hurt mob flees instead of fighting", mob: domain.Point{X: 5, Y: 5}, home: domain.Point{X: 5, Y: 5}, health: 10,
+			player: domain.Point{X: 6, Y: 5}, want: domain.MobFlee},
+		{name: "mob past its leash heads home instead of fighting", mob: domain.Point{X: 20, Y: 5}, home: domain.Point{X: 5, Y: 5}, health: 100,
+			player: domain.Point{X: 21, Y: 5}, want: domain.MobReturn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			player := domain.NewPlayer("player-1", tt.player.X, tt.player.Y)
+			h := newTestHandler(t, testRoom(), player)
+			mob := &domain.Mob{ID: "mob-1", Name: "Goblin", Type: "Goblin", WorldID: "room", X: tt.mob.X, Y: tt.mob.Y,
+				Health: tt.health, MaxHealth: 100, Attack: 50, State: domain.MobChase, TargetID: player.ID, Home: tt.home}
+			h.Mobs.CreateMob(mob)
+
+			health := player.Health
+			for range 10 {
+				h.Step(context.Background())
+				if mob.State != tt.want {
+					t.Fatalf("mob is %s at %d,%d, want %s", mob.State, mob.X, mob.Y, tt.want)
+				}
+			}
+			if player.Health != health {
+				t.Errorf("player health = %d, want %d: the mob fought back", player.Health, health)
+			}
+			if (domain.Point{X: mob.X, Y: mob.Y}) == tt.mob {
+				t.Errorf("mob stayed at %d,%d", mob.X, mob.Y)
+			}
+		})
+	}
+}
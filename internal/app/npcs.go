@@ -0,0 +1,319 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+// npcInfo is an NPC standing in a player's view.
+type npcInfo struct {
+	ID     string `json:"id"`
+	NPC    string `json:"npc"`
+	Name   string `json:"name"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Symbol rune   `json:"symbol"`
+	Shop   bool   `json:"shop,omitempty"`
+}
+
+// dialogueInfo is the line an NPC says and the answers the player can give,
+// in order.
+type dialogueInfo struct {
+	NPC     string   `json:"npc"`
+	Name    string   `json:"name"`
+	Text    string   `json:"text"`
+	Choices []string `json:"choices"`
+}
+
+// shopItem is an item as listed by a shop. Price is what the shop sells it
+// for, zero when it does not, and SellPrice what it pays for one. Owned is
+// how many the player carries.
+type shopItem struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Price       int    `json:"price,omitempty"`
+	SellPrice   int    `json:"sellPrice"`
+	Owned       int    `json:"owned"`
+}
+
+// shopInfo lists what a shop sells along with what the player could sell
+// it.
+type shopInfo struct {
+	NPC   string     `json:"npc"`
+	Name  string     `json:"name"`
+	Gold  int        `json:"gold"`
+	Items []shopItem `json:"items"`
+}
+
+// dialogueMsg opens, moves or, with a nil Dialogue, closes the dialogue
+// panel of the client.
+type dialogueMsg struct {
+	Type     string        `json:"type"`
+	Dialogue *dialogueInfo `json:"dialogue,omitempty"`
+}
+
+type shopMsg struct {
+	Type string   `json:"type"`
+	Msg  string   `json:"msg,omitempty"`
+	Shop shopInfo `json:"shop"`
+}
+
+// dialogue is a conversation in progress between a player and the NPC
+// standing on spawn in worldID. shop is set once the NPC's shop is open.
+type dialogue struct {
+	worldID string
+	spawn   domain.NPCSpawn
+	npc     *domain.NPC
+	node    string
+	shop    bool
+}
+
+func (h *Handler) npc(id string) *domain.NPC {
+	for _, n := range h.NPCs {
+		if n.ID == id {
+			return n
+		}
+	}
+	return nil
+}
+
+func (h *Handler) item(id string) *domain.Item {
+	for _, i := range h.Items {
+		if i.ID == id {
+			return i
+		}
+	}
+	return nil
+}
+
+// npcsInView returns the NPCs of world the player can see.
+func (h *Handler) npcsInView(world *domain.World, fov *domain.FOV) []npcInfo {
+	var npcs []npcInfo
+	for _, s := range world.NPCs {
+		n := h.npc(s.NPC)
+		if n == nil || !fov.Visible(s.X, s.Y) {
+			continue
+		}
+		npcs = append(npcs, npcInfo{
+			ID: s.ID, NPC: n.ID, Name: n.Name, X: s.X, Y: s.Y, Symbol: n.Symbol, Shop: n.Shop != nil,
+		})
+	}
+	return npcs
+}
+
+// nextTo reports whether the player stands next to the NPC, close enough to
+// talk and trade.
+func nextTo(p *domain.Player, s domain.NPCSpawn) bool {
+	return max(abs(p.X-s.X), abs(p.Y-s.Y)) <= 1
+}
+
+func (h *Handler) setDialogue(playerID string, d *dialogue) {
+	h.dialogueMu.Lock()
+	defer h.dialogueMu.Unlock()
+
+	if d == nil {
+		delete(h.dialogues, playerID)
+		return
+	}
+	h.dialogues[playerID] = d
+}
+
+func (h *Handler) dialogueOf(playerID string) *dialogue {
+	h.dialogueMu.Lock()
+	defer h.dialogueMu.Unlock()
+
+	return h.dialogues[playerID]
+}
+
+// HandleInteract starts a conversation with the NPC targetID, or with the
+// one next to the player when targetID is empty.
+func (h *Handler) HandleInteract(ctx context.Context, cc *clientConn, p *domain.Player, targetID string) error {
+	if p == nil {
+		return fmt.Errorf("player not found")
+	}
+	world := h.Worlds.GetWorld(p.WorldID)
+	if world == nil {
+		return fmt.Errorf("world %s not found", p.WorldID)
+	}
+
+	var spawn *domain.NPCSpawn
+	for i, s := range world.NPCs {
+		if (targetID == "" || s.ID == targetID) && nextTo(p, s) {
+			spawn = &world.NPCs[i]
+			break
+		}
+	}
+	if spawn == nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: domain.ErrNoOneToTalk.Error()})
+	}
+	n := h.npc(spawn.NPC)
+	if n == nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: domain.ErrUnknownNPC.Error()})
+	}
+
+	d := &dialogue{worldID: world.ID, spawn: *spawn, npc: n, node: n.Start}
+	h.setDialogue(p.ID, d)
+	cc.log.Debug("player talks to npc", "npc", spawn.ID)
+	h.questEvent(p, domain.QuestEvent{Kind: domain.ObjectiveTalk, Target: n.ID})
+	return h.sendDialogue(cc, d)
+}
+
+// HandleChoose answers the current line of the player's conversation with
+// the choice at the given index, starting from 1.
+func (h *Handler) HandleChoose(ctx context.Context, cc *clientConn, p *domain.Player, choice string) error {
+	d, err := h.activeDialogue(cc, p)
+	if d == nil {
+		return err
+	}
+	node := d.npc.Dialogue[d.node]
+	i, err := strconv.Atoi(choice)
+	if err != nil || i < 1 || i > len(node.Choices) {
+		return cc.sendJson(serverMsg{Type: "error", Msg: fmt.Sprintf("invalid choice %q", choice)})
+	}
+	c := node.Choices[i-1]
+
+	if c.Action == domain.ActionShop {
+		d.shop = true
+		return cc.sendJson(shopMsg{Type: "shop", Shop: h.shopFor(d.npc, p)})
+	}
+	if c.Action == domain.ActionQuest {
+		if err := h.takeQuest(cc, p, c.Quest); err != nil {
+			return err
+		}
+	}
+	if c.Next == "" {
+		h.setDialogue(p.ID, nil)
+		return cc.sendJson(dialogueMsg{Type: "dialogue"})
+	}
+	d.node = c.Next
+	return h.sendDialogue(cc, d)
+}
+
+// HandleBuy buys one item from the shop the player has open.
+func (h *Handler) HandleBuy(ctx context.Context, cc *clientConn, p *domain.Player, itemID string) error {
+	d, err := h.activeShop(cc, p)
+	if d == nil {
+		return err
+	}
+	item := h.item(itemID)
+	if item == nil || !d.npc.Shop.Stocks(itemID) {
+		return cc.sendJson(serverMsg{Type: "error", Msg: fmt.Sprintf("%s does not sell that", d.npc.Name)})
+	}
+	unlock := h.lockPlayers(p.ID)
+	err = p.Buy(item, item.Price)
+	unlock()
+	if err != nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: err.Error()})
+	}
+	h.Player.SavePlayer(p)
+	cc.log.Info("player bought item", "item", item.ID, "npc", d.spawn.ID, "gold", item.Price)
+	h.questEvent(p, domain.QuestEvent{Kind: domain.ObjectiveCollect})
+	return h.sendTrade(cc, p, d, fmt.Sprintf("Bought %s for %d gold", item.Name, item.Price))
+}
+
+// HandleSell sells one of the player's items to the shop they have open.
+func (h *Handler) HandleSell(ctx context.Context, cc *clientConn, p *domain.Player, itemID string) error {
+	d, err := h.activeShop(cc, p)
+	if d == nil {
+		return err
+	}
+	item := h.item(itemID)
+	if item == nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: domain.ErrUnknownItem.Error()})
+	}
+	price := d.npc.Shop.SellPrice(item)
+	unlock := h.lockPlayers(p.ID)
+	err = p.Sell(item, price)
+	unlock()
+	if err != nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: err.Error()})
+	}
+	h.Player.SavePlayer(p)
+	cc.log.Info("player sold item", "item", item.ID, "npc", d.spawn.ID, "gold", price)
+	return h.sendTrade(cc, p, d, fmt.Sprintf("Sold %s for %d gold", item.Name, price))
+}
+
+// activeDialogue returns the player's conversation when they are still next
+// to the NPC. Otherwise it ends the conversation, tells the client, and
+// returns nil with the error of sending that.
+func (h *Handler) activeDialogue(cc *clientConn, p *domain.Player) (*dialogue, error) {
+	if p == nil {
+		return nil, fmt.Errorf("player not found")
+	}
+	d := h.dialogueOf(p.ID)
+	if d == nil {
+		return nil, cc.sendJson(serverMsg{Type: "error", Msg: "you are not talking to anyone"})
+	}
+	if p.WorldID != d.worldID || !nextTo(p, d.spawn) {
+		h.setDialogue(p.ID, nil)
+		cc.sendJson(dialogueMsg{Type: "dialogue"})
+		return nil, cc.sendJson(serverMsg{Type: "error", Msg: "you walked away from " + d.npc.Name})
+	}
+	return d, nil
+}
+
+func (h *Handler) activeShop(cc *clientConn, p *domain.Player) (*dialogue, error) {
+	d, err := h.activeDialogue(cc, p)
+	if d == nil {
+		return nil, err
+	}
+	if !d.shop {
+		return nil, cc.sendJson(serverMsg{Type: "error", Msg: d.npc.Name + " is not trading with you"})
+	}
+	return d, nil
+}
+
+func (h *Handler) sendDialogue(cc *clientConn, d *dialogue) error {
+	node := d.npc.Dialogue[d.node]
+	info := &dialogueInfo{NPC: d.spawn.ID, Name: d.npc.Name, Text: node.Text, Choices: []string{}}
+	for _, c := range node.Choices {
+		info.Choices = append(info.Choices, c.Text)
+	}
+	if len(node.Choices) == 0 {
+		// The NPC had the last word.
+		h.setDialogue(cc.playerID, nil)
+	}
+	return cc.sendJson(dialogueMsg{Type: "dialogue", Dialogue: info})
+}
+
+// sendTrade refreshes the player's gold and inventory along with the shop
+// after a trade.
+func (h *Handler) sendTrade(cc *clientConn, p *domain.Player, d *dialogue, msg string) error {
+	if err := cc.sendJson(serverMsg{Type: "playerUpdate", Player: p}); err != nil {
+		return err
+	}
+	return cc.sendJson(shopMsg{Type: "shop", Msg: msg, Shop: h.shopFor(d.npc, p)})
+}
+
+// shopFor lists the items the NPC sells, in order, followed by the other
+// items the player could sell it.
+func (h *Handler) shopFor(n *domain.NPC, p *domain.Player) shopInfo {
+	info := shopInfo{NPC: n.ID, Name: n.Name, Gold: p.Gold, Items: []shopItem{}}
+	listed := make(map[string]bool)
+	add := func(item *domain.Item, price int) {
+		listed[item.ID] = true
+		info.Items = append(info.Items, shopItem{
+			ID:          item.ID,
+			Name:        item.Name,
+			Description: item.Description,
+			Price:       price,
+			SellPrice:   n.Shop.SellPrice(item),
+			Owned:       p.Inventory[item.ID],
+		})
+	}
+	for _, id := range n.Shop.Sells {
+		if item := h.item(id); item != nil {
+			add(item, item.Price)
+		}
+	}
+	for _, item := range h.Items {
+		if !listed[item.ID] && p.Inventory[item.ID] > 0 {
+			add(item, 0)
+		}
+	}
+	return info
+}
@@ -0,0 +1,161 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+// WorldStat is a loaded world with how many players and mobs are in it.
+type WorldStat struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	Players int    `json:"players"`
+	Mobs    int    `json:"mobs"`
+}
+
+// OnlinePlayer is a connected player as operators see them.
+type OnlinePlayer struct {
+	ID        string      `json:"id"`
+	WorldID   string      `json:"worldID"`
+	X         int         `json:"x"`
+	Y         int         `json:"y"`
+	Level     int         `json:"level"`
+	Health    int         `json:"health"`
+	MaxHealth int         `json:"maxHealth"`
+	Role      domain.Role `json:"role"`
+}
+
+// Connection is an open game connection; PlayerID is empty until the
+// player joined.
+type Connection struct {
+	RemoteAddr string    `json:"remoteAddr"`
+	PlayerID   string    `json:"playerID"`
+	Since      time.Time `json:"since"`
+}
+
+// WorldStats lists the loaded worlds in configuration order.
+func (h *Handler) WorldStats() []WorldStat {
+	stats := []WorldStat{}
+	for _, id := range h.WorldIDs {
+		world := h.Worlds.GetWorld(id)
+		if world == nil {
+			continue
+		}
+		stats = append(stats, WorldStat{
+			ID: world.ID, Name: world.Name, Width: world.Width, Height: world.Height,
+			Players: len(h.playersInWorld(world.ID)),
+			Mobs:    h.Mobs.CountMobsInWorld(world.ID),
+		})
+	}
+	return stats
+}
+
+// OnlinePlayers lists the connected players by id.
+func (h *Handler) OnlinePlayers() []OnlinePlayer {
+	players := []OnlinePlayer{}
+	for _, conn := range h.connectionList() {
+		p := h.Player.GetPlayer(conn.playerID)
+		if p == nil {
+			continue
+		}
+		role := p.Role
+		if role == "" {
+			role = domain.RolePlayer
+		}
+		players = append(players, OnlinePlayer{
+			ID: p.ID, WorldID: p.WorldID, X: p.X, Y: p.Y, Level: p.Level,
+			Health: p.Health, MaxHealth: p.MaxHealth, Role: role,
+		})
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].ID < players[j].ID })
+	return players
+}
+
+// Connections lists the open game connections, oldest first.
+func (h *Handler) Connections() []Connection {
+	conns := []Connection{}
+	for _, conn := range h.connectionList() {
+		conns = append(conns, Connection{RemoteAddr: conn.conn.RemoteAddr().String(), PlayerID: conn.playerID, Since: conn.since})
+	}
+	sort.Slice(conns, func(i, j int) bool { return conns[i].Since.Before(conns[j].Since) })
+	return conns
+}
+
+// Kick disconnects the player, telling them why.
+func (h *Handler) Kick(playerID, why string) error {
+	conn := h.connForPlayer(playerID)
+	if conn == nil {
+		return fmt.Errorf("no player %q online", playerID)
+	}
+	h.kick(conn, why)
+	return nil
+}
+
+// Announce says text to every connected player and returns how many
+// connections it was sent to.
+func (h *Handler) Announce(text string) int {
+	conns := h.connectionList()
+	msg := chatMsg{Type: "chat", Channel: "announce", Msg: text}
+	for _, conn := range conns {
+		conn.sendJson(msg)
+	}
+	return len(conns)
+}
+
+// ErrNotDurable is returned by SaveState when the player store loses what it
+// saves with the server, so saving would not keep anything.
+var ErrNotDurable = errors.New("the player store does not keep players across restarts")
+
+// Durable reports whether the player store keeps players across restarts.
+func (h *Handler) Durable() bool {
+	d, ok := h.Player.(domain.DurableStore)
+	return ok && d.Durable()
+}
+
+// SaveState saves every online player at once and returns how many were
+// saved, or ErrNotDurable when the store would not keep them.
+func (h *Handler) SaveState() (int, error) {
+	if !h.Durable() {
+		return 0, ErrNotDurable
+	}
+	var players []*domain.Player
+	for _, conn := range h.connectionList() {
+		if p := h.Player.GetPlayer(conn.playerID); p != nil {
+			players = append(players, p)
+		}
+	}
+	if err := h.savePlayers(players...); err != nil {
+		return 0, err
+	}
+	h.Log.Debug("saved players", "count", len(players))
+	return len(players), nil
+}
+
+// savePlayers saves the players at once, holding their locks so that
+// nothing changes them halfway through.
+func (h *Handler) savePlayers(players ...*domain.Player) error {
+	ids := make([]string, len(players))
+	for i, p := range players {
+		ids[i] = p.ID
+	}
+	unlock := h.lockPlayers(ids...)
+	defer unlock()
+	return h.Player.SavePlayers(players...)
+}
+
+func (h *Handler) connectionList() []*clientConn {
+	h.connMutex.RLock()
+	defer h.connMutex.RUnlock()
+
+	conns := make([]*clientConn, 0, len(h.connections))
+	for conn := range h.connections {
+		conns = append(conns, conn)
+	}
+	return conns
+}
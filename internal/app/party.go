@@ -0,0 +1,407 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+// partyMember is a member of the player's party as shown in their HUD.
+type partyMember struct {
+	ID        string `json:"id"`
+	WorldID   string `json:"worldID"`
+	Level     int    `json:"level"`
+	Health    int    `json:"health"`
+	MaxHealth int    `json:"maxHealth"`
+}
+
+type partyInfo struct {
+	ID      string        `json:"id"`
+	Leader  string        `json:"leader"`
+	Members []partyMember `json:"members"`
+}
+
+// partyMsg sends the player their party, or tells them they are no longer
+// in one when Party is nil.
+type partyMsg struct {
+	Type  string     `json:"type"`
+	Party *partyInfo `json:"party,omitempty"`
+}
+
+// chatMsg is a message said on a chat channel.
+type chatMsg struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel"`
+	From    string `json:"from"`
+	Msg     string `json:"msg"`
+}
+
+// partyOf returns the party of the player, nil when they are in none. The
+// party must only be read or changed under partyMu.
+func (h *Handler) partyOf(playerID string) *domain.Party {
+	return h.partyByMember[playerID]
+}
+
+// partyMembers returns the IDs of the members of the player's party, or
+// just the player when they are in none.
+func (h *Handler) partyMembers(playerID string) []string {
+	h.partyMu.Lock()
+	defer h.partyMu.Unlock()
+
+	party := h.partyOf(playerID)
+	if party == nil {
+		return []string{playerID}
+	}
+	return append([]string(nil), party.Members...)
+}
+
+// HandlePartyInvite invites the player targetID into the player's party,
+// which is created once they accept if the player is not in one yet.
+func (h *Handler) HandlePartyInvite(cc *clientConn, p *domain.Player, targetID string) error {
+	if p == nil {
+		return fmt.Errorf("player not found")
+	}
+	target := h.connForPlayer(targetID)
+	if target == nil || targetID == p.ID {
+		return cc.sendJson(serverMsg{Type: "error", Msg: fmt.Sprintf("no player %q to invite", targetID)})
+	}
+
+	h.partyMu.Lock()
+	err := h.checkInvite(p.ID, targetID)
+	if err == nil {
+		h.invites[targetID] = p.ID
+	}
+	h.partyMu.Unlock()
+	if err != nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: err.Error()})
+	}
+
+	target.sendJson(serverMsg{Type: "success", Msg: fmt.Sprintf("%s invites you to their party: /accept or /decline", p.ID)})
+	return cc.sendJson(serverMsg{Type: "success", Msg: "Invited " + targetID})
+}
+
+// checkInvite tells why inviterID may not invite targetID, if anything.
+// It must be called under partyMu.
+func (h *Handler) checkInvite(inviterID, targetID string) error {
+	if h.partyOf(targetID) != nil {
+		return fmt.Errorf("%s is %w", targetID, domain.ErrInParty)
+	}
+	party := h.partyOf(inviterID)
+	switch {
+	case party == nil:
+		return nil
+	case party.Leader() != inviterID:
+		return domain.ErrNotPartyLeader
+	case len(party.Members) >= domain.MaxPartySize:
+		return domain.ErrPartyFull
+	}
+	return nil
+}
+
+// HandlePartyAccept joins the party of the player's pending invite.
+func (h *Handler) HandlePartyAccept(cc *clientConn, p *domain.Player) error {
+	if p == nil {
+		return fmt.Errorf("player not found")
+	}
+
+	h.partyMu.Lock()
+	party, err := h.acceptInvite(p.ID)
+	h.partyMu.Unlock()
+	if err != nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: err.Error()})
+	}
+
+	cc.log.Info("player joined party", "party", party.ID)
+	h.partySay(party.ID, p.ID+" joined the party")
+	h.sendParty(party.ID)
+	return nil
+}
+
+// acceptInvite adds playerID to the party of their inviter, creating it
+// when the inviter has none, and returns it. It must be called under
+// partyMu.
+func (h *Handler) acceptInvite(playerID string) (*domain.Party, error) {
+	inviterID, ok := h.invites[playerID]
+	if !ok {
+		return nil, domain.ErrNoInvite
+	}
+	delete(h.invites, playerID)
+	if h.connForPlayer(inviterID) == nil {
+		return nil, fmt.Errorf("%s is gone", inviterID)
+	}
+	if err := h.checkInvite(inviterID, playerID); err != nil {
+		return nil, err
+	}
+
+	party := h.partyOf(inviterID)
+	if party == nil {
+		h.partySeq++
+		party = domain.NewParty(fmt.Sprintf("party-%d", h.partySeq), inviterID)
+		h.parties[party.ID] = party
+		h.partyByMember[inviterID] = party
+	}
+	if err := party.Join(playerID); err != nil {
+		return nil, err
+	}
+	h.partyByMember[playerID] = party
+	return party, nil
+}
+
+// HandlePartyDecline turns down the player's pending invite.
+func (h *Handler) HandlePartyDecline(cc *clientConn, p *domain.Player) error {
+	if p == nil {
+		return fmt.Errorf("player not found")
+	}
+	h.partyMu.Lock()
+	inviterID, ok := h.invites[p.ID]
+	delete(h.invites, p.ID)
+	h.partyMu.Unlock()
+	if !ok {
+		return cc.sendJson(serverMsg{Type: "error", Msg: domain.ErrNoInvite.Error()})
+	}
+
+	if inviter := h.connForPlayer(inviterID); inviter != nil {
+		inviter.sendJson(serverMsg{Type: "success", Msg: p.ID + " declined your invite"})
+	}
+	return cc.sendJson(serverMsg{Type: "success", Msg: "Declined the invite of " + inviterID})
+}
+
+// HandlePartyLeave takes the player out of their party.
+func (h *Handler) HandlePartyLeave(cc *clientConn, p *domain.Player) error {
+	if p == nil {
+		return fmt.Errorf("player not found")
+	}
+	if !h.leaveParty(p.ID, p.ID+" left the party") {
+		return cc.sendJson(serverMsg{Type: "error", Msg: domain.ErrNotInParty.Error()})
+	}
+	return cc.sendJson(serverMsg{Type: "success", Msg: "You left the party"})
+}
+
+// HandlePartyKick lets the party leader remove targetID from the party.
+func (h *Handler) HandlePartyKick(cc *clientConn, p *domain.Player, targetID string) error {
+	if p == nil {
+		return fmt.Errorf("player not found")
+	}
+	h.partyMu.Lock()
+	party := h.partyOf(p.ID)
+	var err error
+	switch {
+	case party == nil:
+		err = domain.ErrNotInParty
+	case party.Leader() != p.ID:
+		err = domain.ErrNotPartyLeader
+	case targetID == p.ID || !party.Has(targetID):
+		err = fmt.Errorf("%s is not in your party", targetID)
+	}
+	h.partyMu.Unlock()
+	if err != nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: err.Error()})
+	}
+
+	h.leaveParty(targetID, targetID+" was kicked from the party")
+	if target := h.connForPlayer(targetID); target != nil {
+		target.sendJson(serverMsg{Type: "success", Msg: "You were kicked from the party"})
+	}
+	return nil
+}
+
+// leaveParty removes playerID from their party, disbanding it when a single
+// member is left, and tells the remaining members why. It reports whether
+// the player was in a party.
+func (h *Handler) leaveParty(playerID, why string) bool {
+	h.partyMu.Lock()
+	party := h.partyOf(playerID)
+	if party == nil {
+		h.partyMu.Unlock()
+		return false
+	}
+	party.Leave(playerID)
+	delete(h.partyByMember, playerID)
+	disbanded := len(party.Members) < 2
+	remaining := append([]string(nil), party.Members...)
+	if disbanded {
+		for _, id := range party.Members {
+			delete(h.partyByMember, id)
+		}
+		delete(h.parties, party.ID)
+	}
+	h.partyMu.Unlock()
+
+	h.Log.Info("player left party", "player", playerID, "party", party.ID, "reason", why)
+	if cc := h.connForPlayer(playerID); cc != nil {
+		cc.sendJson(partyMsg{Type: "party"})
+	}
+	for _, id := range remaining {
+		cc := h.connForPlayer(id)
+		if cc == nil {
+			continue
+		}
+		cc.sendJson(chatMsg{Type: "chat", Channel: "party", Msg: why})
+		if disbanded {
+			cc.sendJson(partyMsg{Type: "party"})
+			cc.sendJson(serverMsg{Type: "success", Msg: "The party disbanded"})
+		}
+	}
+	if !disbanded {
+		h.sendParty(party.ID)
+	}
+	return true
+}
+
+// HandlePartyChat says text to the player's party.
+func (h *Handler) HandlePartyChat(cc *clientConn, p *domain.Player, text string) error {
+	if p == nil {
+		return fmt.Errorf("player not found")
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	h.partyMu.Lock()
+	party := h.partyOf(p.ID)
+	h.partyMu.Unlock()
+	if party == nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: domain.ErrNotInParty.Error()})
+	}
+
+	for _, id := range h.partyMembers(p.ID) {
+		if member := h.connForPlayer(id); member != nil {
+			member.sendJson(chatMsg{Type: "chat", Channel: "party", From: p.ID, Msg: text})
+		}
+	}
+	return nil
+}
+
+// partySay tells the members of the party about something that happened to
+// it.
+func (h *Handler) partySay(partyID, text string) {
+	h.partyMu.Lock()
+	party := h.parties[partyID]
+	var members []string
+	if party != nil {
+		members = append(members, party.Members...)
+	}
+	h.partyMu.Unlock()
+
+	for _, id := range members {
+		if cc := h.connForPlayer(id); cc != nil {
+			cc.sendJson(chatMsg{Type: "chat", Channel: "party", Msg: text})
+		}
+	}
+}
+
+// HandleWho lists the players in the player's world, so they know whom to
+// invite.
+func (h *Handler) HandleWho(cc *clientConn, p *domain.Player) error {
+	if p == nil {
+		return fmt.Errorf("player not found")
+	}
+	var names []string
+	for _, other := range h.playersInWorld(p.WorldID) {
+		name := fmt.Sprintf("%s (Lv %d)", other.ID, other.Level)
+		if other.ID == p.ID {
+			name += " (you)"
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return cc.sendJson(serverMsg{Type: "success", Msg: "Players here: " + strings.Join(names, ", ")})
+}
+
+// sendParty sends the party with the health of its members to each of them.
+func (h *Handler) sendParty(partyID string) {
+	h.partyMu.Lock()
+	party := h.parties[partyID]
+	if party == nil {
+		h.partyMu.Unlock()
+		return
+	}
+	info := &partyInfo{ID: party.ID, Leader: party.Leader()}
+	members := append([]string(nil), party.Members...)
+	h.partyMu.Unlock()
+
+	for _, id := range members {
+		member := partyMember{ID: id}
+		if p := h.Player.GetPlayer(id); p != nil {
+			member.WorldID, member.Level = p.WorldID, p.Level
+			member.Health, member.MaxHealth = p.Health, p.MaxHealth
+		}
+		info.Members = append(info.Members, member)
+	}
+	for _, id := range members {
+		if cc := h.connForPlayer(id); cc != nil {
+			cc.sendJson(partyMsg{Type: "party", Party: info})
+		}
+	}
+}
+
+// BroadcastParties sends every party member the health of the others. The
+// game loop calls it once per tick.
+func (h *Handler) BroadcastParties() {
+	h.partyMu.Lock()
+	ids := make([]string, 0, len(h.parties))
+	for id := range h.parties {
+		ids = append(ids, id)
+	}
+	h.partyMu.Unlock()
+
+	for _, id := range ids {
+		h.sendParty(id)
+	}
+}
+
+// disconnect forgets what a player leaving the game leaves behind: their
+// party membership, their invites, their trade, their duel and their
+// conversation.
+func (h *Handler) disconnect(playerID string) {
+	h.leaveParty(playerID, playerID+" disconnected")
+	h.cancelTrade(playerID, playerID+" disconnected")
+	h.forgetTradeRequests(playerID)
+	h.forfeitDuel(playerID)
+
+	h.partyMu.Lock()
+	delete(h.invites, playerID)
+	for invitee, inviter := range h.invites {
+		if inviter == playerID {
+			delete(h.invites, invitee)
+		}
+	}
+	h.partyMu.Unlock()
+
+	h.setDialogue(playerID, nil)
+}
+
+// killSharers returns the players sharing the rewards of a mob killed by p:
+// p first, then the living members of their party in the same world within
+// domain.PartyShareRange of the mob.
+func (h *Handler) killSharers(p *domain.Player, mob *domain.Mob) []*domain.Player {
+	sharers := []*domain.Player{p}
+	for _, id := range h.partyMembers(p.ID) {
+		if id == p.ID {
+			continue
+		}
+		member := h.Player.GetPlayer(id)
+		if member == nil || !member.IsAlive() || member.WorldID != mob.WorldID {
+			continue
+		}
+		if max(abs(member.X-mob.X), abs(member.Y-mob.Y)) <= domain.PartyShareRange {
+			sharers = append(sharers, member)
+		}
+	}
+	return sharers
+}
+
+// nextLooter picks which of the n sharers of a kill by playerID gets its
+// loot: the party members take turns, and players alone get it all.
+func (h *Handler) nextLooter(playerID string, n int) int {
+	h.partyMu.Lock()
+	defer h.partyMu.Unlock()
+
+	party := h.partyOf(playerID)
+	if party == nil || n <= 1 {
+		return 0
+	}
+	return party.NextLooter(n)
+}
@@ -0,0 +1,260 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+// playerProjectile is what players shoot.
+const playerProjectile = "arrow"
+
+// launchProjectile fires a projectile of the given kind and keeps it in
+// flight until MoveProjectiles resolves it.
+func (h *Handler) launchProjectile(kind, worldID, ownerID, owner string, fromMob bool, damage int, from, to domain.Point) (*domain.Projectile, error) {
+	h.projMu.Lock()
+	defer h.projMu.Unlock()
+
+	h.projSeq++
+	proj, err := domain.NewProjectile(kind, fmt.Sprintf("proj-%d", h.projSeq), worldID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	proj.OwnerID, proj.Owner, proj.FromMob, proj.Damage = ownerID, owner, fromMob, damage
+	h.projectiles = append(h.projectiles, proj)
+	return proj, nil
+}
+
+// projectilesInWorld returns the projectiles in flight in the world, oldest
+// first.
+func (h *Handler) projectilesInWorld(worldID string) []*domain.Projectile {
+	h.projMu.Lock()
+	defer h.projMu.Unlock()
+
+	var projs []*domain.Projectile
+	for _, proj := range h.projectiles {
+		if proj.WorldID == worldID {
+			projs = append(projs, proj)
+		}
+	}
+	return projs
+}
+
+func (h *Handler) removeProjectile(id string) {
+	h.projMu.Lock()
+	defer h.projMu.Unlock()
+
+	for i, proj := range h.projectiles {
+		if proj.ID == id {
+			h.projectiles = append(h.projectiles[:i], h.projectiles[i+1:]...)
+			return
+		}
+	}
+}
+
+// HandlePlayerShoot fires an arrow at the mob targetID, in direction dir
+// when there is no target, or at the nearest mob in range when neither is
+// given. Shooting shares the cooldown of melee attacks.
+func (h *Handler) HandlePlayerShoot(ctx context.Context, cc *clientConn, p *domain.Player, targetID, dir string) error {
+	if p == nil {
+		return fmt.Errorf("player not found")
+	}
+	world := h.Worlds.GetWorld(p.WorldID)
+	if world == nil {
+		return fmt.Errorf("world %s not found", p.WorldID)
+	}
+	kind, ok := domain.LookupProjectile(playerProjectile)
+	if !ok {
+		return domain.ErrUnknownProjectile
+	}
+	if world.RegionAt(p.X, p.Y) == domain.RegionSafe {
+		return cc.sendJson(serverMsg{Type: "error", Msg: domain.ErrSafeZone.Error()})
+	}
+
+	from := domain.Point{X: p.X, Y: p.Y}
+	var to domain.Point
+	switch {
+	case targetID != "":
+		mob := h.Mobs.GetMob(targetID)
+		if mob == nil || mob.WorldID != p.WorldID {
+			return cc.sendJson(serverMsg{Type: "error", Msg: "target is gone"})
+		}
+		to = domain.Point{X: mob.X, Y: mob.Y}
+		if !inReach(world, p, to, kind.Range) {
+			return cc.sendJson(serverMsg{Type: "error", Msg: mob.Name + " is out of range or out of sight"})
+		}
+	case dir != "":
+		next, ok := domain.Step(from, dir)
+		if !ok {
+			return cc.sendJson(serverMsg{Type: "error", Msg: domain.ErrNothingToShoot.Error()})
+		}
+		to = next
+	default:
+		mob := h.nearestMobInReach(world, p, kind.Range)
+		if mob == nil {
+			return cc.sendJson(serverMsg{Type: "error", Msg: "no mob in range to shoot"})
+		}
+		to = domain.Point{X: mob.X, Y: mob.Y}
+	}
+
+	err := p.Shoot(h.Clock.Now(), h.AttackCooldown)
+	if errors.Is(err, domain.ErrAttackCooldown) {
+		return cc.sendJson(serverMsg{
+			Type: "error",
+			Msg:  fmt.Sprintf("You can shoot again in %.1fs", p.CooldownLeft(h.Clock.Now()).Seconds()),
+		})
+	}
+	if err != nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: err.Error()})
+	}
+	if _, err := h.launchProjectile(kind.Name, world.ID, p.ID, p.ID, false, p.Attack, from, to); err != nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: err.Error()})
+	}
+	h.Player.SavePlayer(p)
+	return nil
+}
+
+// HandleMobShoot makes an engaged ranged mob shoot at the nearest player it
+// can see within range once it has reloaded, and reports whether it did.
+func (h *Handler) HandleMobShoot(ctx context.Context, mob *domain.Mob) bool {
+	ranged, ok := mob.Ranged()
+	if !ok || mob.Stunned() || !mob.Engaged() || !mob.Reloaded() {
+		return false
+	}
+	world := h.Worlds.GetWorld(mob.WorldID)
+	if world == nil {
+		return false
+	}
+
+	from := domain.Point{X: mob.X, Y: mob.Y}
+	var target *domain.Player
+	minDist := ranged.Range + 1
+	for _, player := range h.playersInWorld(mob.WorldID) {
+		to := domain.Point{X: player.X, Y: player.Y}
+		dist := max(abs(to.X-from.X), abs(to.Y-from.Y))
+		if player.IsAlive() && dist < minDist && world.CanSee(from, to) && world.RegionAt(to.X, to.Y) != domain.RegionSafe {
+			minDist = dist
+			target = player
+		}
+	}
+	if target == nil {
+		return false
+	}
+
+	to := domain.Point{X: target.X, Y: target.Y}
+	if _, err := h.launchProjectile(ranged.Projectile, world.ID, mob.ID, mob.Name, true, mob.Attack, from, to); err != nil {
+		h.Log.Warn("mob failed to shoot", "mob", mob.ID, "world", mob.WorldID, "err", err)
+		return false
+	}
+	mob.Shot()
+	h.Mobs.SaveMob(mob)
+	return true
+}
+
+// MoveProjectiles advances every projectile in the world and resolves the
+// ones that hit something. Projectiles stop at the first mob or player in
+// their way other than whoever fired them, but only hurt the other side:
+// mobs do not shoot each other and players do not shoot players.
+func (h *Handler) MoveProjectiles(ctx context.Context, worldID string) {
+	world := h.Worlds.GetWorld(worldID)
+	if world == nil {
+		return
+	}
+
+	for _, proj := range h.projectilesInWorld(worldID) {
+		cell, hit := proj.Advance(world, func(c domain.Point) bool {
+			return h.entityAt(worldID, c, proj.OwnerID)
+		})
+		spent := proj.Spent()
+		if hit && h.projectileHit(world, proj, cell) {
+			spent = true
+		}
+		if spent {
+			h.removeProjectile(proj.ID)
+		}
+	}
+}
+
+// entityAt reports whether a mob or a living player other than ignoreID is
+// on the cell.
+func (h *Handler) entityAt(worldID string, c domain.Point, ignoreID string) bool {
+	if mob := h.mobAt(worldID, c); mob != nil && mob.ID != ignoreID {
+		return true
+	}
+	player := h.playerAt(worldID, c)
+	return player != nil && player.ID != ignoreID
+}
+
+func (h *Handler) mobAt(worldID string, c domain.Point) *domain.Mob {
+	for _, mob := range h.Mobs.GetMobsByWorld(worldID) {
+		if mob.X == c.X && mob.Y == c.Y {
+			return mob
+		}
+	}
+	return nil
+}
+
+func (h *Handler) playerAt(worldID string, c domain.Point) *domain.Player {
+	for _, player := range h.playersInWorld(worldID) {
+		if player.IsAlive() && player.X == c.X && player.Y == c.Y {
+			return player
+		}
+	}
+	return nil
+}
+
+// projectileHit resolves a projectile reaching an entity on cell and
+// reports whether it was stopped. Projectiles that miss fly on.
+func (h *Handler) projectileHit(world *domain.World, proj *domain.Projectile, cell domain.Point) bool {
+	if mob := h.mobAt(world.ID, cell); mob != nil {
+		if proj.FromMob {
+			return true
+		}
+		roll := h.rollAttack(world.ID)
+		damage := 0
+		if roll != domain.AttackMiss {
+			damage = mob.TakeDamage(roll.Damage(proj.Damage))
+			if damage > 0 && proj.Inflicts.Kind != "" {
+				mob.Statuses.Apply(proj.Inflicts)
+			}
+		}
+
+		cc := h.connForPlayer(proj.OwnerID)
+		if cc != nil {
+			cc.sendJson(playerHitMob(mob, proj.Kind, roll, damage))
+		}
+		if mob.IsAlive() {
+			mob.Provoke(proj.OwnerID)
+			h.Mobs.SaveMob(mob)
+			return roll != domain.AttackMiss
+		}
+		owner := h.Player.GetPlayer(proj.OwnerID)
+		if owner == nil {
+			h.Mobs.DeleteMob(mob.ID)
+			return true
+		}
+		msg := h.killMob(owner, mob)
+		h.Player.SavePlayer(owner)
+		if cc != nil {
+			cc.sendJson(serverMsg{Type: "playerUpdate", Msg: msg, Player: owner})
+		}
+		return true
+	}
+
+	player := h.playerAt(world.ID, cell)
+	if player == nil {
+		return false
+	}
+	if !proj.FromMob || world.RegionAt(player.X, player.Y) == domain.RegionSafe {
+		return true
+	}
+	roll := h.rollAttack(world.ID)
+	damage := 0
+	if roll != domain.AttackMiss {
+		damage = player.TakeDamage(roll.Damage(proj.Damage))
+	}
+	h.mobHitPlayer(proj.Owner, proj.Kind, world.ID, player, roll, damage, proj.Inflicts)
+	return roll != domain.AttackMiss
+}
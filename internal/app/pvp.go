@@ -0,0 +1,311 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+// playerInfo is another player standing in view.
+type playerInfo struct {
+	ID        string `json:"id"`
+	X         int    `json:"x"`
+	Y         int    `json:"y"`
+	Level     int    `json:"level"`
+	Health    int    `json:"health"`
+	MaxHealth int    `json:"maxHealth"`
+}
+
+// playersInView returns the living players other than player that are
+// visible in fov.
+func (h *Handler) playersInView(player *domain.Player, world *domain.World, fov *domain.FOV) []playerInfo {
+	var infos []playerInfo
+	for _, other := range h.playersInWorld(world.ID) {
+		if other.ID == player.ID || !other.IsAlive() || !fov.Visible(other.X, other.Y) {
+			continue
+		}
+		infos = append(infos, playerInfo{
+			ID: other.ID, X: other.X, Y: other.Y, Level: other.Level,
+			Health: other.Health, MaxHealth: other.MaxHealth,
+		})
+	}
+	return infos
+}
+
+// HandleDuelRequest challenges targetID to a duel, or accepts their
+// challenge when they made one first, which starts the countdown.
+func (h *Handler) HandleDuelRequest(cc *clientConn, p *domain.Player, targetID string) error {
+	if p == nil {
+		return fmt.Errorf("player not found")
+	}
+	targetConn := h.connForPlayer(targetID)
+	target := h.Player.GetPlayer(targetID)
+	if targetConn == nil || target == nil || targetID == p.ID {
+		return cc.sendJson(serverMsg{Type: "error", Msg: fmt.Sprintf("no player %q to duel", targetID)})
+	}
+	world := h.Worlds.GetWorld(p.WorldID)
+	if world == nil {
+		return fmt.Errorf("world %s not found", p.WorldID)
+	}
+	if target.WorldID != p.WorldID || max(abs(target.X-p.X), abs(target.Y-p.Y)) > domain.DuelRange {
+		return cc.sendJson(serverMsg{Type: "error", Msg: fmt.Sprintf("%s is %s", targetID, domain.ErrTooFarToDuel)})
+	}
+	if world.RegionAt(p.X, p.Y) == domain.RegionSafe || world.RegionAt(target.X, target.Y) == domain.RegionSafe {
+		return cc.sendJson(serverMsg{Type: "error", Msg: domain.ErrSafeZone.Error()})
+	}
+	if p.Health <= p.YieldHealth() {
+		return cc.sendJson(serverMsg{Type: "error", Msg: "you are " + domain.ErrTooHurtToDuel.Error()})
+	}
+	if target.Health <= target.YieldHealth() {
+		return cc.sendJson(serverMsg{Type: "error", Msg: fmt.Sprintf("%s is %s", targetID, domain.ErrTooHurtToDuel)})
+	}
+
+	h.duelMu.Lock()
+	var duel *domain.Duel
+	var err error
+	switch {
+	case h.duels[p.ID] != nil:
+		err = domain.ErrDueling
+	case h.duels[targetID] != nil:
+		err = fmt.Errorf("%s is %w", targetID, domain.ErrDueling)
+	case h.duelRequests[p.ID] == targetID:
+		delete(h.duelRequests, p.ID)
+		delete(h.duelRequests, targetID)
+		h.duelSeq++
+		duel = domain.NewDuel(fmt.Sprintf("duel-%d", h.duelSeq), targetID, p.ID, h.Clock.Now())
+		h.duels[p.ID], h.duels[targetID] = duel, duel
+	default:
+		h.duelRequests[targetID] = p.ID
+	}
+	h.duelMu.Unlock()
+	if err != nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: err.Error()})
+	}
+
+	if duel == nil {
+		targetConn.sendJson(serverMsg{Type: "success", Msg: fmt.Sprintf("%s challenges you to a duel: /duel %s", p.ID, p.ID)})
+		return cc.sendJson(serverMsg{Type: "success", Msg: "Challenged " + targetID + " to a duel"})
+	}
+	h.Log.Info("duel started", "duel", duel.ID, "players", duel.Players)
+	for _, id := range duel.Players {
+		if conn := h.connForPlayer(id); conn != nil {
+			conn.sendJson(serverMsg{Type: "success", Msg: fmt.Sprintf("Duel with %s starts in %d seconds", duel.Opponent(id), int(domain.DuelCountdown.Seconds()))})
+		}
+	}
+	return nil
+}
+
+// TickDuels starts the duels whose countdown is over and ends those whose
+// duelists parted. The game loop calls it once per tick.
+func (h *Handler) TickDuels(ctx context.Context) {
+	now := h.Clock.Now()
+	var started, parted []*domain.Duel
+
+	h.duelMu.Lock()
+	seen := make(map[*domain.Duel]bool)
+	for _, duel := range h.duels {
+		if seen[duel] {
+			continue
+		}
+		seen[duel] = true
+		a, b := h.Player.GetPlayer(duel.Players[0]), h.Player.GetPlayer(duel.Players[1])
+		if a == nil || b == nil || a.WorldID != b.WorldID || max(abs(a.X-b.X), abs(a.Y-b.Y)) > domain.DuelRange {
+			parted = append(parted, duel)
+			continue
+		}
+		if !duel.Fighting && !now.Before(duel.Start) {
+			duel.Fighting = true
+			started = append(started, duel)
+		}
+	}
+	h.duelMu.Unlock()
+
+	for _, duel := range started {
+		for _, id := range duel.Players {
+			if conn := h.connForPlayer(id); conn != nil {
+				conn.sendJson(serverMsg{Type: "success", Msg: "Fight!"})
+			}
+		}
+	}
+	for _, duel := range parted {
+		h.endDuel(duel, "", "the duelists parted")
+	}
+}
+
+// endDuel closes the duel and credits winnerID, if any, telling both
+// duelists how it ended.
+func (h *Handler) endDuel(duel *domain.Duel, winnerID, why string) {
+	h.duelMu.Lock()
+	if h.duels[duel.Players[0]] != duel {
+		h.duelMu.Unlock()
+		return
+	}
+	for _, id := range duel.Players {
+		delete(h.duels, id)
+	}
+	h.duelMu.Unlock()
+
+	h.Log.Info("duel ended", "duel", duel.ID, "players", duel.Players, "winner", winnerID, "reason", why)
+	if winner := h.Player.GetPlayer(winnerID); winner != nil {
+		winner.DuelsWon++
+		h.Player.SavePlayer(winner)
+	}
+	for _, id := range duel.Players {
+		conn := h.connForPlayer(id)
+		if conn == nil {
+			continue
+		}
+		msg := "Duel over: " + why
+		switch winnerID {
+		case "":
+		case id:
+			msg = fmt.Sprintf("You won the duel against %s: %s", duel.Opponent(id), why)
+		default:
+			msg = fmt.Sprintf("You lost the duel against %s: %s", winnerID, why)
+		}
+		conn.sendJson(serverMsg{Type: "playerUpdate", Msg: msg, Player: h.Player.GetPlayer(id)})
+	}
+}
+
+// forfeitDuel ends the duel of playerID in favour of their opponent, and
+// drops the challenges made by or to them.
+func (h *Handler) forfeitDuel(playerID string) {
+	h.duelMu.Lock()
+	duel := h.duels[playerID]
+	delete(h.duelRequests, playerID)
+	for target, challenger := range h.duelRequests {
+		if challenger == playerID {
+			delete(h.duelRequests, target)
+		}
+	}
+	h.duelMu.Unlock()
+
+	if duel != nil {
+		h.endDuel(duel, duel.Opponent(playerID), playerID+" left")
+	}
+}
+
+// hostility tells whether p may attack target now. Duelists may fight once
+// the countdown is over, down to the health at which the target yields,
+// and anyone may fight to the death inside PvP zones. Nobody fights inside
+// safe zones.
+func (h *Handler) hostility(world *domain.World, p, target *domain.Player) (floor int, duel *domain.Duel, err error) {
+	if world.RegionAt(p.X, p.Y) == domain.RegionSafe || world.RegionAt(target.X, target.Y) == domain.RegionSafe {
+		return 0, nil, domain.ErrSafeZone
+	}
+
+	h.duelMu.Lock()
+	duel = h.duels[p.ID]
+	if duel != nil && duel.Opponent(p.ID) != target.ID {
+		duel = nil
+	}
+	fighting := duel != nil && duel.Fighting
+	h.duelMu.Unlock()
+
+	switch {
+	case fighting:
+		return target.YieldHealth(), duel, nil
+	case duel != nil:
+		return 0, nil, domain.ErrDuelNotStarted
+	case world.RegionAt(p.X, p.Y) == domain.RegionPvP && world.RegionAt(target.X, target.Y) == domain.RegionPvP:
+		return 0, nil, nil
+	}
+	return 0, nil, domain.ErrNotHostile
+}
+
+// nearestHostileInReach returns the closest player p may attack within
+// reach, or nil when there is none.
+func (h *Handler) nearestHostileInReach(world *domain.World, p *domain.Player, reach int) *domain.Player {
+	var nearest *domain.Player
+	minDist := reach + 1
+	for _, other := range h.playersInWorld(world.ID) {
+		if other.ID == p.ID || !other.IsAlive() {
+			continue
+		}
+		dist := abs(p.X-other.X) + abs(p.Y-other.Y)
+		if dist >= minDist || !inReach(world, p, domain.Point{X: other.X, Y: other.Y}, reach) {
+			continue
+		}
+		if _, _, err := h.hostility(world, p, other); err == nil {
+			minDist = dist
+			nearest = other
+		}
+	}
+	return nearest
+}
+
+// attackPlayer makes p attack target. A duelist brought down to their
+// yield health loses the duel; a player killed in a PvP zone respawns and
+// their killer is credited with the kill.
+func (h *Handler) attackPlayer(cc *clientConn, world *domain.World, p, target *domain.Player) error {
+	if !target.IsAlive() {
+		return cc.sendJson(serverMsg{Type: "error", Msg: "target is gone"})
+	}
+	if !inReach(world, p, domain.Point{X: target.X, Y: target.Y}, p.Range) {
+		return cc.sendJson(serverMsg{Type: "error", Msg: target.ID + " is out of range or out of sight"})
+	}
+	floor, duel, err := h.hostility(world, p, target)
+	if err != nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: err.Error()})
+	}
+
+	roll := h.rollAttack(world.ID)
+	damage, err := p.AttackPlayer(target, roll, h.Clock.Now(), h.AttackCooldown, floor)
+	if errors.Is(err, domain.ErrAttackCooldown) {
+		return cc.sendJson(serverMsg{
+			Type: "error",
+			Msg:  fmt.Sprintf("You can attack again in %.1fs", p.CooldownLeft(h.Clock.Now()).Seconds()),
+		})
+	}
+	if err != nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: err.Error()})
+	}
+
+	ev := newCombatEvent(roll.String(), "You", target.ID, target.ID, damage, target.Health, target.MaxHealth)
+	theirs := newCombatEvent(roll.String(), p.ID, "you", target.ID, damage, target.Health, target.MaxHealth)
+	switch {
+	case roll == domain.AttackMiss:
+		ev.Msg = "You miss " + target.ID
+		theirs.Msg = p.ID + " misses you"
+	case !target.IsAlive():
+		ev.Kind, theirs.Kind = combatKill, combatDeath
+		ev.Msg = fmt.Sprintf("You hit %s for %d and kill them", target.ID, damage)
+		theirs.Msg = fmt.Sprintf("%s hits you for %d, you died", p.ID, damage)
+	default:
+		ev.Msg = fmt.Sprintf("You hit %s for %d", target.ID, damage)
+		theirs.Msg = fmt.Sprintf("%s hits you for %d", p.ID, damage)
+	}
+	if err := cc.sendJson(ev); err != nil {
+		return err
+	}
+	targetConn := h.connForPlayer(target.ID)
+	if targetConn != nil {
+		targetConn.sendJson(theirs)
+	}
+
+	var msg string
+	switch {
+	case duel != nil && target.Health <= floor:
+		h.Player.SavePlayer(target)
+		h.Player.SavePlayer(p)
+		h.endDuel(duel, p.ID, target.ID+" yields")
+		return nil
+	case !target.IsAlive():
+		xp := target.PvPXPReward()
+		p.PvPKills++
+		msg = fmt.Sprintf("You killed %s: +%d XP", target.ID, xp)
+		if p.GainXP(xp) {
+			msg += fmt.Sprintf(", you reached level %d!", p.Level)
+		}
+		h.respawnPlayer(world, target)
+		h.Log.Info("player killed", "player", target.ID, "by", p.ID, "world", world.ID)
+	}
+	h.Player.SavePlayer(target)
+	h.Player.SavePlayer(p)
+	if targetConn != nil {
+		targetConn.sendJson(serverMsg{Type: "playerUpdate", Player: target})
+	}
+	return cc.sendJson(serverMsg{Type: "playerUpdate", Msg: msg, Player: p})
+}
@@ -0,0 +1,196 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+// objectiveInfo is a quest objective as shown in the journal.
+type objectiveInfo struct {
+	Text   string `json:"text"`
+	Count  int    `json:"count"`
+	Needed int    `json:"needed"`
+	Done   bool   `json:"done"`
+}
+
+// questInfo is a quest the player took, with the objectives met so far and
+// the current one. Later objectives stay hidden until reached.
+type questInfo struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Objectives  []objectiveInfo `json:"objectives"`
+	Reward      string          `json:"reward"`
+	Completed   bool            `json:"completed"`
+}
+
+type questsMsg struct {
+	Type   string      `json:"type"`
+	Quests []questInfo `json:"quests"`
+}
+
+func (h *Handler) quest(id string) *domain.Quest {
+	for _, q := range h.Quests {
+		if q.ID == id {
+			return q
+		}
+	}
+	return nil
+}
+
+// HandleSendQuests sends the player's quest journal: the quests in progress
+// followed by the completed ones, each in file order.
+func (h *Handler) HandleSendQuests(cc *clientConn, p *domain.Player) error {
+	if p == nil {
+		return fmt.Errorf("player not found")
+	}
+	msg := questsMsg{Type: "quests", Quests: []questInfo{}}
+	unlock := h.lockPlayers(p.ID)
+	for _, q := range h.Quests {
+		pr, ok := p.Quests[q.ID]
+		if !ok {
+			continue
+		}
+		info := questInfo{
+			ID:          q.ID,
+			Name:        q.Name,
+			Description: q.Description,
+			Reward:      h.describeReward(q.Reward),
+			Completed:   pr.Completed,
+		}
+		for i, o := range q.Objectives {
+			if i > pr.Step {
+				break
+			}
+			done := i < pr.Step || pr.Completed
+			count := pr.Count
+			if done {
+				count = o.Needed()
+			}
+			info.Objectives = append(info.Objectives, objectiveInfo{
+				Text: h.describeObjective(o), Count: count, Needed: o.Needed(), Done: done,
+			})
+		}
+		msg.Quests = append(msg.Quests, info)
+	}
+	unlock()
+	sort.SliceStable(msg.Quests, func(i, j int) bool {
+		return !msg.Quests[i].Completed && msg.Quests[j].Completed
+	})
+	return cc.sendJson(msg)
+}
+
+// takeQuest gives the player the quest questID offered by an NPC and
+// checks it right away, since they may already carry what it asks for.
+func (h *Handler) takeQuest(cc *clientConn, p *domain.Player, questID string) error {
+	q := h.quest(questID)
+	if q == nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: domain.ErrUnknownQuest.Error()})
+	}
+	unlock := h.lockPlayers(p.ID)
+	err := p.TakeQuest(q)
+	unlock()
+	if err != nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: err.Error()})
+	}
+	cc.log.Info("player took quest", "quest", q.ID)
+	if err := cc.sendJson(serverMsg{Type: "success", Msg: "Quest accepted: " + q.Name}); err != nil {
+		return err
+	}
+	if !h.questEvent(p, domain.QuestEvent{}) {
+		h.Player.SavePlayer(p)
+		return h.HandleSendQuests(cc, p)
+	}
+	return nil
+}
+
+// questEvent advances the player's quests with ev and grants the rewards of
+// the ones it completes. When any quest moved, it saves the player and sends
+// them the progress, their journal and their updated stats. It reports
+// whether any quest moved.
+func (h *Handler) questEvent(p *domain.Player, ev domain.QuestEvent) bool {
+	var msgs []string
+	unlock := h.lockPlayers(p.ID)
+	for _, q := range h.Quests {
+		pr, ok := p.Quests[q.ID]
+		if !ok || pr.Completed || !p.Advance(q, ev) {
+			continue
+		}
+		after := p.Quests[q.ID]
+		if !after.Completed {
+			o := q.Objectives[after.Step]
+			msgs = append(msgs, fmt.Sprintf("%s: %s (%d/%d)", q.Name, h.describeObjective(o), after.Count, o.Needed()))
+			continue
+		}
+		msg := fmt.Sprintf("Quest complete: %s!", q.Name)
+		if reward := h.describeReward(q.Reward); reward != "" {
+			msg += " " + reward
+		}
+		if p.Grant(q.Reward) {
+			msg += fmt.Sprintf(", you reached level %d!", p.Level)
+		}
+		msgs = append(msgs, msg)
+		h.Log.Info("player completed quest", "player", p.ID, "quest", q.ID)
+	}
+	unlock()
+	if len(msgs) == 0 {
+		return false
+	}
+	h.Player.SavePlayer(p)
+
+	cc := h.connForPlayer(p.ID)
+	if cc == nil {
+		return true
+	}
+	for _, msg := range msgs {
+		cc.sendJson(serverMsg{Type: "success", Msg: msg})
+	}
+	h.HandleSendQuests(cc, p)
+	cc.sendJson(serverMsg{Type: "playerUpdate", Player: p})
+	return true
+}
+
+// describeObjective returns the objective's text, or one made up from its
+// kind and target.
+func (h *Handler) describeObjective(o domain.Objective) string {
+	if o.Text != "" {
+		return o.Text
+	}
+	switch o.Kind {
+	case domain.ObjectiveKill:
+		return "Kill " + o.Target
+	case domain.ObjectiveCollect:
+		if item := h.item(o.Target); item != nil {
+			return "Collect " + item.Name
+		}
+	case domain.ObjectiveReach:
+		if world := h.Worlds.GetWorld(o.Target); world != nil {
+			return fmt.Sprintf("Reach %d,%d in %s", o.X, o.Y, world.Name)
+		}
+	case domain.ObjectiveTalk:
+		if n := h.npc(o.Target); n != nil {
+			return "Talk to " + n.Name
+		}
+	}
+	return fmt.Sprintf("%s %s", o.Kind, o.Target)
+}
+
+// describeReward lists the reward like "+50 XP, +10 gold, 2 Bread".
+func (h *Handler) describeReward(r domain.Reward) string {
+	var parts []string
+	if r.XP > 0 {
+		parts = append(parts, fmt.Sprintf("+%d XP", r.XP))
+	}
+	if r.Gold > 0 {
+		parts = append(parts, fmt.Sprintf("+%d gold", r.Gold))
+	}
+	for _, item := range h.Items {
+		if n := r.Items[item.ID]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, item.Name))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sync"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+// lockedRNG is a generator shared by the game loop and the connections.
+type lockedRNG struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func (r *lockedRNG) Intn(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.Intn(n)
+}
+
+func (r *lockedRNG) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.Float64()
+}
+
+// SetSeed reseeds every random choice of the simulation. Each world draws
+// from a generator of its own, seeded from seed and the world id, so a world
+// replays the same spawns, mob moves and combat rolls whatever happens in
+// the others.
+func (h *Handler) SetSeed(seed int64) {
+	h.rngMu.Lock()
+	defer h.rngMu.Unlock()
+	h.seed = seed
+	h.rngs = make(map[string]*lockedRNG)
+}
+
+// rand returns the generator of worldID, or of the server, for the choices
+// made outside of any world, when worldID is empty.
+func (h *Handler) rand(worldID string) domain.RNG {
+	h.rngMu.Lock()
+	defer h.rngMu.Unlock()
+	rng := h.rngs[worldID]
+	if rng == nil {
+		rng = &lockedRNG{rng: rand.New(rand.NewSource(worldSeed(h.seed, worldID)))}
+		h.rngs[worldID] = rng
+	}
+	return rng
+}
+
+// worldSeed derives the seed of a world's generator from the game seed.
+func worldSeed(seed int64, worldID string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(worldID))
+	return seed ^ int64(hash.Sum64())
+}
+
+func (h *Handler) rollAttack(worldID string) domain.AttackRoll {
+	return domain.RollAttack(h.rand(worldID))
+}
@@ -0,0 +1,75 @@
+package app_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/LealKevin/terminus/internal/app"
+	"github.com/LealKevin/terminus/internal/domain"
+	"github.com/LealKevin/terminus/internal/infra/datafile"
+	"github.com/LealKevin/terminus/internal/infra/mapfile"
+	"github.com/LealKevin/terminus/internal/infra/store"
+)
+
+// simulate steps a server seeded with seed through the bundled maps and
+// returns every mob, as "id x,y health state", once the steps are done.
+func simulate(t *testing.T, seed int64, steps int) []string {
+	t.Helper()
+	worlds, err := mapfile.LoadDir("../../maps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bosses, err := datafile.LoadBosses("../../data")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	worldStore := store.NewWorldMemoryStore()
+	var worldIDs []string
+	for id, w := range worlds {
+		worldStore.SaveWorld(w)
+		worldIDs = append(worldIDs, id)
+	}
+	slices.Sort(worldIDs)
+	mobStore := store.NewMobMemoryStore()
+
+	h := app.NewHandler(worldStore, store.NewPlayerMemoryStore(), mobStore)
+	h.WorldIDs = worldIDs
+	h.Bosses = bosses
+	h.Spawn = app.SpawnSettings{MobCap: 5, MobType: "Goblin"}
+	h.SetSeed(seed)
+	clock := domain.NewManualClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	h.Clock = clock
+
+	ctx := context.Background()
+	for range steps {
+		h.Step(ctx)
+		clock.Advance(100 * time.Millisecond)
+	}
+
+	var mobs []string
+	for _, id := range worldIDs {
+		for _, m := range mobStore.GetMobsByWorld(id) {
+			mobs = append(mobs, fmt.Sprintf("%s %d,%d %d %s", m.ID, m.X, m.Y, m.Health, m.State))
+		}
+	}
+	return mobs
+}
+
+func TestStepIsDeterministic(t *testing.T) {
+	const steps = 200
+	first := simulate(t, 42, steps)
+	if len(first) == 0 {
+		t.Fatal("no mobs spawned")
+	}
+	second := simulate(t, 42, steps)
+	if !slices.Equal(first, second) {
+		t.Errorf("same seed, different mobs after %d steps:\n%v\n%v", steps, first, second)
+	}
+	if other := simulate(t, 43, steps); slices.Equal(first, other) {
+		t.Errorf("seeds 42 and 43 left the same mobs after %d steps", steps)
+	}
+}
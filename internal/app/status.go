@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"fmt"
+)
+
+// TickStatuses advances the status effects of every player and mob in the
+// world by one tick, applying damage and healing over time. Players killed
+// by their afflictions respawn and mobs die without granting experience.
+func (h *Handler) TickStatuses(ctx context.Context, worldID string) {
+	world := h.Worlds.GetWorld(worldID)
+	if world == nil {
+		return
+	}
+
+	for _, player := range h.playersInWorld(worldID) {
+		if len(player.Statuses) == 0 {
+			continue
+		}
+		damage, healed := player.TickStatuses()
+
+		var msg string
+		switch {
+		case !player.IsAlive():
+			msg = "You succumbed to your afflictions"
+			h.respawnPlayer(world, player)
+			h.Log.Info("player died of status effects", "player", player.ID, "world", worldID)
+		case damage > 0:
+			msg = fmt.Sprintf("You suffer %d damage over time", damage)
+		case healed > 0:
+			msg = fmt.Sprintf("You regenerate %d health", healed)
+		}
+		h.Player.SavePlayer(player)
+
+		if cc := h.connForPlayer(player.ID); cc != nil {
+			cc.sendJson(serverMsg{Type: "playerUpdate", Msg: msg, Player: player})
+		}
+	}
+
+	for _, mob := range h.Mobs.GetMobsByWorld(worldID) {
+		if len(mob.Statuses) == 0 {
+			continue
+		}
+		mob.TickStatuses()
+		if !mob.IsAlive() {
+			h.Mobs.DeleteMob(mob.ID)
+			continue
+		}
+		h.Mobs.SaveMob(mob)
+	}
+}
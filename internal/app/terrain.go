@@ -0,0 +1,115 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+type tileUpdate struct {
+	Type  string `json:"type"`
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	Glyph byte   `json:"glyph"`
+}
+
+// broadcastTile tells the players in the world who know the cell at (x, y)
+// that it changed, e.g. after a door was opened.
+func (h *Handler) broadcastTile(world *domain.World, x, y int) {
+	if !world.InBounds(x, y) {
+		return
+	}
+	h.broadcastToWorld(world.ID, tileUpdate{
+		Type:  "tileUpdate",
+		X:     x,
+		Y:     y,
+		Glyph: world.Glyph(x, y),
+	}, func(p *domain.Player) bool {
+		unlock := h.lockPlayers(p.ID)
+		defer unlock()
+		return p.Explored.Has(world, x, y)
+	})
+}
+
+func (h *Handler) HandleToggleDoor(ctx context.Context, cc *clientConn, player *domain.Player, dir string) error {
+	if player == nil {
+		return fmt.Errorf("player not found")
+	}
+	world := h.Worlds.GetWorld(player.WorldID)
+	if world == nil {
+		return fmt.Errorf("world not found")
+	}
+
+	target, ok := domain.Step(domain.Point{X: player.X, Y: player.Y}, dir)
+	if !ok {
+		return cc.sendJson(serverMsg{Type: "error", Msg: fmt.Sprintf("invalid direction %q", dir)})
+	}
+	x, y := target.X, target.Y
+	if h.getOccupiedPositions(world.ID)[fmt.Sprintf("%d,%d", x, y)] {
+		return cc.sendJson(serverMsg{Type: "error", Msg: "something is in the way"})
+	}
+	if err := world.ToggleDoor(x, y); err != nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: err.Error()})
+	}
+
+	h.broadcastTile(world, x, y)
+	return cc.sendJson(serverMsg{Type: "success", Msg: "Door toggled"})
+}
+
+// ApplyHazards damages every player standing on a hazardous tile, such as
+// lava, applies the status effect it inflicts, and respawns the ones it
+// kills.
+func (h *Handler) ApplyHazards(ctx context.Context, worldID string) {
+	world := h.Worlds.GetWorld(worldID)
+	if world == nil {
+		return
+	}
+
+	for _, player := range h.playersInWorld(worldID) {
+		tile := world.TileAt(player.X, player.Y)
+		if !tile.IsHazard() {
+			continue
+		}
+		damage := player.TakeHazardDamage(tile.Damage)
+		if tile.Inflicts.Kind != "" {
+			player.Statuses.Apply(tile.Inflicts)
+		}
+
+		cc := h.connForPlayer(player.ID)
+		msg := fmt.Sprintf("The %s burns you for %d", tile.Name, damage)
+		if !player.IsAlive() {
+			msg = fmt.Sprintf("You died in the %s", tile.Name)
+			h.respawnPlayer(world, player)
+			h.Log.Info("player died on hazard", "player", player.ID, "tile", tile.Name, "world", worldID)
+		}
+		h.Player.SavePlayer(player)
+
+		if cc != nil {
+			cc.sendJson(serverMsg{Type: "playerUpdate", Msg: msg, Player: player})
+		}
+	}
+}
+
+func (h *Handler) respawnPlayer(world *domain.World, player *domain.Player) {
+	x, y, err := world.FindPlayerSpawnPosition(h.getOccupiedPositions(world.ID), h.rand(world.ID))
+	if err != nil {
+		h.Log.Error("unable to respawn player", "player", player.ID, "world", world.ID, "err", err)
+		return
+	}
+	player.Respawn(x, y)
+}
+
+func (h *Handler) playersInWorld(worldID string) []*domain.Player {
+	h.connMutex.RLock()
+	defer h.connMutex.RUnlock()
+
+	var players []*domain.Player
+	for conn := range h.connections {
+		player := h.Player.GetPlayer(conn.playerID)
+		if player != nil && player.WorldID == worldID {
+			players = append(players, player)
+		}
+	}
+	return players
+}
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+// TestToggleDoorWhileStepping toggles a door while the game loop computes
+// fields of view and paths through it; run it with -race.
+func TestToggleDoorWhileStepping(t *testing.T) {
+	world := testRoom()
+	world.Layout[5][10] = domain.TileDoor
+	player := domain.NewPlayer("alice", 9, 5)
+	h := newTestHandler(t, world, player)
+	h.Mobs.CreateMob(&domain.Mob{ID: "mob-1", Name: "Goblin", Type: "Goblin", WorldID: world.ID, X: 14, Y: 5,
+		Health: 100, MaxHealth: 100, State: domain.MobChase, TargetID: player.ID, Home: domain.Point{X: 14, Y: 5}})
+
+	ctx := context.Background()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 200 {
+			if err := world.ToggleDoor(10, 5); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for range 50 {
+		h.Step(ctx)
+	}
+	<-done
+
+	if g := world.Glyph(10, 5); g != domain.TileDoor {
+		t.Errorf("door cell is %q after an even number of toggles, want %q", g, domain.TileDoor)
+	}
+}
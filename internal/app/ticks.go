@@ -0,0 +1,153 @@
+package app
+
+import (
+	"time"
+)
+
+// The phases the game loop ticks are timed by.
+const (
+	PhaseSpawn       = "spawn"
+	PhaseAI          = "ai"
+	PhaseCombat      = "combat"
+	PhaseBroadcast   = "broadcast"
+	PhasePersistence = "persistence"
+)
+
+// TickPhases lists the phases in the order a tick runs them.
+var TickPhases = []string{PhaseSpawn, PhaseAI, PhaseCombat, PhaseBroadcast, PhasePersistence}
+
+// TickWindow is how many of the latest ticks the phase breakdown covers.
+const TickWindow = 120
+
+// TickTimer times the phases of one game loop tick. A phase may run
+// several times in a tick, once per world, and its laps add up.
+type TickTimer struct {
+	start  time.Time
+	lap    time.Time
+	phases map[string]time.Duration
+}
+
+// NewTickTimer starts timing a tick.
+func NewTickTimer() *TickTimer {
+	now := time.Now()
+	return &TickTimer{start: now, lap: now, phases: make(map[string]time.Duration, len(TickPhases))}
+}
+
+// Lap charges the time since the previous lap, or the start, to phase.
+func (t *TickTimer) Lap(phase string) {
+	now := time.Now()
+	t.phases[phase] += now.Sub(t.lap)
+	t.lap = now
+}
+
+// tickSample is the timing of a recorded tick.
+type tickSample struct {
+	total  time.Duration
+	phases map[string]time.Duration
+}
+
+// TickStats sums up how long the game loop ticks took, in milliseconds.
+// Ticks, MeanMs, MaxMs and Overruns cover every tick so far, and Phases the
+// Window latest ones.
+type TickStats struct {
+	Ticks    int64       `json:"ticks"`
+	LastMs   float64     `json:"lastMs"`
+	MeanMs   float64     `json:"meanMs"`
+	MaxMs    float64     `json:"maxMs"`
+	BudgetMs float64     `json:"budgetMs"`
+	Overruns int64       `json:"overruns"`
+	Window   int         `json:"window"`
+	Phases   []PhaseStat `json:"phases"`
+}
+
+// PhaseStat is the time a tick phase took over the latest ticks, with
+// Share the percentage of the tick time it accounts for.
+type PhaseStat struct {
+	Phase  string  `json:"phase"`
+	LastMs float64 `json:"lastMs"`
+	MeanMs float64 `json:"meanMs"`
+	MaxMs  float64 `json:"maxMs"`
+	Share  float64 `json:"share"`
+}
+
+// RecordTick accounts for the tick t timed, and warns when it overran the
+// tick budget, telling which phases the time went to.
+func (h *Handler) RecordTick(t *TickTimer) {
+	d := time.Since(t.start)
+	tickDuration.Observe(d.Seconds())
+	for phase, spent := range t.phases {
+		tickPhaseDuration.With(phase).Observe(spent.Seconds())
+	}
+	overrun := h.TickBudget > 0 && d > h.TickBudget
+
+	h.tickMu.Lock()
+	h.tickCount++
+	h.tickTotal += d
+	h.tickLast = d
+	h.tickMax = max(h.tickMax, d)
+	sample := tickSample{total: d, phases: t.phases}
+	if len(h.tickWindow) < TickWindow {
+		h.tickWindow = append(h.tickWindow, sample)
+	} else {
+		h.tickWindow[h.tickNext] = sample
+	}
+	h.tickNext = (h.tickNext + 1) % TickWindow
+	if overrun {
+		h.tickOverruns++
+	}
+	h.tickMu.Unlock()
+
+	if !overrun {
+		return
+	}
+	tickOverruns.Inc()
+	args := []any{"took", d, "budget", h.TickBudget}
+	for _, phase := range TickPhases {
+		args = append(args, phase, t.phases[phase])
+	}
+	h.Log.Warn("slow tick", args...)
+}
+
+// TickStats returns the timings of the ticks recorded so far.
+func (h *Handler) TickStats() TickStats {
+	h.tickMu.Lock()
+	defer h.tickMu.Unlock()
+
+	stats := TickStats{
+		Ticks: h.tickCount, LastMs: ms(h.tickLast), MaxMs: ms(h.tickMax),
+		BudgetMs: ms(h.TickBudget), Overruns: h.tickOverruns, Window: len(h.tickWindow),
+		Phases: []PhaseStat{},
+	}
+	if h.tickCount > 0 {
+		stats.MeanMs = ms(h.tickTotal / time.Duration(h.tickCount))
+	}
+	if len(h.tickWindow) == 0 {
+		return stats
+	}
+
+	last := h.tickWindow[(h.tickNext+len(h.tickWindow)-1)%len(h.tickWindow)]
+	var windowTotal time.Duration
+	for _, sample := range h.tickWindow {
+		windowTotal += sample.total
+	}
+	for _, phase := range TickPhases {
+		var sum, longest time.Duration
+		for _, sample := range h.tickWindow {
+			sum += sample.phases[phase]
+			longest = max(longest, sample.phases[phase])
+		}
+		stat := PhaseStat{
+			Phase: phase, LastMs: ms(last.phases[phase]), MaxMs: ms(longest),
+			MeanMs: ms(sum / time.Duration(len(h.tickWindow))),
+		}
+		if windowTotal > 0 {
+			stat.Share = 100 * float64(sum) / float64(windowTotal)
+		}
+		stats.Phases = append(stats.Phases, stat)
+	}
+	return stats
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
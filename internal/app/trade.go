@@ -0,0 +1,284 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+// tradeItem is a number of items of a kind in a trade window.
+type tradeItem struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type offerInfo struct {
+	Items []tradeItem `json:"items"`
+	Gold  int         `json:"gold"`
+}
+
+// tradeInfo is a trade as seen by one side: both offers, who confirmed
+// them, and the items the player carries to offer from.
+type tradeInfo struct {
+	ID               string      `json:"id"`
+	Partner          string      `json:"partner"`
+	Mine             offerInfo   `json:"mine"`
+	Theirs           offerInfo   `json:"theirs"`
+	Confirmed        bool        `json:"confirmed"`
+	PartnerConfirmed bool        `json:"partnerConfirmed"`
+	Carried          []tradeItem `json:"carried"`
+}
+
+// tradeMsg opens or updates the trade window, or closes it when Trade is
+// nil.
+type tradeMsg struct {
+	Type  string     `json:"type"`
+	Msg   string     `json:"msg,omitempty"`
+	Trade *tradeInfo `json:"trade,omitempty"`
+}
+
+// HandleTradeRequest asks targetID to trade with the player, or opens the
+// trade when targetID asked first.
+func (h *Handler) HandleTradeRequest(cc *clientConn, p *domain.Player, targetID string) error {
+	if p == nil {
+		return fmt.Errorf("player not found")
+	}
+	targetConn := h.connForPlayer(targetID)
+	target := h.Player.GetPlayer(targetID)
+	if targetConn == nil || target == nil || targetID == p.ID {
+		return cc.sendJson(serverMsg{Type: "error", Msg: fmt.Sprintf("no player %q to trade with", targetID)})
+	}
+	if target.WorldID != p.WorldID || max(abs(target.X-p.X), abs(target.Y-p.Y)) > domain.TradeRange {
+		return cc.sendJson(serverMsg{Type: "error", Msg: fmt.Sprintf("%s is %s", targetID, domain.ErrTooFarToTrade)})
+	}
+
+	h.tradeMu.Lock()
+	var trade *domain.Trade
+	var err error
+	switch {
+	case h.trades[p.ID] != nil:
+		err = domain.ErrTrading
+	case h.trades[targetID] != nil:
+		err = fmt.Errorf("%s is %w", targetID, domain.ErrTrading)
+	case h.tradeRequests[p.ID] == targetID:
+		delete(h.tradeRequests, p.ID)
+		delete(h.tradeRequests, targetID)
+		h.tradeSeq++
+		trade = domain.NewTrade(fmt.Sprintf("trade-%d", h.tradeSeq), targetID, p.ID)
+		h.trades[p.ID], h.trades[targetID] = trade, trade
+	default:
+		h.tradeRequests[targetID] = p.ID
+	}
+	h.tradeMu.Unlock()
+	if err != nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: err.Error()})
+	}
+
+	if trade == nil {
+		targetConn.sendJson(serverMsg{Type: "success", Msg: fmt.Sprintf("%s wants to trade with you: /trade %s", p.ID, p.ID)})
+		return cc.sendJson(serverMsg{Type: "success", Msg: "Asked " + targetID + " to trade"})
+	}
+	h.Log.Info("trade opened", "trade", trade.ID, "players", []string{targetID, p.ID})
+	h.sendTradeWindow(trade, "Trade opened")
+	return nil
+}
+
+// HandleTradeOffer puts up count of the item itemID, or count gold when
+// itemID is "gold", in the player's offer.
+func (h *Handler) HandleTradeOffer(cc *clientConn, p *domain.Player, itemID string, count int) error {
+	if p == nil {
+		return fmt.Errorf("player not found")
+	}
+	if itemID != "gold" && h.item(itemID) == nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: domain.ErrUnknownItem.Error()})
+	}
+
+	h.tradeMu.Lock()
+	trade := h.trades[p.ID]
+	err := domain.ErrNotTrading
+	if trade != nil {
+		if itemID == "gold" {
+			err = trade.SetGold(p, count)
+		} else {
+			err = trade.SetItems(p, itemID, count)
+		}
+	}
+	h.tradeMu.Unlock()
+	if err != nil {
+		return cc.sendJson(serverMsg{Type: "error", Msg: err.Error()})
+	}
+
+	h.sendTradeWindow(trade, "")
+	return nil
+}
+
+// HandleTradeConfirm agrees to the trade as it stands, and swaps the offers
+// once both sides did.
+func (h *Handler) HandleTradeConfirm(cc *clientConn, p *domain.Player) error {
+	if p == nil {
+		return fmt.Errorf("player not found")
+	}
+
+	h.tradeMu.Lock()
+	trade := h.trades[p.ID]
+	if trade == nil {
+		h.tradeMu.Unlock()
+		return cc.sendJson(serverMsg{Type: "error", Msg: domain.ErrNotTrading.Error()})
+	}
+	done, err := trade.Confirm(p.ID)
+	var a, b *domain.Player
+	if done {
+		a, b = h.Player.GetPlayer(trade.Players[0]), h.Player.GetPlayer(trade.Players[1])
+		if a == nil || b == nil {
+			err = fmt.Errorf("trade partner is gone")
+		} else {
+			err = h.exchange(trade, a, b)
+		}
+		if err != nil {
+			trade.Confirmed = [2]bool{}
+		} else {
+			delete(h.trades, a.ID)
+			delete(h.trades, b.ID)
+		}
+	}
+	h.tradeMu.Unlock()
+
+	if err != nil {
+		h.sendTradeWindow(trade, "Trade failed: "+err.Error())
+		return nil
+	}
+	if !done {
+		h.sendTradeWindow(trade, "")
+		return nil
+	}
+
+	h.Log.Info("trade completed", "trade", trade.ID, "players", []string{a.ID, b.ID})
+	for _, pl := range []*domain.Player{a, b} {
+		if conn := h.connForPlayer(pl.ID); conn != nil {
+			conn.sendJson(tradeMsg{Type: "trade"})
+			conn.sendJson(serverMsg{Type: "playerUpdate", Msg: "Traded with " + trade.Partner(pl.ID), Player: pl})
+		}
+	}
+	return nil
+}
+
+// exchange swaps the offers of trade and saves both players at once. When
+// the save fails, the offers are swapped back so that neither side gains
+// what the store did not keep.
+func (h *Handler) exchange(trade *domain.Trade, a, b *domain.Player) error {
+	unlock := h.lockPlayers(a.ID, b.ID)
+	defer unlock()
+	if err := trade.Exchange(a, b); err != nil {
+		return err
+	}
+	if err := h.Player.SavePlayers(a, b); err != nil {
+		trade.Undo(a, b)
+		h.Log.Error("error saving trade", "trade", trade.ID, "err", err)
+		return fmt.Errorf("it could not be saved")
+	}
+	return nil
+}
+
+// HandleTradeCancel closes the player's trade, or turns down the requests
+// made to them when they are not trading.
+func (h *Handler) HandleTradeCancel(cc *clientConn, p *domain.Player) error {
+	if p == nil {
+		return fmt.Errorf("player not found")
+	}
+	if h.cancelTrade(p.ID, p.ID+" cancelled the trade") {
+		return nil
+	}
+
+	h.tradeMu.Lock()
+	requester, ok := h.tradeRequests[p.ID]
+	delete(h.tradeRequests, p.ID)
+	h.tradeMu.Unlock()
+	if !ok {
+		return cc.sendJson(serverMsg{Type: "error", Msg: domain.ErrNotTrading.Error()})
+	}
+	if conn := h.connForPlayer(requester); conn != nil {
+		conn.sendJson(serverMsg{Type: "success", Msg: p.ID + " declined to trade"})
+	}
+	return cc.sendJson(serverMsg{Type: "success", Msg: "Declined to trade with " + requester})
+}
+
+// cancelTrade closes the trade of playerID without exchanging anything and
+// tells both sides why. It reports whether the player was trading.
+func (h *Handler) cancelTrade(playerID, why string) bool {
+	h.tradeMu.Lock()
+	trade := h.trades[playerID]
+	if trade != nil {
+		for _, id := range trade.Players {
+			delete(h.trades, id)
+		}
+	}
+	h.tradeMu.Unlock()
+	if trade == nil {
+		return false
+	}
+
+	h.Log.Info("trade cancelled", "trade", trade.ID, "reason", why)
+	for _, id := range trade.Players {
+		if conn := h.connForPlayer(id); conn != nil {
+			conn.sendJson(tradeMsg{Type: "trade", Msg: why})
+		}
+	}
+	return true
+}
+
+// forgetTradeRequests drops the requests made by or to playerID.
+func (h *Handler) forgetTradeRequests(playerID string) {
+	h.tradeMu.Lock()
+	defer h.tradeMu.Unlock()
+
+	delete(h.tradeRequests, playerID)
+	for target, requester := range h.tradeRequests {
+		if requester == playerID {
+			delete(h.tradeRequests, target)
+		}
+	}
+}
+
+// sendTradeWindow sends each side of the trade its window along with msg.
+func (h *Handler) sendTradeWindow(trade *domain.Trade, msg string) {
+	h.tradeMu.Lock()
+	infos := make(map[string]*tradeInfo, 2)
+	for i, id := range trade.Players {
+		partner := trade.Players[1-i]
+		info := &tradeInfo{
+			ID:               trade.ID,
+			Partner:          partner,
+			Mine:             h.offerInfo(trade.Offers[i]),
+			Theirs:           h.offerInfo(trade.Offers[1-i]),
+			Confirmed:        trade.Confirmed[i],
+			PartnerConfirmed: trade.Confirmed[1-i],
+		}
+		if p := h.Player.GetPlayer(id); p != nil {
+			info.Carried = h.tradeItems(p.Inventory)
+		}
+		infos[id] = info
+	}
+	h.tradeMu.Unlock()
+
+	for id, info := range infos {
+		if conn := h.connForPlayer(id); conn != nil {
+			conn.sendJson(tradeMsg{Type: "trade", Msg: msg, Trade: info})
+		}
+	}
+}
+
+func (h *Handler) offerInfo(o domain.Offer) offerInfo {
+	return offerInfo{Items: h.tradeItems(o.Items), Gold: o.Gold}
+}
+
+// tradeItems lists the counted items in file order.
+func (h *Handler) tradeItems(counts map[string]int) []tradeItem {
+	items := []tradeItem{}
+	for _, item := range h.Items {
+		if n := counts[item.ID]; n > 0 {
+			items = append(items, tradeItem{ID: item.ID, Name: item.Name, Count: n})
+		}
+	}
+	return items
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/LealKevin/terminus/internal/domain"
+	"github.com/LealKevin/terminus/internal/infra/store"
+)
+
+// failingStore keeps players in memory but fails every SavePlayers, like a
+// database refusing the write.
+type failingStore struct {
+	*store.PlayerMemoryStore
+}
+
+func (failingStore) Durable() bool { return true }
+
+func (failingStore) SavePlayers(...*domain.Player) error {
+	return errors.New("connection reset")
+}
+
+func TestTradeLeavesWalletsAloneWhenTheSaveFails(t *testing.T) {
+	alice := domain.NewPlayer("alice", 5, 5)
+	alice.Gold = 100
+	h := newTestHandler(t, testRoom(), alice)
+	h.Player = failingStore{h.Player.(*store.PlayerMemoryStore)}
+	h.Items = []*domain.Item{{ID: "potion", Name: "Potion"}}
+	bob := domain.NewPlayer("bob", 6, 5)
+	bob.Gold = 0
+	bob.Inventory["potion"] = 2
+	aliceConn, bobConn := h.connForPlayer("alice"), connectTestPlayer(t, h, bob)
+
+	for _, step := range []error{
+		h.HandleTradeRequest(aliceConn, alice, "bob"),
+		h.HandleTradeRequest(bobConn, bob, "alice"),
+		h.HandleTradeOffer(aliceConn, alice, "gold", 40),
+		h.HandleTradeOffer(bobConn, bob, "potion", 2),
+		h.HandleTradeConfirm(aliceConn, alice),
+		h.HandleTradeConfirm(bobConn, bob),
+	} {
+		if step != nil {
+			t.Fatal(step)
+		}
+	}
+
+	if alice.Gold != 100 || alice.Inventory["potion"] != 0 {
+		t.Errorf("alice has %d gold and %d potions, want 100 and 0", alice.Gold, alice.Inventory["potion"])
+	}
+	if bob.Gold != 0 || bob.Inventory["potion"] != 2 {
+		t.Errorf("bob has %d gold and %d potions, want 0 and 2", bob.Gold, bob.Inventory["potion"])
+	}
+	if h.trades["alice"] == nil {
+		t.Error("the trade was closed although it was not saved")
+	}
+}
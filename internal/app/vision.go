@@ -0,0 +1,78 @@
+package app
+
+import (
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+// exploredWorld is the part of world the player is allowed to know about:
+// the layout is masked to the explored cells and only discovered portals
+// are kept. Regions are public so that the client can tell safe and PvP
+// ground apart.
+func exploredWorld(world *domain.World, player *domain.Player) *domain.World {
+	masked := &domain.World{
+		ID:      world.ID,
+		Name:    world.Name,
+		Width:   world.Width,
+		Height:  world.Height,
+		Layout:  player.Explored.MaskLayout(world),
+		Regions: world.Regions,
+	}
+	for _, p := range world.Portals {
+		if player.Explored.Has(world, p.X, p.Y) {
+			masked.Portals = append(masked.Portals, p)
+		}
+	}
+	return masked
+}
+
+// see computes what the player sees of world and remembers it as explored,
+// under the player's lock since saving reads the explored cells.
+func (h *Handler) see(player *domain.Player, world *domain.World) *domain.FOV {
+	unlock := h.lockPlayers(player.ID)
+	defer unlock()
+	return player.See(world)
+}
+
+// viewFor computes the player's field of view and the mobs, projectiles,
+// NPCs and other players inside it.
+func (h *Handler) viewFor(player *domain.Player, world *domain.World, mobs []*domain.Mob) MobUpdate {
+	fov := h.see(player, world)
+
+	visibleMobs := []*domain.Mob{}
+	for _, mob := range mobs {
+		if fov.Visible(mob.X, mob.Y) {
+			visibleMobs = append(visibleMobs, mob)
+		}
+	}
+
+	var projectiles []*domain.Projectile
+	for _, proj := range h.projectilesInWorld(world.ID) {
+		if fov.Visible(proj.X, proj.Y) {
+			projectiles = append(projectiles, proj)
+		}
+	}
+
+	var tiles []byte
+	for y := fov.Y; y < fov.Y+fov.Height; y++ {
+		for x := fov.X; x < fov.X+fov.Width; x++ {
+			if fov.Visible(x, y) {
+				tiles = append(tiles, world.Glyph(x, y))
+			}
+		}
+	}
+
+	return MobUpdate{
+		Type:  "mobsUpdate",
+		Mobs:  visibleMobs,
+		View:  fov,
+		Tiles: tiles,
+
+		Projectiles: projectiles,
+		NPCs:        h.npcsInView(world, fov),
+		Players:     h.playersInView(player, world, fov),
+	}
+}
+
+func (h *Handler) sendView(cc *clientConn, player *domain.Player, world *domain.World) error {
+	return cc.sendJson(h.viewFor(player, world, h.Mobs.GetMobsByWorld(world.ID)))
+}
@@ -0,0 +1,104 @@
+package client
+
+import "strings"
+
+// camera is the window of the world shown on screen. It follows the player
+// and stops at the world edges instead of showing empty space past them.
+type camera struct {
+	x, y int
+	w, h int
+}
+
+func newCamera(centerX, centerY, viewW, viewH, worldW, worldH int) camera {
+	return camera{
+		x: cameraOrigin(centerX, viewW, worldW),
+		y: cameraOrigin(centerY, viewH, worldH),
+		w: min(viewW, worldW),
+		h: min(viewH, worldH),
+	}
+}
+
+func cameraOrigin(center, view, world int) int {
+	if world <= view {
+		return 0
+	}
+	origin := center - view/2
+	return max(0, min(origin, world-view))
+}
+
+func (c camera) crop(display [][]cell) [][]cell {
+	rows := make([][]cell, 0, c.h)
+	for y := c.y; y < c.y+c.h && y < len(display); y++ {
+		row := display[y]
+		end := min(c.x+c.w, len(row))
+		if c.x >= end {
+			rows = append(rows, nil)
+			continue
+		}
+		rows = append(rows, row[c.x:end])
+	}
+	return rows
+}
+
+const (
+	minimapMaxW = 32
+	minimapMaxH = 16
+)
+
+// Minimap draws the whole explored world scaled down to fit in a small
+// framed panel. Each minimap cell summarizes a block of world cells, showing
+// the most important thing inside it: the player, a visible mob, walkable
+// ground, then walls.
+func (gs *GameState) Minimap() []string {
+	if len(gs.world.Layout) == 0 {
+		return nil
+	}
+	scaleX := (gs.world.Width + minimapMaxW - 1) / minimapMaxW
+	scaleY := (gs.world.Height + minimapMaxH - 1) / minimapMaxH
+	w := (gs.world.Width + scaleX - 1) / scaleX
+	h := (gs.world.Height + scaleY - 1) / scaleY
+
+	mobAt := make(map[[2]int]bool)
+	for _, mob := range gs.mobs {
+		mobAt[[2]int{mob.X / scaleX, mob.Y / scaleY}] = true
+	}
+
+	lines := []string{"+" + strings.Repeat("-", w) + "+"}
+	for my := 0; my < h; my++ {
+		var b strings.Builder
+		b.WriteByte('|')
+		for mx := 0; mx < w; mx++ {
+			switch {
+			case gs.player.X/scaleX == mx && gs.player.Y/scaleY == my:
+				b.WriteByte('@')
+			case mobAt[[2]int{mx, my}]:
+				b.WriteByte('m')
+			default:
+				b.WriteByte(gs.minimapCell(mx*scaleX, my*scaleY, scaleX, scaleY))
+			}
+		}
+		b.WriteByte('|')
+		lines = append(lines, b.String())
+	}
+	lines = append(lines, lines[0])
+	return lines
+}
+
+func (gs *GameState) minimapCell(x0, y0, scaleX, scaleY int) byte {
+	explored := false
+	for y := y0; y < y0+scaleY && y < len(gs.world.Layout); y++ {
+		for x := x0; x < x0+scaleX && x < len(gs.world.Layout[y]); x++ {
+			switch gs.world.Layout[y][x] {
+			case 0:
+			case '#':
+				explored = true
+			default:
+				return '.'
+			}
+		}
+	}
+	if explored {
+		return '#'
+	}
+	return ' '
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// updateInput edits the command line: enter sends it, esc drops it.
+func (m Model) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.typing, m.input = false, ""
+		return m, nil
+	case tea.KeyEnter:
+		line := strings.TrimSpace(m.input)
+		m.typing, m.input = false, ""
+		if line == "" {
+			return m, nil
+		}
+		return m.runCommand(line)
+	case tea.KeyBackspace:
+		if r := []rune(m.input); len(r) > 0 {
+			m.input = string(r[:len(r)-1])
+		}
+	case tea.KeySpace:
+		m.input += " "
+	case tea.KeyRunes:
+		m.input += string(msg.Runes)
+	}
+	return m, nil
+}
+
+// runCommand runs a slash command typed on the command line, handing the
+// ones it does not know to the server. Anything else is said to the party.
+func (m Model) runCommand(line string) (tea.Model, tea.Cmd) {
+	if !strings.HasPrefix(line, "/") {
+		line = "/p " + line
+	}
+	name, arg, _ := strings.Cut(line[1:], " ")
+	arg = strings.TrimSpace(arg)
+
+	var cmd tea.Cmd
+	switch name {
+	case "invite", "pkick":
+		if arg == "" {
+			m.msgForNow = "Usage: /" + name + " <player>"
+			return m, nil
+		}
+		cmd = m.conn.sendCommand(partyCommands[name], arg, "")
+	case "accept", "decline", "leave":
+		cmd = m.conn.sendCommand(partyCommands[name], "", "")
+	case "p", "party":
+		cmd = m.conn.sendCommand("partyChat", "", arg)
+	case "who":
+		cmd = m.conn.sendCommand("who", "", "")
+	case "trade":
+		if arg == "" {
+			m.msgForNow = "Usage: /trade <player>"
+			return m, nil
+		}
+		cmd = m.conn.sendCommand("tradeRequest", arg, "")
+	case "duel":
+		if arg == "" {
+			m.msgForNow = "Usage: /duel <player>"
+			return m, nil
+		}
+		cmd = m.conn.sendCommand("duel", arg, "")
+	case "gold":
+		gold, err := strconv.Atoi(arg)
+		if err != nil || gold < 0 {
+			m.msgForNow = "Usage: /gold <amount>"
+			return m, nil
+		}
+		cmd = m.conn.sendTradeOffer("gold", gold)
+	default:
+		// Admin commands, and the commands the server does not know, are
+		// left for the server to judge.
+		cmd = m.conn.sendCommand("command", "", line)
+	}
+	return m, tea.Batch(cmd, m.conn.listenForServerMessages())
+}
+
+// partyCommands maps the party slash commands to their message types.
+var partyCommands = map[string]string{
+	"invite":  "partyInvite",
+	"pkick":   "partyKick",
+	"accept":  "partyAccept",
+	"decline": "partyDecline",
+	"leave":   "partyLeave",
+}
@@ -3,6 +3,7 @@ package client
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 
@@ -16,12 +17,12 @@ type connectionWrapper struct {
 	decoder *json.Decoder
 }
 
-func ServerConnection() (*connectionWrapper, error) {
-	port := "4200"
-	addr := "localhost:" + port
+// ServerConnection connects to the server at addr and joins as the player
+// name, returning the error the server refused the join with.
+func ServerConnection(addr, name, secret string) (*connectionWrapper, error) {
 	conn, err := net.Dial("tcp", addr)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	writer := bufio.NewWriter(conn)
@@ -29,6 +30,22 @@ func ServerConnection() (*connectionWrapper, error) {
 	encoder.SetEscapeHTML(false)
 	decoder := json.NewDecoder(conn)
 
+	var joined ServerMsg
+	err = encoder.Encode(ClientMsg{Type: "join", PlayerID: name, Secret: secret})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = decoder.Decode(&joined)
+	}
+	if err == nil && joined.Type == "error" {
+		err = errors.New(joined.Msg)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("joining as %s: %w", name, err)
+	}
+
 	return &connectionWrapper{
 		conn:    conn,
 		writer:  writer,
@@ -76,20 +93,158 @@ func (cw *connectionWrapper) getPlayer(id string) tea.Cmd {
 		if err != nil {
 			return errMsg{err}
 		}
+		return nil
+	}
+}
 
-		var player Player
-		err = cw.decoder.Decode(&player)
+func (cw *connectionWrapper) sendMove(dir string) tea.Cmd {
+	return func() tea.Msg {
+		msg := ClientMsg{PlayerID: "1", Type: "move", Direction: dir}
+		data, err := json.Marshal(msg)
 		if err != nil {
 			return errMsg{err}
 		}
+		_, err = cw.conn.Write(append(data, '\n'))
+		if err != nil {
+			return errMsg{err}
+		}
+		return nil
+	}
+}
 
-		return player
+// sendAttack attacks the mob targetID, or the nearest one when it is empty.
+func (cw *connectionWrapper) sendAttack(targetID string) tea.Cmd {
+	return func() tea.Msg {
+		msg := ClientMsg{PlayerID: "1", Type: "attack", Target: targetID}
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return errMsg{err}
+		}
+		_, err = cw.conn.Write(append(data, '\n'))
+		if err != nil {
+			return errMsg{err}
+		}
+		return nil
 	}
 }
 
-func (cw *connectionWrapper) sendMove(dir string) tea.Cmd {
+// sendShoot fires an arrow at the mob targetID, or in direction dir when it
+// is empty.
+func (cw *connectionWrapper) sendShoot(targetID, dir string) tea.Cmd {
 	return func() tea.Msg {
-		msg := ClientMsg{PlayerID: "1", Type: "move", Direction: dir}
+		msg := ClientMsg{PlayerID: "1", Type: "shoot", Target: targetID, Direction: dir}
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return errMsg{err}
+		}
+		_, err = cw.conn.Write(append(data, '\n'))
+		if err != nil {
+			return errMsg{err}
+		}
+		return nil
+	}
+}
+
+// sendInteract talks to the NPC next to the player.
+func (cw *connectionWrapper) sendInteract() tea.Cmd {
+	return func() tea.Msg {
+		msg := ClientMsg{PlayerID: "1", Type: "interact"}
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return errMsg{err}
+		}
+		_, err = cw.conn.Write(append(data, '\n'))
+		if err != nil {
+			return errMsg{err}
+		}
+		return nil
+	}
+}
+
+// sendDialogue sends a dialogue or trade message of the given type: the
+// number of a dialogue choice, or the item to buy or sell.
+func (cw *connectionWrapper) sendDialogue(msgType, value string) tea.Cmd {
+	return func() tea.Msg {
+		msg := ClientMsg{PlayerID: "1", Type: msgType, Message: value}
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return errMsg{err}
+		}
+		_, err = cw.conn.Write(append(data, '\n'))
+		if err != nil {
+			return errMsg{err}
+		}
+		return nil
+	}
+}
+
+func (cw *connectionWrapper) sendToggleDoor(dir string) tea.Cmd {
+	return func() tea.Msg {
+		msg := ClientMsg{Type: "toggleDoor", Direction: dir}
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return errMsg{err}
+		}
+		_, err = cw.conn.Write(append(data, '\n'))
+		if err != nil {
+			return errMsg{err}
+		}
+		return nil
+	}
+}
+
+func (cw *connectionWrapper) getAbilities() tea.Cmd {
+	return func() tea.Msg {
+		msg := ClientMsg{Type: "getAbilities"}
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return errMsg{err}
+		}
+		_, err = cw.conn.Write(append(data, '\n'))
+		if err != nil {
+			return errMsg{err}
+		}
+		return nil
+	}
+}
+
+func (cw *connectionWrapper) getQuests() tea.Cmd {
+	return func() tea.Msg {
+		msg := ClientMsg{Type: "getQuests"}
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return errMsg{err}
+		}
+		_, err = cw.conn.Write(append(data, '\n'))
+		if err != nil {
+			return errMsg{err}
+		}
+		return nil
+	}
+}
+
+// sendUseAbility casts the ability id on targetID, or towards dir for aimed
+// abilities without a target.
+func (cw *connectionWrapper) sendUseAbility(id, targetID, dir string) tea.Cmd {
+	return func() tea.Msg {
+		msg := ClientMsg{PlayerID: "1", Type: "useAbility", Message: id, Target: targetID, Direction: dir}
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return errMsg{err}
+		}
+		_, err = cw.conn.Write(append(data, '\n'))
+		if err != nil {
+			return errMsg{err}
+		}
+		return nil
+	}
+}
+
+// sendCommand sends a party, trade or duel command msgType about the
+// player target, the chat line text, or the admin command line text.
+func (cw *connectionWrapper) sendCommand(msgType, target, text string) tea.Cmd {
+	return func() tea.Msg {
+		msg := ClientMsg{Type: msgType, Target: target, Message: text}
 		data, err := json.Marshal(msg)
 		if err != nil {
 			return errMsg{err}
@@ -102,9 +257,11 @@ func (cw *connectionWrapper) sendMove(dir string) tea.Cmd {
 	}
 }
 
-func (cw *connectionWrapper) sendAttack() tea.Cmd {
+// sendTradeOffer puts up count items itemID, or count gold when itemID is
+// "gold", in the open trade.
+func (cw *connectionWrapper) sendTradeOffer(itemID string, count int) tea.Cmd {
 	return func() tea.Msg {
-		msg := ClientMsg{PlayerID: "1", Type: "attack"}
+		msg := ClientMsg{Type: "tradeOffer", Message: itemID, Count: count}
 		data, err := json.Marshal(msg)
 		if err != nil {
 			return errMsg{err}
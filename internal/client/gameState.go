@@ -1,47 +1,166 @@
 package client
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
-func (gs *GameState) copyWorldLayout() [][]rune {
+// isVisible reports whether the cell is in the player's current field of
+// view.
+func (gs *GameState) isVisible(x, y int) bool {
+	v := gs.view
+	x, y = x-v.X, y-v.Y
+	if x < 0 || y < 0 || x >= v.Width || y >= v.Height {
+		return false
+	}
+	i := y*v.Width + x
+	return i/8 < len(v.Bits) && v.Bits[i/8]&(1<<(i%8)) != 0
+}
+
+// applyView stores the field of view and updates the layout with the glyphs
+// of the visible cells, exploring them.
+func (gs *GameState) applyView(v view, tiles []byte) {
+	gs.view = v
+	n := 0
+	for y := v.Y; y < v.Y+v.Height && y < len(gs.world.Layout); y++ {
+		row := gs.world.Layout[y]
+		for x := v.X; x < v.X+v.Width && x < len(row); x++ {
+			if n < len(tiles) && gs.isVisible(x, y) {
+				row[x] = tiles[n]
+				n++
+			}
+		}
+	}
+}
+
+// cell is one screen position of the map with the style key it is drawn
+// with.
+type cell struct {
+	r     rune
+	style string
+}
+
+// copyWorldLayout renders the terrain with fog of war: unexplored cells are
+// blank, remembered cells show their terrain dimmed, and visible floor is
+// dotted so the field of view stands out.
+func (gs *GameState) copyWorldLayout() [][]cell {
 	if len(gs.world.Layout) == 0 {
 		return nil
 	}
 
-	display := make([][]rune, len(gs.world.Layout))
+	display := make([][]cell, len(gs.world.Layout))
 	for i, row := range gs.world.Layout {
-		display[i] = make([]rune, len(row))
-		for j, cell := range row {
-			display[i][j] = rune(cell)
+		display[i] = make([]cell, len(row))
+		for j, g := range row {
+			visible := gs.isVisible(j, i)
+			c := cell{r: rune(g), style: tileStyles[g]}
+			switch {
+			case g == 0:
+				c = cell{r: ' '}
+			case !visible:
+				c.style = "remembered"
+			case g == ' ':
+				c.r = '.'
+			}
+			display[i][j] = c
 		}
 	}
 	return display
 }
 
-func (gs *GameState) Render() string {
+func (gs *GameState) setTile(x, y int, glyph byte) {
+	if y >= 0 && y < len(gs.world.Layout) &&
+		x >= 0 && x < len(gs.world.Layout[y]) {
+		gs.world.Layout[y][x] = glyph
+	}
+}
+
+// Render draws the part of the world that fits in a viewW x viewH viewport
+// centered on the player, colored with theme. A non-positive size renders
+// the whole world.
+func (gs *GameState) Render(viewW, viewH int, theme *Theme) string {
 	if len(gs.world.Layout) == 0 {
 		return "Loading world...\n"
 	}
 
+	display := gs.compose()
+	if viewW <= 0 || viewH <= 0 {
+		viewW, viewH = gs.world.Width, gs.world.Height
+	}
+	cam := newCamera(gs.player.X, gs.player.Y, viewW, viewH, gs.world.Width, gs.world.Height)
+
+	var b strings.Builder
+	for _, row := range cam.crop(display) {
+		renderRow(&b, row, theme)
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
+
+// renderRow writes row to b, styling each run of cells that share a style
+// at once to keep the escape sequences short.
+func renderRow(b *strings.Builder, row []cell, theme *Theme) {
+	for i := 0; i < len(row); {
+		j := i
+		var run []rune
+		for j < len(row) && row[j].style == row[i].style {
+			run = append(run, row[j].r)
+			j++
+		}
+		if row[i].style == "" {
+			b.WriteString(string(run))
+		} else {
+			b.WriteString(theme.Style(row[i].style).Render(string(run)))
+		}
+		i = j
+	}
+}
+
+// compose draws the terrain and every entity on a full-size copy of the world.
+func (gs *GameState) compose() [][]cell {
 	display := gs.copyWorldLayout()
+	put := func(x, y int, c cell) bool {
+		if y >= 0 && y < len(display) && x >= 0 && x < len(display[y]) {
+			display[y][x] = c
+			return true
+		}
+		return false
+	}
+
+	for _, p := range gs.world.Portals {
+		put(p.X, p.Y, cell{r: 'O', style: "portal"})
+	}
 
 	for _, item := range gs.items {
-		if item.Y >= 0 && item.Y < len(display) &&
-			item.X >= 0 && item.X < len(display[item.Y]) {
-			display[item.Y][item.X] = item.Symbol
-		}
+		put(item.X, item.Y, cell{r: item.Symbol, style: "item"})
+	}
+
+	for _, n := range gs.npcs {
+		put(n.X, n.Y, cell{r: n.Symbol, style: "npc"})
 	}
 
 	for _, mob := range gs.mobs {
-		if mob.Y >= 0 && mob.Y < len(display) &&
-			mob.X >= 0 && mob.X < len(display[mob.Y]) {
-			display[mob.Y][mob.X] = mob.Symbol
+		style := "mob:" + mob.Type
+		if gs.target != nil && mob.ID == gs.target.ID {
+			style = "target"
 		}
+		put(mob.X, mob.Y, cell{r: mob.Symbol, style: style})
 	}
 
-	if gs.player.Y >= 0 && gs.player.Y < len(display) &&
-		gs.player.X >= 0 && gs.player.X < len(display[gs.player.Y]) {
-		display[gs.player.Y][gs.player.X] = '@'
-	} else {
+	for _, other := range gs.players {
+		style := "otherPlayer"
+		if gs.target != nil && other.ID == gs.target.ID {
+			style = "target"
+		}
+		put(other.X, other.Y, cell{r: '@', style: style})
+	}
+
+	for _, proj := range gs.projectiles {
+		put(proj.X, proj.Y, cell{r: proj.Symbol, style: "projectile:" + proj.Kind})
+	}
+
+	if !put(gs.player.X, gs.player.Y, cell{r: '@', style: "player"}) {
 		worldWidth := 0
 		if len(display) > 0 {
 			worldWidth = len(display[0])
@@ -50,10 +169,5 @@ func (gs *GameState) Render() string {
 			gs.player.X, gs.player.Y, worldWidth, len(display))
 	}
 
-	var result string
-	for _, row := range display {
-		result += string(row) + "\n"
-	}
-
-	return result
+	return display
 }
@@ -2,6 +2,7 @@ package client
 
 import (
 	"fmt"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -11,6 +12,9 @@ type ClientMsg struct {
 	Type      string `json:"type"`
 	Message   string `json:"message"`
 	Direction string `json:"direction"`
+	Target    string `json:"target,omitempty"`
+	Count     int    `json:"count,omitempty"`
+	Secret    string `json:"secret,omitempty"`
 }
 
 type ServerMsg struct {
@@ -23,22 +27,245 @@ type ServerMsg struct {
 	Mobs   []Mob  `json:"mobs"`
 	World  world  `json:"world"`
 	Player Player `json:"player"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Glyph  byte   `json:"glyph"`
+	// View is the field of view, and Tiles the current glyphs of its
+	// visible cells in row-major order.
+	View        view         `json:"view"`
+	Tiles       []byte       `json:"tiles"`
+	Projectiles []projectile `json:"projectiles"`
+	NPCs        []npc        `json:"npcs"`
+	// Players are the other players in view.
+	Players []otherPlayer `json:"players"`
+	// Combat event fields: Health and MaxHealth are the target's after
+	// the attack.
+	Kind      string `json:"kind"`
+	Attacker  string `json:"attacker"`
+	Target    string `json:"target"`
+	TargetID  string `json:"targetID"`
+	Damage    int    `json:"damage"`
+	Health    int    `json:"health"`
+	MaxHealth int    `json:"maxHealth"`
+
+	Abilities []ability `json:"abilities"`
+
+	// Dialogue is the conversation with an NPC, nil once it is over, and
+	// Shop the wares of the NPC the player trades with.
+	Dialogue *dialogue `json:"dialogue"`
+	Shop     shop      `json:"shop"`
+
+	Quests []quest `json:"quests"`
+
+	// Party is the player's party, nil when they are in none.
+	Party *party `json:"party"`
+	// Trade is the open trade, nil once it is over.
+	Trade *trade `json:"trade"`
+	// Chat fields: Channel is where the message was said and From who
+	// said it, empty for notices.
+	Channel string `json:"channel"`
+	From    string `json:"from"`
+}
+
+// view is a window of the world with a bitset of the visible cells in it.
+type view struct {
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Bits   []byte `json:"bits"`
 }
 
 type Player struct {
-	ID      string `json:"id"`
-	WorldID string `json:"worldID"`
-	X       int    `json:"x"`
-	Y       int    `json:"y"`
+	ID        string `json:"id"`
+	WorldID   string `json:"worldID"`
+	X         int    `json:"x"`
+	Y         int    `json:"y"`
+	Health    int    `json:"health"`
+	MaxHealth int    `json:"maxHealth"`
+	Level     int    `json:"level"`
+	XP        int    `json:"xp"`
+	Mana      int    `json:"mana"`
+	MaxMana   int    `json:"maxMana"`
+	Gold      int    `json:"gold"`
+	// Inventory counts the items the player carries, by ID.
+	Inventory map[string]int `json:"inventory"`
+	// Cooldowns holds when each ability used so far is ready again.
+	Cooldowns map[string]time.Time `json:"cooldowns"`
+	Statuses  []status             `json:"statuses"`
+}
+
+// projectile is an arrow or spell in flight.
+type projectile struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Symbol rune   `json:"symbol"`
+}
+
+// otherPlayer is another player standing in view.
+type otherPlayer struct {
+	ID        string `json:"id"`
+	X         int    `json:"x"`
+	Y         int    `json:"y"`
+	Level     int    `json:"level"`
+	Health    int    `json:"health"`
+	MaxHealth int    `json:"maxHealth"`
+}
+
+// npc is a non-hostile character standing in view.
+type npc struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Symbol rune   `json:"symbol"`
+	Shop   bool   `json:"shop"`
+}
+
+// dialogue is a line of an NPC with the answers the player can pick.
+type dialogue struct {
+	NPC     string   `json:"npc"`
+	Name    string   `json:"name"`
+	Text    string   `json:"text"`
+	Choices []string `json:"choices"`
+}
+
+// shop lists what an NPC sells and buys. An item with no Price is only
+// bought by the shop.
+type shop struct {
+	NPC   string     `json:"npc"`
+	Name  string     `json:"name"`
+	Gold  int        `json:"gold"`
+	Items []shopItem `json:"items"`
+}
+
+type shopItem struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Price       int    `json:"price"`
+	SellPrice   int    `json:"sellPrice"`
+	Owned       int    `json:"owned"`
+}
+
+// quest is an entry of the quest journal. Objectives lists the ones met so
+// far followed by the current one.
+type quest struct {
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Objectives  []objective `json:"objectives"`
+	Reward      string      `json:"reward"`
+	Completed   bool        `json:"completed"`
+}
+
+type objective struct {
+	Text   string `json:"text"`
+	Count  int    `json:"count"`
+	Needed int    `json:"needed"`
+	Done   bool   `json:"done"`
+}
+
+// party is the player's party. The first member is the leader.
+type party struct {
+	ID      string        `json:"id"`
+	Leader  string        `json:"leader"`
+	Members []partyMember `json:"members"`
+}
+
+type partyMember struct {
+	ID        string `json:"id"`
+	WorldID   string `json:"worldID"`
+	Level     int    `json:"level"`
+	Health    int    `json:"health"`
+	MaxHealth int    `json:"maxHealth"`
+}
+
+// trade is a trade with another player as seen from this side. Carried
+// lists the items the player can offer.
+type trade struct {
+	ID               string      `json:"id"`
+	Partner          string      `json:"partner"`
+	Mine             offer       `json:"mine"`
+	Theirs           offer       `json:"theirs"`
+	Confirmed        bool        `json:"confirmed"`
+	PartnerConfirmed bool        `json:"partnerConfirmed"`
+	Carried          []tradeItem `json:"carried"`
+}
+
+type offer struct {
+	Items []tradeItem `json:"items"`
+	Gold  int         `json:"gold"`
+}
+
+type tradeItem struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// status is a status effect with the number of ticks it has left.
+type status struct {
+	Kind  string `json:"kind"`
+	Ticks int    `json:"ticks"`
+	Power int    `json:"power"`
+}
+
+// ability is an ability definition as sent by the server. Abilities fill
+// the hotbar in order.
+type ability struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Level      int    `json:"level"`
+	ManaCost   int    `json:"manaCost"`
+	CooldownMs int64  `json:"cooldownMs"`
+	Range      int    `json:"range"`
+	Shape      string `json:"shape"`
+	Size       int    `json:"size"`
 }
 
 type errMsg struct{ error }
 
 type world struct {
-	ID     string
-	Width  int
-	Height int
-	Layout [][]byte
+	ID      string
+	Name    string
+	Width   int
+	Height  int
+	Layout  [][]byte
+	Portals []portal
+	Regions []region
+}
+
+// region is a rectangle of the world where players may fight each other
+// ("pvp") or nobody may fight ("safe").
+type region struct {
+	Kind string `json:"kind"`
+	MinX int    `json:"minX"`
+	MinY int    `json:"minY"`
+	MaxX int    `json:"maxX"`
+	MaxY int    `json:"maxY"`
+}
+
+// regionAt mirrors the server's rule: safe regions win over PvP ones.
+func (w world) regionAt(x, y int) string {
+	kind := ""
+	for _, r := range w.Regions {
+		if x < r.MinX || x > r.MaxX || y < r.MinY || y > r.MaxY {
+			continue
+		}
+		if r.Kind == "safe" {
+			return "safe"
+		}
+		kind = r.Kind
+	}
+	return kind
+}
+
+type portal struct {
+	X int `json:"x"`
+	Y int `json:"y"`
 }
 
 type Entity struct {
@@ -50,17 +277,19 @@ type Entity struct {
 }
 
 type Mob struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	WorldID     string `json:"worldID"`
-	X           int    `json:"x"`
-	Y           int    `json:"y"`
-	Type        string `json:"type"`
-	Health      int    `json:"health"`
-	Attack      int    `json:"attack"`
-	Defense     int    `json:"defense"`
-	AttackSpeed int    `json:"attackSpeed"`
-	Symbol      rune   `json:"symbol"`
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	WorldID     string   `json:"worldID"`
+	X           int      `json:"x"`
+	Y           int      `json:"y"`
+	Type        string   `json:"type"`
+	Health      int      `json:"health"`
+	MaxHealth   int      `json:"maxHealth"`
+	Attack      int      `json:"attack"`
+	Defense     int      `json:"defense"`
+	AttackSpeed int      `json:"attackSpeed"`
+	Symbol      rune     `json:"symbol"`
+	Statuses    []status `json:"statuses"`
 }
 
 type GameState struct {
@@ -68,6 +297,13 @@ type GameState struct {
 	player Player
 	mobs   []Mob
 	items  []Entity
+	// projectiles are the arrows and spells in flight in view.
+	projectiles []projectile
+	npcs        []npc
+	players     []otherPlayer
+	view        view
+	// target is the selected mob or player, nil when there is none.
+	target *target
 }
 
 type Model struct {
@@ -75,16 +311,85 @@ type Model struct {
 	conn      connectionWrapper
 	err       error
 	msgForNow string
+	// doorMode makes the next direction key toggle a door instead of moving.
+	doorMode bool
+
+	// width and height are the terminal size, zero until the first
+	// tea.WindowSizeMsg.
+	width       int
+	height      int
+	showMinimap bool
+
+	theme *Theme
+
+	abilities []ability
+	// facing is the direction of the last move, used to aim line and cone
+	// abilities when there is no target.
+	facing string
+
+	// log holds the server messages and combat events, oldest first;
+	// logScroll is how many entries the view is scrolled back.
+	log       []string
+	logScroll int
+
+	// dialogue and shop are the open conversation or shop, which replace
+	// the log panel; selling switches the shop from buying to selling.
+	dialogue *dialogue
+	shop     *shop
+	selling  bool
+
+	// quests is the quest journal, shown instead of the map while
+	// showJournal is set.
+	quests      []quest
+	showJournal bool
+
+	party *party
+
+	// trade is the open trade, shown in place of the log panel;
+	// withdrawing makes the number keys take items back from the offer.
+	trade       *trade
+	withdrawing bool
+	// input is the command or chat line being typed while typing is set.
+	input  string
+	typing bool
+}
+
+// target is what the client knows about the mob the player is fighting.
+type target struct {
+	ID        string
+	Name      string
+	Health    int
+	MaxHealth int
+	Statuses  []status
+}
+
+const maxLogEntries = 200
+
+// addLog appends entry to the message log, dropping the oldest entries past
+// maxLogEntries. Scrolled back views stay on the same entries.
+func (m *Model) addLog(entry string) {
+	if entry == "" {
+		return
+	}
+	m.log = append(m.log, entry)
+	if len(m.log) > maxLogEntries {
+		m.log = append([]string(nil), m.log[len(m.log)-maxLogEntries:]...)
+	}
+	if m.logScroll > 0 {
+		m.logScroll = min(m.logScroll+1, len(m.log)-1)
+	}
 }
 
-func NewModel(conn connectionWrapper) Model {
-	return Model{conn: conn}
+func NewModel(conn connectionWrapper, theme *Theme) Model {
+	return Model{conn: conn, theme: theme, facing: "S"}
 }
 
 func (m Model) Init() tea.Cmd {
 	fmt.Print("Connecting to server...\n")
 	var cmds []tea.Cmd
-	cmds = append(cmds, m.conn.getWorld("world1"))
+	cmds = append(cmds, m.conn.getPlayer(""))
+	cmds = append(cmds, m.conn.getAbilities())
+	cmds = append(cmds, m.conn.getQuests())
 	cmds = append(cmds, m.conn.listenForServerMessages())
 
 	return tea.Batch(cmds...)
@@ -0,0 +1,81 @@
+package client
+
+import "sort"
+
+// targets lists what can be targeted in view: the mobs, then the other
+// players.
+func (gs *GameState) targets() []Mob {
+	targets := append([]Mob(nil), gs.mobs...)
+	for _, p := range gs.players {
+		targets = append(targets, Mob{ID: p.ID, Name: p.ID, X: p.X, Y: p.Y, Health: p.Health, MaxHealth: p.MaxHealth})
+	}
+	return targets
+}
+
+// updateTarget refreshes the target's health from the visible mobs and
+// players. A target that is no longer visible keeps its last known health.
+func (gs *GameState) updateTarget() {
+	if gs.target == nil {
+		return
+	}
+	for _, mob := range gs.targets() {
+		if mob.ID == gs.target.ID {
+			gs.target.Health, gs.target.MaxHealth = mob.Health, mob.MaxHealth
+			gs.target.Statuses = mob.Statuses
+			return
+		}
+	}
+}
+
+// targetVisible reports whether the selected target is in view.
+func (gs *GameState) targetVisible() bool {
+	if gs.target == nil {
+		return false
+	}
+	for _, mob := range gs.targets() {
+		if mob.ID == gs.target.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// cycleTarget selects the next visible mob or player, nearest first,
+// wrapping around after the farthest one. It returns the new target, nil
+// when nothing is in sight.
+func (gs *GameState) cycleTarget() *target {
+	mobs := gs.targets()
+	if len(mobs) == 0 {
+		gs.target = nil
+		return nil
+	}
+
+	dist := func(m Mob) int {
+		return max(abs(m.X-gs.player.X), abs(m.Y-gs.player.Y))
+	}
+	sort.Slice(mobs, func(i, j int) bool {
+		if di, dj := dist(mobs[i]), dist(mobs[j]); di != dj {
+			return di < dj
+		}
+		return mobs[i].ID < mobs[j].ID
+	})
+
+	next := mobs[0]
+	if gs.target != nil {
+		for i, mob := range mobs {
+			if mob.ID == gs.target.ID {
+				next = mobs[(i+1)%len(mobs)]
+				break
+			}
+		}
+	}
+	gs.target = &target{ID: next.ID, Name: next.Name, Health: next.Health, MaxHealth: next.MaxHealth, Statuses: next.Statuses}
+	return gs.target
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
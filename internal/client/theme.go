@@ -0,0 +1,172 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Theme maps style keys to foreground colors. Keys are tile names ("wall",
+// "water", ...), "remembered" for explored cells out of sight, "portal",
+// "player", "otherPlayer", "item", "npc", "mob" and "mob:<Type>" for a specific
+// mob type, "target" for the selected mob or player, "projectile" and
+// "projectile:<kind>", the "hud*" keys for the status bar and
+// "status:<kind>" for status effect icons. Colors are anything
+// lipgloss.Color accepts.
+type Theme struct {
+	Name   string            `json:"name"`
+	Colors map[string]string `json:"colors"`
+
+	// Mono disables colors and relies on bold and reverse video to keep
+	// the player and mobs distinguishable.
+	Mono bool `json:"mono"`
+
+	styles map[string]lipgloss.Style
+}
+
+const (
+	ThemeDefault    = "default"
+	ThemeMonochrome = "monochrome"
+)
+
+var builtinThemes = map[string]*Theme{
+	ThemeDefault: {
+		Name: ThemeDefault,
+		Colors: map[string]string{
+			"wall":                "#8a8a8a",
+			"floor":               "#3a3a3a",
+			"grass":               "#5faf5f",
+			"water":               "#5f87ff",
+			"deep-water":          "#005fd7",
+			"door":                "#af875f",
+			"open-door":           "#af875f",
+			"locked-door":         "#d78700",
+			"lava":                "#ff5f00",
+			"stairs-down":         "#ffffaf",
+			"stairs-up":           "#ffffaf",
+			"remembered":          "#444444",
+			"portal":              "#d75fff",
+			"player":              "#ffff00",
+			"otherPlayer":         "#d7d7ff",
+			"item":                "#00d7d7",
+			"npc":                 "#87ffff",
+			"mob":                 "#ff5f5f",
+			"mob:Goblin":          "#87d700",
+			"mob:Archer":          "#d7af5f",
+			"mob:Shaman":          "#d75fd7",
+			"mob:GoblinKing":      "#ffaf00",
+			"projectile":          "#ffffff",
+			"projectile:firebolt": "#ff8700",
+			"target":              "#ff5f5f",
+			"status:poison":       "#87d700",
+			"status:burning":      "#ff8700",
+			"status:slow":         "#5fafff",
+			"status:stun":         "#ffff5f",
+			"status:regen":        "#5fff87",
+			"status:shield":       "#afafff",
+			"hud":                 "#1c1c1c",
+			"hudText":             "#d0d0d0",
+			"hudGood":             "#5fd75f",
+			"hudWarn":             "#ffd75f",
+			"hudBad":              "#ff5f5f",
+		},
+	},
+	ThemeMonochrome: {
+		Name: ThemeMonochrome,
+		Mono: true,
+	},
+}
+
+// tileStyles maps layout glyphs to the style key of their tile.
+var tileStyles = map[byte]string{
+	'#':  "wall",
+	' ':  "floor",
+	'"':  "grass",
+	'~':  "water",
+	'=':  "deep-water",
+	'+':  "door",
+	'\'': "open-door",
+	'D':  "locked-door",
+	'^':  "lava",
+	'>':  "stairs-down",
+	'<':  "stairs-up",
+}
+
+// LoadTheme returns the built-in theme called name, or reads a JSON theme
+// file when name is a path. Terminals without color support always get the
+// monochrome theme.
+func LoadTheme(name string) (*Theme, error) {
+	if lipgloss.ColorProfile() == termenv.Ascii {
+		return builtinThemes[ThemeMonochrome], nil
+	}
+	if name == "" {
+		name = ThemeDefault
+	}
+	if t, ok := builtinThemes[name]; ok {
+		return t, nil
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("theme %q is neither built in nor a readable file: %w", name, err)
+	}
+	var t Theme
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("parsing theme %s: %w", name, err)
+	}
+	if t.Name == "" {
+		t.Name = name
+	}
+	return &t, nil
+}
+
+// Style returns the style for key, falling back from "mob:<Type>" to "mob"
+// and from "projectile:<kind>" to "projectile".
+func (t *Theme) Style(key string) lipgloss.Style {
+	if s, ok := t.styles[key]; ok {
+		return s
+	}
+	if t.styles == nil {
+		t.styles = make(map[string]lipgloss.Style)
+	}
+
+	s := lipgloss.NewStyle()
+	switch {
+	case t.Mono:
+		switch {
+		case key == "player":
+			s = s.Bold(true).Reverse(true)
+		case key == "mob" || strings.HasPrefix(key, "mob:"), strings.HasPrefix(key, "projectile:"), key == "npc", key == "otherPlayer":
+			s = s.Bold(true)
+		case key == "hud":
+			s = s.Reverse(true)
+		}
+	default:
+		color, ok := t.Colors[key]
+		if !ok && strings.HasPrefix(key, "mob:") {
+			color, ok = t.Colors["mob"]
+		}
+		if !ok && strings.HasPrefix(key, "projectile:") {
+			color, ok = t.Colors["projectile"]
+		}
+		if ok {
+			if key == "hud" {
+				s = s.Background(lipgloss.Color(color))
+			} else {
+				s = s.Foreground(lipgloss.Color(color))
+			}
+		}
+		switch key {
+		case "player":
+			s = s.Bold(true)
+		case "target":
+			s = s.Bold(true).Reverse(true)
+		}
+	}
+	t.styles[key] = s
+	return s
+}
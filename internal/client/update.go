@@ -20,38 +20,154 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case ServerMsg:
 		if msg.Type == "world" {
 			m.gameState.world = msg.World
-			return m, tea.Batch(
-				m.conn.getPlayer("1"),
-				m.conn.listenForServerMessages(),
-			)
+			m.gameState.mobs = nil
+			m.gameState.npcs = nil
+			m.gameState.players = nil
+			m.dialogue, m.shop = nil, nil
+			m.gameState.view = view{}
+			m.gameState.target = nil
+			return m, m.conn.listenForServerMessages()
 		}
 
 		if msg.Type == "error" {
-			m.msgForNow = "Cannot move: " + msg.Msg
+			m.addLog(msg.Msg)
 			m.err = nil
 			return m, m.conn.listenForServerMessages()
 		}
 
 		if msg.Type == "playerUpdate" {
-			m.msgForNow = msg.Msg
+			m.addLog(msg.Msg)
 			m.gameState.player = msg.Player
+			if msg.Player.WorldID != m.gameState.world.ID {
+				return m, tea.Batch(
+					m.conn.getWorld(msg.Player.WorldID),
+					m.conn.listenForServerMessages(),
+				)
+			}
 			return m, m.conn.listenForServerMessages()
 		}
 
 		if msg.Type == "mobsUpdate" {
 			m.gameState.mobs = msg.Mobs
+			m.gameState.npcs = msg.NPCs
+			m.gameState.players = msg.Players
+			m.gameState.applyView(msg.View, msg.Tiles)
+			m.gameState.updateTarget()
+			return m, m.conn.listenForServerMessages()
+		}
+
+		if msg.Type == "combatEvent" {
+			m.addLog(msg.Msg)
+			if msg.Attacker == "You" {
+				m.gameState.target = &target{ID: msg.TargetID, Name: msg.Target, Health: msg.Health, MaxHealth: msg.MaxHealth}
+			}
+			return m, m.conn.listenForServerMessages()
+		}
+
+		if msg.Type == "abilities" {
+			m.abilities = msg.Abilities
+			return m, m.conn.listenForServerMessages()
+		}
+
+		if msg.Type == "dialogue" {
+			m.dialogue, m.shop = msg.Dialogue, nil
+			return m, m.conn.listenForServerMessages()
+		}
+
+		if msg.Type == "shop" {
+			m.addLog(msg.Msg)
+			shop := msg.Shop
+			m.dialogue, m.shop = nil, &shop
+			return m, m.conn.listenForServerMessages()
+		}
+
+		if msg.Type == "quests" {
+			m.quests = msg.Quests
+			return m, m.conn.listenForServerMessages()
+		}
+
+		if msg.Type == "trade" {
+			m.addLog(msg.Msg)
+			m.trade = msg.Trade
+			if m.trade == nil {
+				m.withdrawing = false
+			}
+			return m, m.conn.listenForServerMessages()
+		}
+
+		if msg.Type == "party" {
+			m.party = msg.Party
+			return m, m.conn.listenForServerMessages()
+		}
+
+		if msg.Type == "chat" {
+			if msg.From == "" {
+				m.addLog(fmt.Sprintf("[%s] %s", msg.Channel, msg.Msg))
+			} else {
+				m.addLog(fmt.Sprintf("[%s] %s: %s", msg.Channel, msg.From, msg.Msg))
+			}
+			return m, m.conn.listenForServerMessages()
+		}
+
+		if msg.Type == "tileUpdate" {
+			m.gameState.setTile(msg.X, msg.Y, msg.Glyph)
 			return m, m.conn.listenForServerMessages()
 		}
 
 		if msg.Type == "success" {
-			m.msgForNow = msg.Msg
+			m.addLog(msg.Msg)
 			m.err = nil
 			return m, m.conn.listenForServerMessages()
 		}
 
 		return m, m.conn.listenForServerMessages()
 
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+
 	case tea.KeyMsg:
+		if m.typing {
+			return m.updateInput(msg)
+		}
+
+		if m.doorMode {
+			m.doorMode = false
+			if dir, ok := keyDirections[msg.String()]; ok {
+				m.msgForNow = "Toggling door"
+				return m, tea.Batch(m.conn.sendToggleDoor(dir), m.conn.listenForServerMessages())
+			}
+			m.msgForNow = "Never mind"
+			return m, m.conn.listenForServerMessages()
+		}
+
+		if m.showJournal {
+			switch msg.String() {
+			case "J", "esc":
+				m.showJournal = false
+			case "ctrl+c", "q":
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+
+		if m.trade != nil {
+			if model, cmd, ok := m.updateTrade(msg.String()); ok {
+				return model, cmd
+			}
+		} else if m.dialogue != nil || m.shop != nil {
+			if model, cmd, ok := m.updatePanel(msg.String()); ok {
+				return model, cmd
+			}
+		}
+
+		if dir, ok := keyDirections[msg.String()]; ok {
+			m.facing = dir
+		}
+		if slot, ok := hotbarSlot(msg.String()); ok {
+			return m.useHotbar(slot)
+		}
+
 		switch msg.String() {
 		case "ctrl+c", "q":
 			m.msgForNow = "Quitting..."
@@ -80,12 +196,187 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "n":
 			m.msgForNow = "Moving down-right"
 			return m, tea.Batch(m.conn.sendMove("SE"), m.conn.listenForServerMessages())
+		case "m":
+			m.showMinimap = !m.showMinimap
+			return m, nil
+		case "c":
+			m.doorMode = true
+			m.msgForNow = "Toggle door in which direction?"
+			return m, m.conn.listenForServerMessages()
 		case "a":
 			m.msgForNow = "Attacking!"
-			return m, tea.Batch(m.conn.sendAttack(), m.conn.listenForServerMessages())
+			targetID := ""
+			if m.gameState.targetVisible() {
+				targetID = m.gameState.target.ID
+			}
+			return m, tea.Batch(m.conn.sendAttack(targetID), m.conn.listenForServerMessages())
+		case "J":
+			m.showJournal = true
+			return m, tea.Batch(m.conn.getQuests(), m.conn.listenForServerMessages())
+		case "e":
+			m.msgForNow = "Talking"
+			return m, tea.Batch(m.conn.sendInteract(), m.conn.listenForServerMessages())
+		case "enter":
+			m.typing, m.input = true, ""
+			return m, nil
+		case "tab":
+			if t := m.gameState.cycleTarget(); t != nil {
+				m.msgForNow = "Targeting " + t.Name
+			} else {
+				m.msgForNow = "No mob in sight"
+			}
+			return m, nil
+		case "esc":
+			m.gameState.target = nil
+			m.msgForNow = "Target cleared"
+			return m, nil
+		case "pgup", "[":
+			m.logScroll = min(m.logScroll+logLines, max(0, len(m.log)-1))
+			return m, nil
+		case "pgdown", "]":
+			m.logScroll = max(0, m.logScroll-logLines)
+			return m, nil
 		}
 
 		return m, m.conn.listenForServerMessages()
 	}
 	return m, nil
 }
+
+// updatePanel handles the keys of the open dialogue or shop: the number keys
+// pick a choice or the item to trade, tab switches the shop between buying
+// and selling and esc closes the panel. It reports whether it used the key.
+func (m Model) updatePanel(key string) (tea.Model, tea.Cmd, bool) {
+	switch key {
+	case "esc":
+		m.dialogue, m.shop, m.selling = nil, nil, false
+		return m, nil, true
+	case "tab":
+		if m.shop == nil {
+			return m, nil, false
+		}
+		m.selling = !m.selling
+		return m, nil, true
+	}
+	slot, ok := hotbarSlot(key)
+	if !ok {
+		return m, nil, false
+	}
+
+	if m.dialogue != nil {
+		if slot >= len(m.dialogue.Choices) {
+			return m, nil, true
+		}
+		return m, tea.Batch(m.conn.sendDialogue("choose", fmt.Sprint(slot+1)), m.conn.listenForServerMessages()), true
+	}
+	items := m.shopItems()
+	if slot >= len(items) {
+		return m, nil, true
+	}
+	msgType := "buy"
+	if m.selling {
+		msgType = "sell"
+	}
+	return m, tea.Batch(m.conn.sendDialogue(msgType, items[slot].ID), m.conn.listenForServerMessages()), true
+}
+
+// updateTrade handles the keys of the trade window: the number keys put up
+// one more of the listed item, or take one back after tab, + and - change
+// the gold offered, y confirms and esc cancels the trade. Moving cancels it
+// too, on the server. It reports whether it used the key.
+func (m Model) updateTrade(key string) (tea.Model, tea.Cmd, bool) {
+	var cmd tea.Cmd
+	switch key {
+	case "esc":
+		cmd = m.conn.sendCommand("tradeCancel", "", "")
+	case "tab":
+		m.withdrawing = !m.withdrawing
+		return m, nil, true
+	case "y":
+		cmd = m.conn.sendCommand("tradeConfirm", "", "")
+	case "+", "=":
+		cmd = m.conn.sendTradeOffer("gold", m.trade.Mine.Gold+1)
+	case "-":
+		if m.trade.Mine.Gold == 0 {
+			return m, nil, true
+		}
+		cmd = m.conn.sendTradeOffer("gold", m.trade.Mine.Gold-1)
+	default:
+		slot, ok := hotbarSlot(key)
+		if !ok {
+			return m, nil, false
+		}
+		items := m.trade.Carried
+		if m.withdrawing {
+			items = m.trade.Mine.Items
+		}
+		if slot >= len(items) {
+			return m, nil, true
+		}
+		item := items[slot]
+		count := offered(m.trade.Mine, item.ID) + 1
+		if m.withdrawing {
+			count -= 2
+		}
+		cmd = m.conn.sendTradeOffer(item.ID, count)
+	}
+	return m, tea.Batch(cmd, m.conn.listenForServerMessages()), true
+}
+
+// offered returns how many items itemID o puts up.
+func offered(o offer, itemID string) int {
+	for _, item := range o.Items {
+		if item.ID == itemID {
+			return item.Count
+		}
+	}
+	return 0
+}
+
+// shopItems returns the items of the open shop the player can trade in the
+// current mode: the ones for sale, or the ones they carry.
+func (m Model) shopItems() []shopItem {
+	var items []shopItem
+	for _, item := range m.shop.Items {
+		if (m.selling && item.Owned > 0) || (!m.selling && item.Price > 0) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// hotbarSlot maps the keys 1 to 9 to hotbar slots 0 to 8.
+func hotbarSlot(key string) (int, bool) {
+	if len(key) == 1 && key[0] >= '1' && key[0] <= '9' {
+		return int(key[0] - '1'), true
+	}
+	return 0, false
+}
+
+// useHotbar casts the ability in slot at the current target, aiming at the
+// facing direction when there is none.
+func (m Model) useHotbar(slot int) (tea.Model, tea.Cmd) {
+	if slot >= len(m.abilities) {
+		return m, nil
+	}
+	a := m.abilities[slot]
+	if m.gameState.player.Level < a.Level {
+		m.msgForNow = fmt.Sprintf("%s unlocks at level %d", a.Name, a.Level)
+		return m, nil
+	}
+	targetID := ""
+	if m.gameState.targetVisible() {
+		targetID = m.gameState.target.ID
+	}
+	m.msgForNow = "Using " + a.Name
+	return m, tea.Batch(m.conn.sendUseAbility(a.ID, targetID, m.facing), m.conn.listenForServerMessages())
+}
+
+var keyDirections = map[string]string{
+	"up": "N", "k": "N",
+	"down": "S", "j": "S",
+	"left": "W", "h": "W",
+	"right": "E", "l": "E",
+	"y": "NW", "u": "NE",
+	"b": "SW", "n": "SE",
+}
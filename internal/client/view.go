@@ -2,20 +2,372 @@ package client
 
 import (
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// headerLines is the number of status lines printed above the map, not
+// counting the party, and logLines the number of message log lines below it.
+const (
+	headerLines = 4
+	logLines    = 5
 )
 
+const hpBarWidth = 10
+
 func (m Model) View() string {
 	if m.err != nil {
 		return "Error: " + m.err.Error()
 	}
 
-	s := m.msgForNow + "\n"
-	s += fmt.Sprintf("World ID: %s (Width: %d, Height: %d)\n", m.gameState.world.ID, m.gameState.world.Width, m.gameState.world.Height)
-	s += fmt.Sprintf("Player: (%d, %d)\n", m.gameState.player.X, m.gameState.player.Y)
-	s += fmt.Sprintf("Entities: %d items, %d mobs\n", len(m.gameState.items), len(m.gameState.mobs))
-	s += "\n"
+	if m.showJournal {
+		return m.hud() + "\n" + m.journal()
+	}
+
+	s := m.hud() + "\n"
+	party := m.partyLines()
+	for _, line := range party {
+		s += line + "\n"
+	}
+	s += m.hotbar() + "\n"
+	s += m.targetLine() + "\n"
+	if m.typing {
+		s += "> " + m.input + "_\n"
+	} else {
+		s += m.msgForNow + "\n"
+	}
+
+	bottom, bottomLines := m.logPanel(), logLines
+	if panel := m.panel(); panel != "" {
+		bottom, bottomLines = panel, max(logLines, strings.Count(panel, "\n"))
+	}
+
+	if m.width <= 0 || m.height <= 0 {
+		return s + m.gameState.Render(0, 0, m.theme) + bottom
+	}
+
+	viewW, viewH := m.width, m.height-headerLines-len(party)-bottomLines
+	var minimap []string
+	if m.showMinimap {
+		minimap = m.gameState.Minimap()
+		if len(minimap) > 0 {
+			viewW -= len(minimap[0]) + 1
+		}
+	}
+
+	world := m.gameState.Render(viewW, viewH, m.theme)
+	if len(minimap) > 0 {
+		world = sideBySide(strings.Split(strings.TrimSuffix(world, "\n"), "\n"), minimap, viewW+1)
+	}
+	return s + world + bottom
+}
+
+// hud renders the status bar: health, level and experience, gold, a health
+// status, the current world and whether the player stands in a PvP or safe
+// zone.
+func (m Model) hud() string {
+	p := m.gameState.player
+	key, status := healthStatus(p.Health, p.MaxHealth)
+
+	base := m.theme.Style("hud")
+	text := m.theme.Style("hudText").Inherit(base)
+	bar := m.theme.Style(key).Inherit(base)
+
+	line := text.Render(" HP ") +
+		bar.Render(healthBar(p.Health, p.MaxHealth)) +
+		text.Render(fmt.Sprintf(" %d/%d  MP %d/%d  Lv %d (XP %d/%d)  %dg  ", p.Health, p.MaxHealth, p.Mana, p.MaxMana, p.Level, p.XP, xpToLevel(p.Level), p.Gold)) +
+		bar.Render(status) +
+		m.statusIcons(p.Statuses, base) +
+		text.Render("  "+m.gameState.world.Name+" ")
+	switch m.gameState.world.regionAt(p.X, p.Y) {
+	case "pvp":
+		line += m.theme.Style("hudBad").Inherit(base).Render("PvP ")
+	case "safe":
+		line += m.theme.Style("hudGood").Inherit(base).Render("Safe ")
+	}
+	if pad := m.width - lipgloss.Width(line); pad > 0 {
+		line += base.Render(strings.Repeat(" ", pad))
+	}
+	return line
+}
+
+// partyLines shows the health of the other party members, the leader
+// marked with a star and members in other worlds dimmed.
+func (m Model) partyLines() []string {
+	if m.party == nil {
+		return nil
+	}
+	var lines []string
+	for _, member := range m.party.Members {
+		if member.ID == m.gameState.player.ID {
+			continue
+		}
+		mark := " "
+		if member.ID == m.party.Leader {
+			mark = "★"
+		}
+		key, _ := healthStatus(member.Health, member.MaxHealth)
+		line := fmt.Sprintf(" %s %-12s Lv %-2d %s %d/%d", mark, member.ID, member.Level,
+			m.theme.Style(key).Render(healthBar(member.Health, member.MaxHealth)), member.Health, member.MaxHealth)
+		if member.WorldID != m.gameState.player.WorldID {
+			line = m.theme.Style("remembered").Render(fmt.Sprintf(" %s %-12s Lv %-2d elsewhere", mark, member.ID, member.Level))
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// hotbar lists the abilities bound to the number keys with their mana cost,
+// or what keeps them from being used.
+func (m Model) hotbar() string {
+	if len(m.abilities) == 0 {
+		return ""
+	}
+	p := m.gameState.player
+	now := time.Now()
+	var slots []string
+	for i, a := range m.abilities {
+		if i >= 9 {
+			break
+		}
+		slot := fmt.Sprintf("%d %s ", i+1, a.Name)
+		switch left := p.Cooldowns[a.ID].Sub(now); {
+		case p.Level < a.Level:
+			slot = m.theme.Style("remembered").Render(slot + fmt.Sprintf("Lv%d", a.Level))
+		case left > 0:
+			slot = m.theme.Style("hudWarn").Render(slot + fmt.Sprintf("%.1fs", left.Seconds()))
+		case p.Mana < a.ManaCost:
+			slot = m.theme.Style("hudBad").Render(slot + fmt.Sprintf("%dmp", a.ManaCost))
+		default:
+			slot += fmt.Sprintf("%dmp", a.ManaCost)
+		}
+		slots = append(slots, "["+slot+"]")
+	}
+	return " " + strings.Join(slots, " ")
+}
+
+// targetLine shows the health of the selected mob.
+func (m Model) targetLine() string {
+	t := m.gameState.target
+	if t == nil {
+		return " No target"
+	}
+	if t.Health <= 0 {
+		return fmt.Sprintf(" Target: %s (dead)", t.Name)
+	}
+	key, _ := healthStatus(t.Health, t.MaxHealth)
+	return fmt.Sprintf(" Target: %s %s %d/%d", t.Name,
+		m.theme.Style(key).Render(healthBar(t.Health, t.MaxHealth)), t.Health, t.MaxHealth) +
+		m.statusIcons(t.Statuses, lipgloss.NewStyle())
+}
+
+// statusIcons draws one icon per status effect followed by the ticks it has
+// left, styled with the "status:<kind>" theme keys on top of base.
+func (m Model) statusIcons(statuses []status, base lipgloss.Style) string {
+	var b strings.Builder
+	for _, s := range statuses {
+		icon, ok := statusIcon[s.Kind]
+		if !ok {
+			icon = "?"
+		}
+		b.WriteString(base.Render(" "))
+		b.WriteString(m.theme.Style("status:" + s.Kind).Inherit(base).Render(fmt.Sprintf("%s%d", icon, s.Ticks)))
+	}
+	return b.String()
+}
+
+var statusIcon = map[string]string{
+	"poison":  "☠",
+	"burning": "♨",
+	"slow":    "≈",
+	"stun":    "✦",
+	"regen":   "✚",
+	"shield":  "◈",
+}
+
+// logPanel renders the last logLines entries of the message log, or older
+// ones when scrolled back.
+func (m Model) logPanel() string {
+	lines := logLines
+	if m.logScroll > 0 {
+		lines-- // room for the scroll hint
+	}
+	end := len(m.log) - m.logScroll
+	start := max(0, end-lines)
+
+	var b strings.Builder
+	for _, entry := range m.log[start:end] {
+		b.WriteString("> " + entry + "\n")
+	}
+	if m.logScroll > 0 {
+		b.WriteString(fmt.Sprintf("-- %d newer, press ] to scroll down --\n", m.logScroll))
+	}
+	return b.String()
+}
+
+// journal renders the quest journal: the quests in progress with their
+// objectives, then the completed ones.
+func (m Model) journal() string {
+	var b strings.Builder
+	b.WriteString(" Quest journal (J or esc to close)\n\n")
+	if len(m.quests) == 0 {
+		b.WriteString(" No quests yet. Talk to the people you meet with e.\n")
+	}
+	done := m.theme.Style("remembered")
+	for _, q := range m.quests {
+		if q.Completed {
+			b.WriteString(done.Render(" ✔ "+q.Name) + "\n")
+			continue
+		}
+		b.WriteString(" " + m.theme.Style("hudWarn").Render(q.Name) + "\n")
+		b.WriteString("   " + q.Description + "\n")
+		for _, o := range q.Objectives {
+			line := "   - " + o.Text
+			if o.Needed > 1 {
+				line += fmt.Sprintf(" (%d/%d)", o.Count, o.Needed)
+			}
+			if o.Done {
+				line = done.Render(line + " ✔")
+			}
+			b.WriteString(line + "\n")
+		}
+		if q.Reward != "" {
+			b.WriteString("   Reward: " + q.Reward + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// panel renders the open trade, dialogue or shop, or "" when none is open.
+func (m Model) panel() string {
+	var b strings.Builder
+	switch {
+	case m.trade != nil:
+		t := m.trade
+		mode := "offer"
+		if m.withdrawing {
+			mode = "take back"
+		}
+		fmt.Fprintf(&b, "Trading with %s  (1-9 %s, tab to switch, +/- gold, y to confirm, esc to cancel)\n",
+			m.theme.Style("npc").Render(t.Partner), mode)
+		fmt.Fprintf(&b, "  You offer: %s%s\n", describeOffer(t.Mine), confirmMark(t.Confirmed))
+		fmt.Fprintf(&b, "  They offer: %s%s\n", describeOffer(t.Theirs), confirmMark(t.PartnerConfirmed))
+		items := t.Carried
+		if m.withdrawing {
+			items = t.Mine.Items
+		}
+		var slots []string
+		for i, item := range items {
+			if i >= 9 {
+				break
+			}
+			slots = append(slots, fmt.Sprintf("%d. %s x%d", i+1, item.Name, item.Count))
+		}
+		if len(slots) == 0 {
+			slots = append(slots, "Nothing to "+mode)
+		}
+		b.WriteString("  " + strings.Join(slots, "  ") + "\n")
+	case m.dialogue != nil:
+		d := m.dialogue
+		b.WriteString(m.theme.Style("npc").Render(d.Name) + ": " + d.Text + "\n")
+		for i, c := range d.Choices {
+			fmt.Fprintf(&b, "  %d. %s\n", i+1, c)
+		}
+		if len(d.Choices) == 0 {
+			b.WriteString("  (esc to leave)\n")
+		}
+	case m.shop != nil:
+		mode, other := "Buying", "sell"
+		if m.selling {
+			mode, other = "Selling", "buy"
+		}
+		fmt.Fprintf(&b, "%s  %s, %dg  (tab to %s, esc to leave)\n",
+			m.theme.Style("npc").Render(m.shop.Name), mode, m.gameState.player.Gold, other)
+		items := m.shopItems()
+		for i, item := range items {
+			price := item.Price
+			if m.selling {
+				price = item.SellPrice
+			}
+			line := fmt.Sprintf("  %d. %s  %dg", i+1, item.Name, price)
+			if item.Owned > 0 {
+				line += fmt.Sprintf("  (have %d)", item.Owned)
+			}
+			if !m.selling && price > m.gameState.player.Gold {
+				line = m.theme.Style("remembered").Render(line)
+			}
+			b.WriteString(line + "\n")
+		}
+		if len(items) == 0 {
+			b.WriteString("  Nothing to trade\n")
+		}
+	}
+	return b.String()
+}
 
-	s += m.gameState.Render()
+// describeOffer lists what an offer puts up like "5g, 2 Bread".
+func describeOffer(o offer) string {
+	var parts []string
+	if o.Gold > 0 {
+		parts = append(parts, fmt.Sprintf("%dg", o.Gold))
+	}
+	for _, item := range o.Items {
+		parts = append(parts, fmt.Sprintf("%d %s", item.Count, item.Name))
+	}
+	if len(parts) == 0 {
+		return "nothing"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func confirmMark(confirmed bool) string {
+	if confirmed {
+		return "  ✔ confirmed"
+	}
+	return ""
+}
 
-	return s
+// healthBar draws health as a hpBarWidth wide gauge.
+func healthBar(health, maxHealth int) string {
+	maxHealth = max(1, maxHealth)
+	health = max(0, min(health, maxHealth))
+	filled := (health*hpBarWidth + maxHealth - 1) / maxHealth
+	return strings.Repeat("█", filled) + strings.Repeat("░", hpBarWidth-filled)
+}
+
+// healthStatus returns the theme key and word describing health.
+func healthStatus(health, maxHealth int) (key, status string) {
+	maxHealth = max(1, maxHealth)
+	switch {
+	case health*4 <= maxHealth:
+		return "hudBad", "critical"
+	case health*2 <= maxHealth:
+		return "hudWarn", "wounded"
+	}
+	return "hudGood", "healthy"
+}
+
+// xpToLevel mirrors the server's experience curve.
+func xpToLevel(level int) int {
+	return 100 * max(1, level)
+}
+
+// sideBySide prints the left lines padded to width followed by the right
+// lines.
+func sideBySide(left, right []string, width int) string {
+	var b strings.Builder
+	for i := 0; i < max(len(left), len(right)); i++ {
+		line := ""
+		if i < len(left) {
+			line = left[i]
+		}
+		if i < len(right) {
+			line += strings.Repeat(" ", max(0, width-lipgloss.Width(line))) + right[i]
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
 }
@@ -0,0 +1,309 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Config holds the settings of both the game server and the terminal client.
+// Values are resolved from defaults, then the config file, then environment
+// variables, then command line flags, each layer overriding the previous one.
+type Config struct {
+	Server ServerConfig `json:"server"`
+	Store  StoreConfig  `json:"store"`
+	Game   GameConfig   `json:"game"`
+	Admin  AdminConfig  `json:"admin"`
+	Log    LogConfig    `json:"log"`
+	Client ClientConfig `json:"client"`
+}
+
+type ServerConfig struct {
+	Listen string `json:"listen"`
+	// AdminListen is the address of the HTTP admin API, empty to disable
+	// it.
+	AdminListen string `json:"adminListen"`
+	// MetricsListen is the address serving the Prometheus metrics on
+	// /metrics, empty to disable it.
+	MetricsListen string `json:"metricsListen"`
+	// Pprof serves the net/http/pprof profiles on /debug/pprof/ of the
+	// admin API, behind its bearer token.
+	Pprof bool `json:"pprof"`
+}
+
+type StoreConfig struct {
+	Backend string `json:"backend"`
+	DSN     string `json:"dsn"`
+}
+
+type GameConfig struct {
+	TickRate Duration    `json:"tickRate"`
+	MapsDir  string      `json:"mapsDir"`
+	DataDir  string      `json:"dataDir"`
+	Worlds   []string    `json:"worlds"`
+	Spawn    SpawnConfig `json:"spawn"`
+	// Seed seeds combat rolls; zero picks a random seed at startup.
+	Seed           int64    `json:"seed"`
+	AttackCooldown Duration `json:"attackCooldown"`
+	// SaveInterval is how often the game loop saves the online players,
+	// zero to never save them on its own.
+	SaveInterval Duration `json:"saveInterval"`
+}
+
+type SpawnConfig struct {
+	MobCap  int    `json:"mobCap"`
+	MobType string `json:"mobType"`
+	MobName string `json:"mobName"`
+}
+
+// AdminConfig sets who may run the admin commands of the server.
+type AdminConfig struct {
+	// Roles maps secret login tokens to the role ("player", "moderator"
+	// or "admin") a player gets by typing /login with the token.
+	Roles map[string]string `json:"roles"`
+	// Token lets whoever knows it become admin with /login; empty
+	// disables /login.
+	Token string `json:"token"`
+	// AuditLog is the file the admin commands are recorded in, appended
+	// to; empty records them in the server log.
+	AuditLog string `json:"auditLog"`
+	// APIToken is the bearer token the HTTP admin API requires.
+	APIToken string `json:"apiToken"`
+}
+
+type LogConfig struct {
+	Level  string `json:"level"`
+	Format string `json:"format"`
+	// Levels override Level for the subsystems named in LogSubsystems.
+	Levels map[string]string `json:"levels"`
+}
+
+// LogSubsystems are the parts of the server whose log level can be set on
+// their own. Their loggers carry a "subsystem" attribute naming them.
+var LogSubsystems = []string{"app", "server", "admin", "store", "metrics"}
+
+type ClientConfig struct {
+	ServerAddr string `json:"serverAddr"`
+	// Theme is a built-in theme name or the path of a JSON theme file.
+	Theme string `json:"theme"`
+	// Name is the player to join as, empty for the name of the OS user.
+	Name string `json:"name"`
+	// Secret proves to the server that Name is ours; empty uses the one
+	// kept in the user config directory, made up on first use.
+	Secret string `json:"secret"`
+}
+
+const (
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+)
+
+func Default() Config {
+	return Config{
+		Server: ServerConfig{Listen: ":4200"},
+		Store:  StoreConfig{Backend: BackendMemory},
+		Game: GameConfig{
+			TickRate: Duration(500 * time.Millisecond),
+			MapsDir:  "maps",
+			DataDir:  "data",
+			Worlds:   []string{"world1", "cellar", "lair"},
+			Spawn:    SpawnConfig{MobCap: 5, MobType: "Goblin", MobName: "Goblin"},
+
+			AttackCooldown: Duration(800 * time.Millisecond),
+			SaveInterval:   Duration(time.Minute),
+		},
+		Admin:  AdminConfig{Roles: map[string]string{}},
+		Log:    LogConfig{Level: "info", Format: "text", Levels: map[string]string{}},
+		Client: ClientConfig{ServerAddr: "localhost:4200", Theme: "default"},
+	}
+}
+
+// redacted replaces the secrets of the configuration when they are set.
+const redacted = "***"
+
+// Redacted returns a copy of c that is safe to print: the DSN, the tokens,
+// the login tokens of Roles and the client secret read "***" when set.
+// Roles keeps one entry per token, numbered in token order.
+func (c Config) Redacted() Config {
+	hide := func(s *string) {
+		if *s != "" {
+			*s = redacted
+		}
+	}
+	hide(&c.Store.DSN)
+	hide(&c.Admin.Token)
+	hide(&c.Admin.APIToken)
+	hide(&c.Client.Secret)
+
+	tokens := slices.Sorted(maps.Keys(c.Admin.Roles))
+	roles := make(map[string]string, len(tokens))
+	for i, token := range tokens {
+		roles[fmt.Sprintf("%s%d", redacted, i+1)] = c.Admin.Roles[token]
+	}
+	c.Admin.Roles = roles
+	return c
+}
+
+// Duration is a time.Duration that reads and writes as a string like "500ms".
+type Duration time.Duration
+
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"500ms\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) ValidateServer() error {
+	if err := validateAddr("server.listen", c.Server.Listen); err != nil {
+		return err
+	}
+	if c.Server.AdminListen != "" {
+		if err := validateAddr("server.adminListen", c.Server.AdminListen); err != nil {
+			return err
+		}
+		if c.Admin.APIToken == "" {
+			return fmt.Errorf("admin.apiToken is required when server.adminListen is set")
+		}
+	}
+	if c.Server.MetricsListen != "" {
+		if err := validateAddr("server.metricsListen", c.Server.MetricsListen); err != nil {
+			return err
+		}
+	}
+	if c.Server.Pprof && c.Server.AdminListen == "" {
+		return fmt.Errorf("server.pprof needs server.adminListen to be set")
+	}
+	switch c.Store.Backend {
+	case BackendMemory:
+	case BackendPostgres:
+		if c.Store.DSN == "" {
+			return fmt.Errorf("store.dsn is required for the %s backend", BackendPostgres)
+		}
+	default:
+		return fmt.Errorf("store.backend must be %q or %q, got %q", BackendMemory, BackendPostgres, c.Store.Backend)
+	}
+	if c.Game.TickRate.Std() < 10*time.Millisecond {
+		return fmt.Errorf("game.tickRate must be at least 10ms, got %s", c.Game.TickRate)
+	}
+	if c.Game.AttackCooldown < 0 {
+		return fmt.Errorf("game.attackCooldown must not be negative, got %s", c.Game.AttackCooldown)
+	}
+	if c.Game.SaveInterval < 0 {
+		return fmt.Errorf("game.saveInterval must not be negative, got %s", c.Game.SaveInterval)
+	}
+	if c.Game.MapsDir == "" {
+		return fmt.Errorf("game.mapsDir is required")
+	}
+	if c.Game.DataDir == "" {
+		return fmt.Errorf("game.dataDir is required")
+	}
+	if len(c.Game.Worlds) == 0 {
+		return fmt.Errorf("game.worlds must list at least one world")
+	}
+	seen := make(map[string]bool)
+	for _, id := range c.Game.Worlds {
+		if id == "" {
+			return fmt.Errorf("game.worlds contains an empty world id")
+		}
+		if seen[id] {
+			return fmt.Errorf("game.worlds lists %q twice", id)
+		}
+		seen[id] = true
+	}
+	if c.Game.Spawn.MobCap < 0 {
+		return fmt.Errorf("game.spawn.mobCap must not be negative, got %d", c.Game.Spawn.MobCap)
+	}
+	if c.Game.Spawn.MobCap > 0 && c.Game.Spawn.MobType == "" {
+		return fmt.Errorf("game.spawn.mobType is required when mobCap is positive")
+	}
+	for token, role := range c.Admin.Roles {
+		if token == "" {
+			return fmt.Errorf("admin.roles: tokens must not be empty")
+		}
+		switch role {
+		case "player", "moderator", "admin":
+		default:
+			// The token is a secret, leave it out of the error.
+			return fmt.Errorf("admin.roles: roles must be player, moderator or admin, got %q", role)
+		}
+	}
+	return c.validateLog()
+}
+
+func (c *Config) ValidateClient() error {
+	return validateAddr("client.serverAddr", c.Client.ServerAddr)
+}
+
+func (c *Config) validateLog() error {
+	if !validLogLevel(c.Log.Level) {
+		return fmt.Errorf("log.level must be one of debug, info, warn, error, got %q", c.Log.Level)
+	}
+	for subsystem, level := range c.Log.Levels {
+		if !slices.Contains(LogSubsystems, subsystem) {
+			return fmt.Errorf("log.levels: unknown subsystem %q, want one of %s", subsystem, strings.Join(LogSubsystems, ", "))
+		}
+		if !validLogLevel(level) {
+			return fmt.Errorf("log.levels: %s must be one of debug, info, warn, error, got %q", subsystem, level)
+		}
+	}
+	switch c.Log.Format {
+	case "text", "json":
+	default:
+		return fmt.Errorf("log.format must be text or json, got %q", c.Log.Format)
+	}
+	return nil
+}
+
+func validLogLevel(level string) bool {
+	switch level {
+	case "debug", "info", "warn", "error":
+		return true
+	}
+	return false
+}
+
+func validateAddr(name, addr string) error {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return fmt.Errorf("%s: invalid address %q: %w", name, addr, err)
+	}
+	return nil
+}
@@ -0,0 +1,343 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrPrintConfig is returned by LoadServer and LoadClient when --print-config
+// was given. The resolved configuration has already been written to out.
+var ErrPrintConfig = errors.New("config printed")
+
+const configEnv = "TERMINUS_CONFIG"
+
+// option is a single setting reachable from both the environment and the
+// command line.
+type option struct {
+	flag  string
+	env   string
+	usage string
+	get   func(c *Config) string
+	set   func(c *Config, v string) error
+	// boolean lets the flag be given without a value, meaning true.
+	boolean bool
+}
+
+var logOptions = []option{
+	{
+		flag: "log-level", env: "TERMINUS_LOG_LEVEL", usage: "log level (debug, info, warn, error)",
+		get: func(c *Config) string { return c.Log.Level },
+		set: func(c *Config, v string) error { c.Log.Level = v; return nil },
+	},
+	{
+		flag: "log-format", env: "TERMINUS_LOG_FORMAT", usage: "log format (text, json)",
+		get: func(c *Config) string { return c.Log.Format },
+		set: func(c *Config, v string) error { c.Log.Format = v; return nil },
+	},
+	{
+		flag: "log-levels", env: "TERMINUS_LOG_LEVELS", usage: "comma separated subsystem=level pairs overriding the log level of app, server, admin, store or metrics",
+		get: func(c *Config) string { return joinPairs(c.Log.Levels) },
+		set: func(c *Config, v string) error {
+			levels, err := splitPairs(v, "subsystem=level")
+			if err != nil {
+				return err
+			}
+			c.Log.Levels = levels
+			return nil
+		},
+	},
+}
+
+var serverOptions = append([]option{
+	{
+		flag: "listen", env: "TERMINUS_LISTEN", usage: "address the game server listens on",
+		get: func(c *Config) string { return c.Server.Listen },
+		set: func(c *Config, v string) error { c.Server.Listen = v; return nil },
+	},
+	{
+		flag: "admin-listen", env: "TERMINUS_ADMIN_LISTEN", usage: "address the HTTP admin API listens on, empty to disable it",
+		get: func(c *Config) string { return c.Server.AdminListen },
+		set: func(c *Config, v string) error { c.Server.AdminListen = v; return nil },
+	},
+	{
+		flag: "metrics-listen", env: "TERMINUS_METRICS_LISTEN", usage: "address serving the Prometheus metrics on /metrics, empty to disable them",
+		get: func(c *Config) string { return c.Server.MetricsListen },
+		set: func(c *Config, v string) error { c.Server.MetricsListen = v; return nil },
+	},
+	{
+		flag: "pprof", env: "TERMINUS_PPROF", usage: "serve the pprof profiles on /debug/pprof/ of the admin API", boolean: true,
+		get: func(c *Config) string { return strconv.FormatBool(c.Server.Pprof) },
+		set: func(c *Config, v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return err
+			}
+			c.Server.Pprof = b
+			return nil
+		},
+	},
+	{
+		flag: "store", env: "TERMINUS_STORE", usage: "store backend (memory, postgres)",
+		get: func(c *Config) string { return c.Store.Backend },
+		set: func(c *Config, v string) error { c.Store.Backend = v; return nil },
+	},
+	{
+		flag: "dsn", env: "TERMINUS_DSN", usage: "postgres connection string",
+		get: func(c *Config) string { return c.Store.DSN },
+		set: func(c *Config, v string) error { c.Store.DSN = v; return nil },
+	},
+	{
+		flag: "tick-rate", env: "TERMINUS_TICK_RATE", usage: "game loop tick interval",
+		get: func(c *Config) string { return c.Game.TickRate.String() },
+		set: func(c *Config, v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return err
+			}
+			c.Game.TickRate = Duration(d)
+			return nil
+		},
+	},
+	{
+		flag: "maps", env: "TERMINUS_MAPS_DIR", usage: "directory holding the world map files",
+		get: func(c *Config) string { return c.Game.MapsDir },
+		set: func(c *Config, v string) error { c.Game.MapsDir = v; return nil },
+	},
+	{
+		flag: "data", env: "TERMINUS_DATA_DIR", usage: "directory holding the game data files",
+		get: func(c *Config) string { return c.Game.DataDir },
+		set: func(c *Config, v string) error { c.Game.DataDir = v; return nil },
+	},
+	{
+		flag: "worlds", env: "TERMINUS_WORLDS", usage: "comma separated world ids to load",
+		get: func(c *Config) string { return strings.Join(c.Game.Worlds, ",") },
+		set: func(c *Config, v string) error { c.Game.Worlds = splitList(v); return nil },
+	},
+	{
+		flag: "seed", env: "TERMINUS_SEED", usage: "seed of the combat rolls, 0 for a random one",
+		get: func(c *Config) string { return strconv.FormatInt(c.Game.Seed, 10) },
+		set: func(c *Config, v string) error {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return err
+			}
+			c.Game.Seed = n
+			return nil
+		},
+	},
+	{
+		flag: "attack-cooldown", env: "TERMINUS_ATTACK_COOLDOWN", usage: "minimum time between two attacks of a player",
+		get: func(c *Config) string { return c.Game.AttackCooldown.String() },
+		set: func(c *Config, v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return err
+			}
+			c.Game.AttackCooldown = Duration(d)
+			return nil
+		},
+	},
+	{
+		flag: "save-interval", env: "TERMINUS_SAVE_INTERVAL", usage: "how often the game loop saves the online players, 0 to never",
+		get: func(c *Config) string { return c.Game.SaveInterval.String() },
+		set: func(c *Config, v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return err
+			}
+			c.Game.SaveInterval = Duration(d)
+			return nil
+		},
+	},
+	{
+		flag: "mob-cap", env: "TERMINUS_MOB_CAP", usage: "maximum number of mobs per world",
+		get: func(c *Config) string { return strconv.Itoa(c.Game.Spawn.MobCap) },
+		set: func(c *Config, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return err
+			}
+			c.Game.Spawn.MobCap = n
+			return nil
+		},
+	},
+	{
+		flag: "mob-type", env: "TERMINUS_MOB_TYPE", usage: "type of the mobs spawned by the game loop",
+		get: func(c *Config) string { return c.Game.Spawn.MobType },
+		set: func(c *Config, v string) error { c.Game.Spawn.MobType = v; return nil },
+	},
+	{
+		flag: "spawn-mob-name", env: "TERMINUS_SPAWN_MOB_NAME", usage: "name of the mobs spawned by the game loop, empty for their type",
+		get: func(c *Config) string { return c.Game.Spawn.MobName },
+		set: func(c *Config, v string) error { c.Game.Spawn.MobName = v; return nil },
+	},
+	{
+		flag: "roles", env: "TERMINUS_ROLES", usage: "comma separated token=role pairs, /login <token> grants the role",
+		get: func(c *Config) string { return joinPairs(c.Admin.Roles) },
+		set: func(c *Config, v string) error {
+			roles, err := splitPairs(v, "token=role")
+			if err != nil {
+				return err
+			}
+			c.Admin.Roles = roles
+			return nil
+		},
+	},
+	{
+		flag: "admin-token", env: "TERMINUS_ADMIN_TOKEN", usage: "secret that makes players admin with /login, empty to disable",
+		get: func(c *Config) string { return c.Admin.Token },
+		set: func(c *Config, v string) error { c.Admin.Token = v; return nil },
+	},
+	{
+		flag: "audit-log", env: "TERMINUS_AUDIT_LOG", usage: "file recording the admin commands, empty for the server log",
+		get: func(c *Config) string { return c.Admin.AuditLog },
+		set: func(c *Config, v string) error { c.Admin.AuditLog = v; return nil },
+	},
+	{
+		flag: "api-token", env: "TERMINUS_API_TOKEN", usage: "bearer token required by the HTTP admin API",
+		get: func(c *Config) string { return c.Admin.APIToken },
+		set: func(c *Config, v string) error { c.Admin.APIToken = v; return nil },
+	},
+}, logOptions...)
+
+var clientOptions = []option{
+	{
+		flag: "server", env: "TERMINUS_SERVER_ADDR", usage: "address of the game server",
+		get: func(c *Config) string { return c.Client.ServerAddr },
+		set: func(c *Config, v string) error { c.Client.ServerAddr = v; return nil },
+	},
+	{
+		flag: "theme", env: "TERMINUS_THEME", usage: "color theme: default, monochrome or a JSON theme file",
+		get: func(c *Config) string { return c.Client.Theme },
+		set: func(c *Config, v string) error { c.Client.Theme = v; return nil },
+	},
+	{
+		flag: "name", env: "TERMINUS_NAME", usage: "player name to join as, the OS user name by default",
+		get: func(c *Config) string { return c.Client.Name },
+		set: func(c *Config, v string) error { c.Client.Name = v; return nil },
+	},
+	{
+		flag: "secret", env: "TERMINUS_SECRET", usage: "secret that proves the player name is yours",
+		get: func(c *Config) string { return c.Client.Secret },
+		set: func(c *Config, v string) error { c.Client.Secret = v; return nil },
+	},
+}
+
+// LoadServer resolves the game server configuration from args and getenv.
+func LoadServer(args []string, getenv func(string) string, out io.Writer) (*Config, error) {
+	return load("server", serverOptions, (*Config).ValidateServer, args, getenv, out)
+}
+
+// LoadClient resolves the terminal client configuration from args and getenv.
+func LoadClient(args []string, getenv func(string) string, out io.Writer) (*Config, error) {
+	return load("client", clientOptions, (*Config).ValidateClient, args, getenv, out)
+}
+
+func load(name string, opts []option, validate func(*Config) error, args []string, getenv func(string) string, out io.Writer) (*Config, error) {
+	defaults := Default()
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configPath := fs.String("config", getenv(configEnv), "path to a JSON config file (env "+configEnv+")")
+	printConfig := fs.Bool("print-config", false, "print the resolved configuration, secrets redacted, and exit")
+
+	type flagValue struct {
+		opt   option
+		value string
+	}
+	var flagValues []flagValue
+	for _, opt := range opts {
+		opt := opt
+		usage := fmt.Sprintf("%s (env %s, default %q)", opt.usage, opt.env, opt.get(&defaults))
+		record := func(v string) error {
+			flagValues = append(flagValues, flagValue{opt: opt, value: v})
+			return nil
+		}
+		if opt.boolean {
+			fs.BoolFunc(opt.flag, usage, record)
+		} else {
+			fs.Func(opt.flag, usage, record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := defaults
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, opt := range opts {
+		v := getenv(opt.env)
+		if v == "" {
+			continue
+		}
+		if err := opt.set(&cfg, v); err != nil {
+			return nil, fmt.Errorf("%s: %w", opt.env, err)
+		}
+	}
+
+	for _, fv := range flagValues {
+		if err := fv.opt.set(&cfg, fv.value); err != nil {
+			return nil, fmt.Errorf("-%s: %w", fv.opt.flag, err)
+		}
+	}
+
+	if err := validate(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	if *printConfig {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(cfg.Redacted()); err != nil {
+			return nil, err
+		}
+		return nil, ErrPrintConfig
+	}
+
+	return &cfg, nil
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// splitPairs parses comma separated key=value pairs, what naming their
+// form in errors.
+func splitPairs(v, what string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	for _, pair := range splitList(v) {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not a %s pair", pair, what)
+		}
+		pairs[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return pairs, nil
+}
+
+// joinPairs formats pairs the way splitPairs reads them, sorted by key.
+func joinPairs(pairs map[string]string) string {
+	out := make([]string, 0, len(pairs))
+	for key, value := range pairs {
+		out = append(out, key+"="+value)
+	}
+	sort.Strings(out)
+	return strings.Join(out, ",")
+}
@@ -0,0 +1,68 @@
+package config_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/LealKevin/terminus/internal/config"
+)
+
+func TestPrintConfigRedactsSecrets(t *testing.T) {
+	args := []string{
+		"-print-config",
+		"-store", "postgres",
+		"-dsn", "postgres://terminus:hunter2@db/terminus",
+		"-admin-token", "admin-secret",
+		"-api-token", "api-secret",
+		"-roles", "mod-secret=moderator,other-secret=admin",
+	}
+	var out bytes.Buffer
+	_, err := config.LoadServer(args, func(string) string { return "" }, &out)
+	if !errors.Is(err, config.ErrPrintConfig) {
+		t.Fatalf("LoadServer = %v, want ErrPrintConfig", err)
+	}
+
+	for _, secret := range []string{"hunter2", "admin-secret", "api-secret", "mod-secret", "other-secret"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("printed configuration shows %q:\n%s", secret, out.String())
+		}
+	}
+	var printed config.Config
+	if err := json.Unmarshal(out.Bytes(), &printed); err != nil {
+		t.Fatal(err)
+	}
+	if printed.Store.DSN != "***" || printed.Admin.Token != "***" || printed.Admin.APIToken != "***" {
+		t.Errorf("printed dsn %q, token %q, api token %q, want them all ***", printed.Store.DSN, printed.Admin.Token, printed.Admin.APIToken)
+	}
+	wantRoles := map[string]string{"***1": "moderator", "***2": "admin"}
+	if len(printed.Admin.Roles) != len(wantRoles) {
+		t.Errorf("printed roles %v, want %v", printed.Admin.Roles, wantRoles)
+	}
+	for token, role := range wantRoles {
+		if printed.Admin.Roles[token] != role {
+			t.Errorf("printed roles %v, want %v", printed.Admin.Roles, wantRoles)
+		}
+	}
+	if printed.Store.Backend != "postgres" {
+		t.Errorf("printed backend %q, want the settings that are not secret kept", printed.Store.Backend)
+	}
+}
+
+func TestPrintClientConfigRedactsTheSecret(t *testing.T) {
+	env := map[string]string{"TERMINUS_SECRET": "client-secret"}
+	var out bytes.Buffer
+	_, err := config.LoadClient([]string{"-print-config", "-name", "alice"}, func(k string) string { return env[k] }, &out)
+	if !errors.Is(err, config.ErrPrintConfig) {
+		t.Fatalf("LoadClient = %v, want ErrPrintConfig", err)
+	}
+	var printed config.Config
+	if err := json.Unmarshal(out.Bytes(), &printed); err != nil {
+		t.Fatal(err)
+	}
+	if printed.Client.Secret != "***" || printed.Client.Name != "alice" {
+		t.Errorf("printed name %q and secret %q, want alice and ***", printed.Client.Name, printed.Client.Secret)
+	}
+}
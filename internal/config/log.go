@@ -0,0 +1,63 @@
+package config
+
+import (
+	"context"
+	"io"
+	"log/slog"
+)
+
+// NewLogger builds a logger honouring the configured level and format. The
+// loggers derived from it with a "subsystem" attribute, as in
+// logger.With("subsystem", "store"), use the level set for that subsystem.
+func (l LogConfig) NewLogger(w io.Writer) *slog.Logger {
+	level := parseLevel(l.Level)
+	levels := make(map[string]slog.Level, len(l.Levels))
+	lowest := level
+	for subsystem, name := range l.Levels {
+		levels[subsystem] = parseLevel(name)
+		lowest = min(lowest, levels[subsystem])
+	}
+	opts := &slog.HandlerOptions{Level: lowest}
+	var base slog.Handler = slog.NewTextHandler(w, opts)
+	if l.Format == "json" {
+		base = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(&levelHandler{Handler: base, level: level, levels: levels})
+}
+
+func parseLevel(name string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// levelHandler filters records by the level of the subsystem its logger
+// belongs to, level until a subsystem attribute is added.
+type levelHandler struct {
+	slog.Handler
+	level  slog.Level
+	levels map[string]slog.Level
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level && h.Handler.Enabled(ctx, level)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	level := h.level
+	for _, attr := range attrs {
+		if attr.Key != "subsystem" {
+			continue
+		}
+		if l, ok := h.levels[attr.Value.String()]; ok {
+			level = l
+		}
+	}
+	return &levelHandler{Handler: h.Handler.WithAttrs(attrs), level: level, levels: h.levels}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithGroup(name), level: h.level, levels: h.levels}
+}
@@ -0,0 +1,206 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Shape is the area an ability affects.
+type Shape string
+
+const (
+	// ShapeSelf affects the caster only.
+	ShapeSelf Shape = "self"
+	// ShapeSingle affects the targeted cell only.
+	ShapeSingle Shape = "single"
+	// ShapeLine affects Size cells in a straight line from the caster,
+	// stopping at the first opaque tile.
+	ShapeLine Shape = "line"
+	// ShapeCone affects a 90 degree cone Size cells deep in front of the
+	// caster.
+	ShapeCone Shape = "cone"
+	// ShapeRadius affects every cell within Size of the target, or of the
+	// caster when the ability has no range.
+	ShapeRadius Shape = "radius"
+)
+
+// EffectKind is what an ability does to whoever it affects.
+type EffectKind string
+
+const (
+	EffectDamage    EffectKind = "damage"
+	EffectHeal      EffectKind = "heal"
+	EffectKnockback EffectKind = "knockback"
+	EffectStatus    EffectKind = "status"
+)
+
+// Effect is one outcome of an ability. Damage, knockback and harmful
+// statuses hit the mobs in the area; heals and beneficial statuses go to the
+// players in it.
+type Effect struct {
+	Kind EffectKind `json:"kind"`
+	// Amount is the damage, healing or knockback distance.
+	Amount int        `json:"amount,omitempty"`
+	Status StatusKind `json:"status,omitempty"`
+	Ticks  int        `json:"ticks,omitempty"`
+	Power  int        `json:"power,omitempty"`
+}
+
+// Hostile reports whether the effect is meant for enemies.
+func (e Effect) Hostile() bool {
+	switch e.Kind {
+	case EffectHeal:
+		return false
+	case EffectStatus:
+		return !e.Status.Beneficial()
+	}
+	return true
+}
+
+// Ability is a skill players can use. Abilities are data: they are loaded
+// from a file and players learn them when they reach Level.
+type Ability struct {
+	ID       string        `json:"id"`
+	Name     string        `json:"name"`
+	Level    int           `json:"level"`
+	ManaCost int           `json:"manaCost"`
+	Cooldown time.Duration `json:"-"`
+	// Range is how far from the caster the target may be. Line and cone
+	// abilities are aimed rather than targeted and ignore it.
+	Range   int      `json:"range"`
+	Shape   Shape    `json:"shape"`
+	Size    int      `json:"size"`
+	Effects []Effect `json:"effects"`
+}
+
+var (
+	ErrUnknownAbility   = errors.New("unknown ability")
+	ErrAbilityLocked    = errors.New("ability not learned yet")
+	ErrAbilityCooldown  = errors.New("ability is on cooldown")
+	ErrNotEnoughMana    = errors.New("not enough mana")
+	ErrNoAbilityTarget  = errors.New("ability needs a target")
+	ErrTargetOutOfReach = errors.New("target is out of range or out of sight")
+)
+
+// Hostile reports whether any effect of the ability is meant for enemies.
+func (a *Ability) Hostile() bool {
+	for _, e := range a.Effects {
+		if e.Hostile() {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that the ability is usable as defined.
+func (a *Ability) Validate() error {
+	if a.ID == "" {
+		return errors.New("ability without id")
+	}
+	if a.ManaCost < 0 || a.Cooldown < 0 || a.Range < 0 || a.Size < 0 {
+		return fmt.Errorf("ability %s: costs, cooldown, range and size must not be negative", a.ID)
+	}
+	switch a.Shape {
+	case ShapeSelf, ShapeSingle:
+	case ShapeLine, ShapeCone, ShapeRadius:
+		if a.Size == 0 {
+			return fmt.Errorf("ability %s: %s shape needs a size", a.ID, a.Shape)
+		}
+	default:
+		return fmt.Errorf("ability %s: unknown shape %q", a.ID, a.Shape)
+	}
+	if a.Shape == ShapeSingle && a.Range == 0 {
+		return fmt.Errorf("ability %s: single target ability needs a range", a.ID)
+	}
+	if len(a.Effects) == 0 {
+		return fmt.Errorf("ability %s has no effects", a.ID)
+	}
+	for _, e := range a.Effects {
+		switch e.Kind {
+		case EffectDamage, EffectHeal, EffectKnockback:
+			if e.Amount <= 0 {
+				return fmt.Errorf("ability %s: %s effect needs a positive amount", a.ID, e.Kind)
+			}
+		case EffectStatus:
+			if !e.Status.valid() || e.Ticks <= 0 {
+				return fmt.Errorf("ability %s: status effect needs a known status and ticks", a.ID)
+			}
+		default:
+			return fmt.Errorf("ability %s: unknown effect %q", a.ID, e.Kind)
+		}
+	}
+	return nil
+}
+
+// Targeted reports whether the ability is cast on a target rather than
+// around the caster or in a direction.
+func (a *Ability) Targeted() bool {
+	return a.Shape == ShapeSingle || (a.Shape == ShapeRadius && a.Range > 0)
+}
+
+// Aimed reports whether the ability is cast in a direction.
+func (a *Ability) Aimed() bool {
+	return a.Shape == ShapeLine || a.Shape == ShapeCone
+}
+
+// Area returns the cells the ability affects when cast by caster on center,
+// or in direction dir for aimed abilities. Only cells in sight of the
+// caster, or of the center for radius abilities, are affected.
+func (w *World) Area(a *Ability, caster, center Point, dir string) []Point {
+	switch a.Shape {
+	case ShapeSelf:
+		return []Point{caster}
+	case ShapeSingle:
+		return []Point{center}
+	case ShapeLine:
+		var cells []Point
+		p := caster
+		for i := 0; i < a.Size; i++ {
+			next, ok := Step(p, dir)
+			if !ok || !w.InBounds(next.X, next.Y) || w.TileAt(next.X, next.Y).Opaque {
+				break
+			}
+			cells = append(cells, next)
+			p = next
+		}
+		return cells
+	case ShapeCone:
+		d, ok := deltas[dir]
+		if !ok {
+			return nil
+		}
+		fov := w.ComputeFOV(caster, a.Size)
+		var cells []Point
+		for y := caster.Y - a.Size; y <= caster.Y+a.Size; y++ {
+			for x := caster.X - a.Size; x <= caster.X+a.Size; x++ {
+				ox, oy := x-caster.X, y-caster.Y
+				dot := ox*d.dx + oy*d.dy
+				// Within 45 degrees of the direction: cos² >= 1/2.
+				if (ox == 0 && oy == 0) || dot <= 0 ||
+					2*dot*dot < (ox*ox+oy*oy)*(d.dx*d.dx+d.dy*d.dy) ||
+					!fov.Visible(x, y) {
+					continue
+				}
+				cells = append(cells, Point{X: x, Y: y})
+			}
+		}
+		return cells
+	case ShapeRadius:
+		origin := caster
+		if a.Range > 0 {
+			origin = center
+		}
+		fov := w.ComputeFOV(origin, a.Size)
+		var cells []Point
+		for y := origin.Y - a.Size; y <= origin.Y+a.Size; y++ {
+			for x := origin.X - a.Size; x <= origin.X+a.Size; x++ {
+				if fov.Visible(x, y) {
+					cells = append(cells, Point{X: x, Y: y})
+				}
+			}
+		}
+		return cells
+	}
+	return nil
+}
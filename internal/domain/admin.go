@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownRole = errors.New("unknown role")
+
+// Role is the rank of an account. Each role may do everything the roles
+// below it may.
+type Role string
+
+const (
+	RolePlayer    Role = "player"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RolePlayer:    0,
+	RoleModerator: 1,
+	RoleAdmin:     2,
+}
+
+// ParseRole checks that s names a role.
+func ParseRole(s string) (Role, error) {
+	r := Role(s)
+	if _, ok := roleRanks[r]; !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownRole, s)
+	}
+	return r, nil
+}
+
+// Allows reports whether the role ranks at least as high as needed. An
+// empty role is a player.
+func (r Role) Allows(needed Role) bool {
+	return roleRanks[r] >= roleRanks[needed]
+}
+
+// KnownMobType reports whether mobs of mobType have a brain of their own,
+// which is the case of the built-in archetypes.
+func KnownMobType(mobType string) bool {
+	_, ok := brains[mobType]
+	return ok
+}
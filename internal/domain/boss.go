@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Arena is the region of a boss encounter: a rectangle, bounds included,
+// whose doors lock while the fight is on. The boss spawns on Spawn.
+type Arena struct {
+	ID    string `json:"id"`
+	Boss  string `json:"boss"`
+	MinX  int    `json:"minX"`
+	MinY  int    `json:"minY"`
+	MaxX  int    `json:"maxX"`
+	MaxY  int    `json:"maxY"`
+	Spawn Point  `json:"spawn"`
+}
+
+// Contains reports whether (x, y) is inside the arena.
+func (a Arena) Contains(x, y int) bool {
+	return x >= a.MinX && x <= a.MaxX && y >= a.MinY && y <= a.MaxY
+}
+
+// Doors returns the door cells on the edge of the arena, which are the ways
+// in and out of it.
+func (a Arena) Doors(w *World) []Point {
+	var doors []Point
+	for y := a.MinY; y <= a.MaxY; y++ {
+		for x := a.MinX; x <= a.MaxX; x++ {
+			edge := x == a.MinX || x == a.MaxX || y == a.MinY || y == a.MaxY
+			if edge && w.InBounds(x, y) && w.TileAt(x, y).Door {
+				doors = append(doors, Point{X: x, Y: y})
+			}
+		}
+	}
+	return doors
+}
+
+// BossAdd is a group of mobs a boss calls into the fight.
+type BossAdd struct {
+	MobType string `json:"mobType"`
+	Count   int    `json:"count"`
+}
+
+// BossPhase is a stage of a boss fight. It starts once the boss's health
+// drops below the Below fraction of its maximum, spawning its adds and
+// announcing itself. Every CastEvery ticks the boss casts the next of the
+// phase's abilities in turn.
+type BossPhase struct {
+	Name      string    `json:"name"`
+	Below     float64   `json:"below"`
+	Announce  string    `json:"announce,omitempty"`
+	CastEvery int       `json:"castEvery,omitempty"`
+	Abilities []string  `json:"abilities,omitempty"`
+	Adds      []BossAdd `json:"adds,omitempty"`
+}
+
+// Boss is a boss mob archetype. Bosses are data: they are loaded from a
+// file and spawned in the arenas naming their Type.
+type Boss struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Symbol  rune   `json:"-"`
+	Health  int    `json:"health"`
+	Attack  int    `json:"attack"`
+	Defense int    `json:"defense"`
+	// RespawnTicks is how long the boss stays dead once defeated.
+	RespawnTicks int         `json:"respawnTicks"`
+	Phases       []BossPhase `json:"phases"`
+	// Abilities are the abilities its phases cast, by ID.
+	Abilities map[string]*Ability `json:"-"`
+}
+
+var ErrUnknownBoss = errors.New("unknown boss")
+
+// Validate checks that the boss is usable as defined, with every ability
+// its phases cast in Abilities.
+func (b *Boss) Validate() error {
+	if b.Type == "" {
+		return errors.New("boss without type")
+	}
+	if b.Health <= 0 || b.Attack < 0 || b.Defense < 0 || b.RespawnTicks < 0 {
+		return fmt.Errorf("boss %s: health must be positive and stats not negative", b.Type)
+	}
+	if len(b.Phases) == 0 {
+		return fmt.Errorf("boss %s has no phases", b.Type)
+	}
+	for i, ph := range b.Phases {
+		if ph.Below <= 0 || ph.Below > 1 {
+			return fmt.Errorf("boss %s: phase %s: below must be in (0, 1]", b.Type, ph.Name)
+		}
+		if i > 0 && ph.Below >= b.Phases[i-1].Below {
+			return fmt.Errorf("boss %s: phase %s must start below the previous one", b.Type, ph.Name)
+		}
+		if ph.CastEvery < 0 || (len(ph.Abilities) > 0 && ph.CastEvery == 0) {
+			return fmt.Errorf("boss %s: phase %s: abilities need a positive castEvery", b.Type, ph.Name)
+		}
+		for _, id := range ph.Abilities {
+			if b.Abilities[id] == nil {
+				return fmt.Errorf("boss %s: phase %s: %w %q", b.Type, ph.Name, ErrUnknownAbility, id)
+			}
+		}
+		for _, add := range ph.Adds {
+			if add.MobType == "" || add.Count <= 0 {
+				return fmt.Errorf("boss %s: phase %s: adds need a mob type and a count", b.Type, ph.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// PhaseFor returns the index of the phase the boss is in with the given
+// health: the last one whose threshold is above it.
+func (b *Boss) PhaseFor(health, maxHealth int) int {
+	phase := 0
+	for i, ph := range b.Phases {
+		if float64(health) < ph.Below*float64(maxHealth) {
+			phase = i
+		}
+	}
+	return phase
+}
+
+// SpawnBoss creates the boss of arena a on its spawn cell.
+func (w *World) SpawnBoss(a Arena, b *Boss, mobID string) *Mob {
+	mob := w.newMob(b.Type, b.Name, mobID, a.Spawn.X, a.Spawn.Y)
+	mob.Health, mob.MaxHealth = b.Health, b.Health
+	mob.Attack, mob.Defense = b.Attack, b.Defense
+	if b.Symbol != 0 {
+		mob.Symbol = b.Symbol
+	}
+	mob.Zone = a.ID
+	return mob
+}
+
+// Brain returns the brain of a boss guarding arena a: it never wanders or
+// flees, notices anyone entering the arena and does not leave it.
+func (b *Boss) Brain(a Arena) Brain {
+	size := max(a.MaxX-a.MinX, a.MaxY-a.MinY)
+	return &StateMachine{Temperament{
+		AggroRadius: size, LeashRadius: size, KeepDistance: 1,
+	}}
+}
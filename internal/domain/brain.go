@@ -0,0 +1,255 @@
+package domain
+
+import (
+	"sort"
+)
+
+// MobState is what a mob is busy with.
+type MobState string
+
+const (
+	// MobIdle stands still, and MobWander roams around home.
+	MobIdle   MobState = "idle"
+	MobWander MobState = "wander"
+	// MobChase hunts the mob's target and MobFlee runs away from it.
+	MobChase MobState = "chase"
+	MobFlee  MobState = "flee"
+	// MobReturn walks back home after straying too far, regenerating on
+	// the way and ignoring players until it gets there.
+	MobReturn MobState = "return"
+)
+
+// pathBudget bounds the cells a mob explores when looking for a path.
+const pathBudget = 400
+
+// Senses is what a mob perceives when it thinks.
+type Senses struct {
+	World *World
+	// Players are the living players in the world and Mobs every mob in
+	// it, the thinking one included, both ordered by ID.
+	Players []*Player
+	Mobs    []*Mob
+
+	occupied map[Point]bool
+}
+
+// NewSenses gathers what the mobs of world perceive. It sorts players and
+// mobs by ID so that brains behave the same on every run.
+func NewSenses(world *World, players []*Player, mobs []*Mob) Senses {
+	s := Senses{World: world, occupied: make(map[Point]bool)}
+	for _, p := range players {
+		if p.IsAlive() && p.WorldID == world.ID {
+			s.Players = append(s.Players, p)
+			s.occupied[Point{X: p.X, Y: p.Y}] = true
+		}
+	}
+	for _, m := range mobs {
+		s.Mobs = append(s.Mobs, m)
+		s.occupied[Point{X: m.X, Y: m.Y}] = true
+	}
+	sort.Slice(s.Players, func(i, j int) bool { return s.Players[i].ID < s.Players[j].ID })
+	sort.Slice(s.Mobs, func(i, j int) bool { return s.Mobs[i].ID < s.Mobs[j].ID })
+	return s
+}
+
+func (s Senses) player(id string) *Player {
+	for _, p := range s.Players {
+		if p.ID == id {
+			return p
+		}
+	}
+	return nil
+}
+
+// free reports whether a mob may step on the cell.
+func (s Senses) free(m *Mob, c Point) bool {
+	return m.canMove(c.X, c.Y, s.World) && !s.occupied[c]
+}
+
+// Brain decides what a mob does on each tick.
+type Brain interface {
+	// Think updates the mob's state and returns the direction it steps in,
+	// or "" to stay put. Any randomness must come from rng.
+	Think(m *Mob, s Senses, rng RNG) string
+}
+
+// Temperament tunes the state machine of a mob archetype.
+type Temperament struct {
+	// AggroRadius is how close a player in sight must come to be chased.
+	AggroRadius int
+	// LeashRadius is how far from home the mob strays before giving up and
+	// returning.
+	LeashRadius int
+	// WanderRadius bounds the roaming around home, and WanderChance is the
+	// probability of taking a step on a quiet tick.
+	WanderRadius int
+	WanderChance float64
+	// FleeBelow is the fraction of its health under which the mob runs
+	// away from its target.
+	FleeBelow float64
+	// PackRadius is how far a mob calls allies of its type to join its
+	// chase; 0 hunts alone.
+	PackRadius int
+	// KeepDistance is how close a mob gets to a target in sight before it
+	// stops: 1 for melee, more for ranged mobs.
+	KeepDistance int
+	// RegenPerTick is the health regained on every tick spent returning.
+	RegenPerTick int
+}
+
+// StateMachine is the brain of every built-in archetype: it idles and
+// wanders around home, chases players coming into aggro range, flees when
+// badly hurt and returns home once it strays past its leash.
+type StateMachine struct {
+	Temperament
+}
+
+var defaultBrain Brain = &StateMachine{Temperament{
+	AggroRadius: 5, LeashRadius: 10, WanderRadius: 4, WanderChance: 0.5,
+	FleeBelow: 0.2, KeepDistance: 1, RegenPerTick: 5,
+}}
+
+var brains = map[string]Brain{
+	"Goblin": &StateMachine{Temperament{
+		AggroRadius: 6, LeashRadius: 12, WanderRadius: 4, WanderChance: 0.5,
+		FleeBelow: 0.25, PackRadius: 6, KeepDistance: 1, RegenPerTick: 5,
+	}},
+	"Archer": &StateMachine{Temperament{
+		AggroRadius: 7, LeashRadius: 10, WanderRadius: 2, WanderChance: 0.3,
+		FleeBelow: 0.3, KeepDistance: 5, RegenPerTick: 5,
+	}},
+	"Shaman": &StateMachine{Temperament{
+		AggroRadius: 6, LeashRadius: 8, WanderRadius: 3, WanderChance: 0.3,
+		PackRadius: 6, KeepDistance: 4, RegenPerTick: 8,
+	}},
+}
+
+// BrainFor returns the brain of mobs of mobType.
+func BrainFor(mobType string) Brain {
+	if b, ok := brains[mobType]; ok {
+		return b
+	}
+	return defaultBrain
+}
+
+func (sm *StateMachine) Think(m *Mob, s Senses, rng RNG) string {
+	here := Point{X: m.X, Y: m.Y}
+
+	if m.State == MobReturn {
+		m.Health = min(m.MaxHealth, m.Health+sm.RegenPerTick)
+		if here == m.Home || (chebyshev(here, m.Home) == 1 && s.occupied[m.Home]) {
+			m.State = MobIdle
+			return ""
+		}
+		dir := sm.stepTowards(m, s, m.Home)
+		if dir == "" && FindPath(s.World, here, m.Home, pathBudget, func(Tile, int, int) bool { return true }) == nil {
+			// Home is out of reach: settle down where the mob is.
+			m.Home, m.State = here, MobIdle
+		}
+		return dir
+	}
+	if chebyshev(here, m.Home) > sm.LeashRadius {
+		m.State, m.TargetID = MobReturn, ""
+		return sm.stepTowards(m, s, m.Home)
+	}
+
+	target := s.player(m.TargetID)
+	if target == nil {
+		target = sm.spot(m, s)
+	}
+	if target == nil {
+		m.TargetID = ""
+		return sm.wander(m, s, rng)
+	}
+	m.TargetID = target.ID
+	if m.State != MobChase && m.State != MobFlee {
+		sm.alertPack(m, s, target.ID)
+	}
+
+	to := Point{X: target.X, Y: target.Y}
+	if float64(m.Health) < sm.FleeBelow*float64(m.MaxHealth) {
+		m.State = MobFlee
+		return sm.stepAway(m, s, to)
+	}
+	m.State = MobChase
+	if chebyshev(here, to) <= max(1, sm.KeepDistance) && s.World.CanSee(here, to) {
+		return ""
+	}
+	return sm.stepTowards(m, s, to)
+}
+
+// spot returns the nearest player in sight within aggro range.
+func (sm *StateMachine) spot(m *Mob, s Senses) *Player {
+	here := Point{X: m.X, Y: m.Y}
+	var nearest *Player
+	minDist := sm.AggroRadius + 1
+	for _, p := range s.Players {
+		to := Point{X: p.X, Y: p.Y}
+		if d := chebyshev(here, to); d < minDist && s.World.CanSee(here, to) {
+			minDist, nearest = d, p
+		}
+	}
+	return nearest
+}
+
+// alertPack makes the idle allies of the mob's type around it chase target
+// too.
+func (sm *StateMachine) alertPack(m *Mob, s Senses, targetID string) {
+	if sm.PackRadius == 0 {
+		return
+	}
+	for _, ally := range s.Mobs {
+		if ally == m || ally.Type != m.Type || ally.TargetID != "" || ally.State == MobReturn {
+			continue
+		}
+		if chebyshev(Point{X: m.X, Y: m.Y}, Point{X: ally.X, Y: ally.Y}) <= sm.PackRadius {
+			ally.TargetID = targetID
+		}
+	}
+}
+
+func (sm *StateMachine) wander(m *Mob, s Senses, rng RNG) string {
+	if rng.Float64() >= sm.WanderChance {
+		m.State = MobIdle
+		return ""
+	}
+	m.State = MobWander
+	here := Point{X: m.X, Y: m.Y}
+	dir := Directions[rng.Intn(len(Directions))]
+	next, _ := Step(here, dir)
+	if chebyshev(next, m.Home) > sm.WanderRadius && here != m.Home {
+		dir = Heading(here, m.Home)
+		next, _ = Step(here, dir)
+	}
+	if !s.free(m, next) {
+		return ""
+	}
+	return dir
+}
+
+// stepTowards returns the first step of the mob's path to `to`, or "" when
+// there is none or the next cell is taken.
+func (sm *StateMachine) stepTowards(m *Mob, s Senses, to Point) string {
+	here := Point{X: m.X, Y: m.Y}
+	path := FindPath(s.World, here, to, pathBudget, func(t Tile, x, y int) bool {
+		return s.free(m, Point{X: x, Y: y})
+	})
+	if len(path) == 0 || !s.free(m, path[0]) {
+		return ""
+	}
+	return DirectionTo(here, path[0])
+}
+
+// stepAway returns the step taking the mob farthest from `from`, or "" when
+// it is cornered.
+func (sm *StateMachine) stepAway(m *Mob, s Senses, from Point) string {
+	here := Point{X: m.X, Y: m.Y}
+	best, bestDist := "", chebyshev(here, from)
+	for _, dir := range Directions {
+		next, _ := Step(here, dir)
+		if d := chebyshev(next, from); d > bestDist && s.free(m, next) {
+			best, bestDist = dir, d
+		}
+	}
+	return best
+}
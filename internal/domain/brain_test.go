@@ -0,0 +1,156 @@
+package domain_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+// fixedRNG always draws the same numbers: n modulo the bound for Intn.
+type fixedRNG struct {
+	f float64
+	n int
+}
+
+func (r fixedRNG) Intn(bound int) int { return r.n % bound }
+func (r fixedRNG) Float64() float64   { return r.f }
+
+// room returns an open floor of 24 by 11 cells walled in.
+func room() *domain.World {
+	const width, height = 24, 11
+	rows := make([]string, height)
+	for y := range rows {
+		if y == 0 || y == height-1 {
+			rows[y] = strings.Repeat("#", width)
+		} else {
+			rows[y] = "#" + strings.Repeat(" ", width-2) + "#"
+		}
+	}
+	return domain.NewWorld("room", width, height, domain.ConvertLayout(strings.Join(rows, "\n")))
+}
+
+var temperament = domain.Temperament{
+	AggroRadius: 5, LeashRadius: 8, WanderRadius: 3, WanderChance: 0.5,
+	FleeBelow: 0.25, KeepDistance: 1, RegenPerTick: 5,
+}
+
+func TestStateMachineTransitions(t *testing.T) {
+	home := domain.Point{X: 5, Y: 5}
+	quiet := fixedRNG{f: 0.9}
+	restless := fixedRNG{f: 0.1, n: 2} // Directions[2] is east
+
+	tests := []struct {
+		name   string
+		at     domain.Point
+		state  domain.MobState
+		health int
+		player *domain.Point
+		rng    domain.RNG
+
+		wantState  domain.MobState
+		wantDir    string
+		wantTarget bool
+		wantHealth int
+	}{
+		{name: "idle without players", at: home, state: domain.MobIdle, health: 100, rng: quiet,
+			wantState: domain.MobIdle, wantHealth: 100},
+		{name: "wander on a restless tick", at: home, state: domain.MobIdle, health: 100, rng: restless,
+			wantState: domain.MobWander, wantDir: "E", wantHealth: 100},
+		{name: "wander back inside the wander radius", at: domain.Point{X: 8, Y: 5}, state: domain.MobWander, health: 100, rng: restless,
+			wantState: domain.MobWander, wantDir: "W", wantHealth: 100},
+		{name: "ignore players out of aggro range", at: home, state: domain.MobIdle, health: 100, player: &domain.Point{X: 11, Y: 5}, rng: quiet,
+			wantState: domain.MobIdle, wantHealth: 100},
+		{name: "chase a player in aggro range", at: home, state: domain.MobIdle, health: 100, player: &domain.Point{X: 8, Y: 5}, rng: quiet,
+			wantState: domain.MobChase, wantDir: "E", wantTarget: true, wantHealth: 100},
+		{name: "stop next to the target", at: home, state: domain.MobChase, health: 100, player: &domain.Point{X: 6, Y: 5}, rng: quiet,
+			wantState: domain.MobChase, wantTarget: true, wantHealth: 100},
+		{name: "flee when badly hurt", at: home, state: domain.MobChase, health: 20, player: &domain.Point{X: 7, Y: 5}, rng: quiet,
+			wantState: domain.MobFlee, wantDir: "W", wantTarget: true, wantHealth: 20},
+		{name: "leash past the leash radius", at: domain.Point{X: 14, Y: 5}, state: domain.MobChase, health: 100, player: &domain.Point{X: 15, Y: 5}, rng: quiet,
+			wantState: domain.MobReturn, wantDir: "W", wantHealth: 100},
+		{name: "flee past the leash radius returns home", at: domain.Point{X: 14, Y: 5}, state: domain.MobFlee, health: 20, player: &domain.Point{X: 12, Y: 5}, rng: quiet,
+			wantState: domain.MobReturn, wantDir: "W", wantHealth: 20},
+		{name: "return ignores players and regenerates", at: domain.Point{X: 10, Y: 5}, state: domain.MobReturn, health: 50, player: &domain.Point{X: 11, Y: 5}, rng: quiet,
+			wantState: domain.MobReturn, wantDir: "W", wantHealth: 55},
+		{name: "return ends at home", at: home, state: domain.MobReturn, health: 98, rng: quiet,
+			wantState: domain.MobIdle, wantHealth: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			world := room()
+			m := &domain.Mob{ID: "mob-1", WorldID: world.ID, Type: "Goblin", X: tt.at.X, Y: tt.at.Y,
+				Health: tt.health, MaxHealth: 100, State: tt.state, Home: home}
+			var players []*domain.Player
+			if tt.player != nil {
+				players = append(players, domain.NewPlayer("player-1", tt.player.X, tt.player.Y))
+				players[0].WorldID = world.ID
+				if tt.state == domain.MobChase || tt.state == domain.MobFlee {
+					m.TargetID = "player-1"
+				}
+			}
+			sm := &domain.StateMachine{Temperament: temperament}
+
+			dir := sm.Think(m, domain.NewSenses(world, players, []*domain.Mob{m}), tt.rng)
+			if dir != tt.wantDir {
+				t.Errorf("direction = %q, want %q", dir, tt.wantDir)
+			}
+			if m.State != tt.wantState {
+				t.Errorf("state = %q, want %q", m.State, tt.wantState)
+			}
+			if got := m.TargetID != ""; got != tt.wantTarget {
+				t.Errorf("target = %q, want one: %v", m.TargetID, tt.wantTarget)
+			}
+			if m.Health != tt.wantHealth {
+				t.Errorf("health = %d, want %d", m.Health, tt.wantHealth)
+			}
+		})
+	}
+}
+
+// TestFleeingMobReturnsHome follows a hurt mob running from a player until it
+// strays past its leash, walks back home and settles down there.
+func TestFleeingMobReturnsHome(t *testing.T) {
+	world := room()
+	home := domain.Point{X: 5, Y: 5}
+	m := &domain.Mob{ID: "mob-1", WorldID: world.ID, Type: "Goblin", X: home.X, Y: home.Y,
+		Health: 20, MaxHealth: 100, State: domain.MobIdle, Home: home}
+	player := domain.NewPlayer("player-1", 3, 5)
+	player.WorldID = world.ID
+	sm := &domain.StateMachine{Temperament: temperament}
+
+	var states []domain.MobState
+	for tick := 0; tick < 50; tick++ {
+		// The player gives up once the mob starts heading home.
+		var players []*domain.Player
+		if m.State != domain.MobReturn {
+			players = []*domain.Player{player}
+		}
+		dir := sm.Think(m, domain.NewSenses(world, players, []*domain.Mob{m}), fixedRNG{f: 0.9})
+		if len(states) == 0 || states[len(states)-1] != m.State {
+			states = append(states, m.State)
+		}
+		if dir != "" {
+			next, ok := domain.Step(domain.Point{X: m.X, Y: m.Y}, dir)
+			if !ok {
+				t.Fatalf("tick %d: bad direction %q", tick, dir)
+			}
+			m.X, m.Y = next.X, next.Y
+		}
+		if m.State == domain.MobIdle && len(states) > 1 {
+			break
+		}
+	}
+
+	want := []domain.MobState{domain.MobFlee, domain.MobReturn, domain.MobIdle}
+	if !slices.Equal(states, want) {
+		t.Fatalf("states = %v, want %v", states, want)
+	}
+	if (domain.Point{X: m.X, Y: m.Y}) != home {
+		t.Errorf("mob settled at %d,%d, want home %d,%d", m.X, m.Y, home.X, home.Y)
+	}
+	if m.Health <= 20 {
+		t.Errorf("health = %d, want regenerated above 20", m.Health)
+	}
+}
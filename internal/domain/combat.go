@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// HitChance is the probability that an attack lands, and CritChance
+	// the probability that it lands as a critical hit.
+	HitChance      = 0.85
+	CritChance     = 0.10
+	CritMultiplier = 2
+
+	DefaultAttackCooldown = 800 * time.Millisecond
+)
+
+var ErrAttackCooldown = errors.New("attack is on cooldown")
+
+// AttackRoll is the outcome of the dice thrown for an attack.
+type AttackRoll int
+
+const (
+	AttackMiss AttackRoll = iota
+	AttackHit
+	AttackCrit
+)
+
+// RollAttack throws the dice for one attack.
+func RollAttack(rng RNG) AttackRoll {
+	r := rng.Float64()
+	switch {
+	case r >= HitChance:
+		return AttackMiss
+	case r < CritChance:
+		return AttackCrit
+	default:
+		return AttackHit
+	}
+}
+
+// Damage is the raw damage of an attack with the given base damage, before
+// the target's defense.
+func (r AttackRoll) Damage(base int) int {
+	switch r {
+	case AttackMiss:
+		return 0
+	case AttackCrit:
+		return base * CritMultiplier
+	default:
+		return base
+	}
+}
+
+func (r AttackRoll) String() string {
+	switch r {
+	case AttackMiss:
+		return "miss"
+	case AttackCrit:
+		return "crit"
+	default:
+		return "hit"
+	}
+}
+
+// CanSee reports whether to is in line of sight from from. It agrees with
+// ComputeFOV, so a player can attack exactly the mobs they are shown.
+func (w *World) CanSee(from, to Point) bool {
+	dx, dy := from.X-to.X, from.Y-to.Y
+	radius := chebyshev(from, to)
+	for radius*radius < dx*dx+dy*dy {
+		radius++
+	}
+	return w.ComputeFOV(from, radius).Visible(to.X, to.Y)
+}
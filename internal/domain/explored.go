@@ -0,0 +1,54 @@
+package domain
+
+// Explored remembers, per world, the cells a player has ever seen as a
+// bitset over the world layout.
+type Explored map[string][]byte
+
+// Reveal marks every cell of fov as explored in world and reports whether
+// any of them was new.
+func (e Explored) Reveal(world *World, fov *FOV) bool {
+	size := (world.Width*world.Height + 7) / 8
+	bits := e[world.ID]
+	if len(bits) != size {
+		bits = make([]byte, size)
+		e[world.ID] = bits
+	}
+	changed := false
+	for y := fov.Y; y < fov.Y+fov.Height; y++ {
+		for x := fov.X; x < fov.X+fov.Width; x++ {
+			if !fov.Visible(x, y) {
+				continue
+			}
+			i := y*world.Width + x
+			if bits[i/8]&(1<<(i%8)) == 0 {
+				bits[i/8] |= 1 << (i % 8)
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+func (e Explored) Has(world *World, x, y int) bool {
+	bits := e[world.ID]
+	if !world.InBounds(x, y) {
+		return false
+	}
+	i := y*world.Width + x
+	return i/8 < len(bits) && bits[i/8]&(1<<(i%8)) != 0
+}
+
+// MaskLayout returns a copy of the world layout where the cells the player
+// has not explored yet are zero.
+func (e Explored) MaskLayout(world *World) Layout {
+	masked := make(Layout, len(world.Layout))
+	for y, row := range world.Layout {
+		masked[y] = make([]byte, len(row))
+		for x := range row {
+			if e.Has(world, x, y) {
+				masked[y][x] = world.Glyph(x, y)
+			}
+		}
+	}
+	return masked
+}
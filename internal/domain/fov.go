@@ -0,0 +1,157 @@
+package domain
+
+const DefaultSightRadius = 10
+
+// FOV is the set of cells visible from a point. Only the window of the world
+// within sight radius is stored, as a bitset in row-major order, so its size
+// does not grow with the world.
+type FOV struct {
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Bits   []byte `json:"bits"`
+}
+
+func newFOV(w *World, origin Point, radius int) *FOV {
+	x0, y0 := max(0, origin.X-radius), max(0, origin.Y-radius)
+	x1, y1 := min(w.Width-1, origin.X+radius), min(w.Height-1, origin.Y+radius)
+	width, height := max(0, x1-x0+1), max(0, y1-y0+1)
+	return &FOV{X: x0, Y: y0, Width: width, Height: height, Bits: make([]byte, (width*height+7)/8)}
+}
+
+func (f *FOV) index(x, y int) (int, bool) {
+	x, y = x-f.X, y-f.Y
+	if x < 0 || y < 0 || x >= f.Width || y >= f.Height {
+		return 0, false
+	}
+	return y*f.Width + x, true
+}
+
+func (f *FOV) Visible(x, y int) bool {
+	i, ok := f.index(x, y)
+	return ok && f.Bits[i/8]&(1<<(i%8)) != 0
+}
+
+func (f *FOV) set(x, y int) {
+	if i, ok := f.index(x, y); ok {
+		f.Bits[i/8] |= 1 << (i % 8)
+	}
+}
+
+// ComputeFOV returns the cells visible from origin within radius using
+// symmetric shadowcasting: if A can see B then B can see A. Opaque tiles are
+// visible themselves but hide what lies behind them.
+func (w *World) ComputeFOV(origin Point, radius int) *FOV {
+	fov := newFOV(w, origin, radius)
+	fov.set(origin.X, origin.Y)
+
+	for q := 0; q < 4; q++ {
+		s := shadowcaster{world: w, fov: fov, origin: origin, quadrant: q, radius: radius}
+		s.scan(shadowRow{depth: 1, start: fraction{-1, 1}, end: fraction{1, 1}})
+	}
+	return fov
+}
+
+// fraction is an exact slope, avoiding the float rounding that would break
+// the symmetry of the algorithm.
+type fraction struct {
+	num, den int
+}
+
+type shadowRow struct {
+	depth      int
+	start, end fraction
+}
+
+// minCol rounds depth*start half up and maxCol rounds depth*end half down.
+func (r shadowRow) minCol() int {
+	return floorDiv(2*r.depth*r.start.num+r.start.den, 2*r.start.den)
+}
+
+func (r shadowRow) maxCol() int {
+	return -floorDiv(-(2*r.depth*r.end.num - r.end.den), 2*r.end.den)
+}
+
+func (r shadowRow) next() shadowRow {
+	return shadowRow{depth: r.depth + 1, start: r.start, end: r.end}
+}
+
+// symmetric reports whether the center of the tile in column col lies within
+// the row's sector.
+func (r shadowRow) symmetric(col int) bool {
+	return col*r.start.den >= r.depth*r.start.num && col*r.end.den <= r.depth*r.end.num
+}
+
+func tileSlope(depth, col int) fraction {
+	return fraction{2*col - 1, 2 * depth}
+}
+
+type shadowcaster struct {
+	world    *World
+	fov      *FOV
+	origin   Point
+	quadrant int
+	radius   int
+}
+
+// transform maps a (depth, col) position in the current quadrant to world
+// coordinates. Quadrants are north, east, south and west.
+func (s *shadowcaster) transform(depth, col int) (int, int) {
+	switch s.quadrant {
+	case 0:
+		return s.origin.X + col, s.origin.Y - depth
+	case 1:
+		return s.origin.X + depth, s.origin.Y + col
+	case 2:
+		return s.origin.X + col, s.origin.Y + depth
+	default:
+		return s.origin.X - depth, s.origin.Y + col
+	}
+}
+
+func (s *shadowcaster) opaque(depth, col int) bool {
+	x, y := s.transform(depth, col)
+	return s.world.TileAt(x, y).Opaque
+}
+
+func (s *shadowcaster) reveal(depth, col int) {
+	if depth*depth+col*col > s.radius*s.radius {
+		return
+	}
+	s.fov.set(s.transform(depth, col))
+}
+
+func (s *shadowcaster) scan(row shadowRow) {
+	if row.depth > s.radius {
+		return
+	}
+
+	hasPrev, prevOpaque := false, false
+	for col := row.minCol(); col <= row.maxCol(); col++ {
+		opaque := s.opaque(row.depth, col)
+		if opaque || row.symmetric(col) {
+			s.reveal(row.depth, col)
+		}
+		if hasPrev && prevOpaque && !opaque {
+			row.start = tileSlope(row.depth, col)
+		}
+		if hasPrev && !prevOpaque && opaque {
+			next := row.next()
+			next.end = tileSlope(row.depth, col)
+			s.scan(next)
+		}
+		hasPrev, prevOpaque = true, opaque
+	}
+	if hasPrev && !prevOpaque {
+		s.scan(row.next())
+	}
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrBadPlayerName = errors.New("names are 3 to 16 letters, digits, - or _")
+	ErrBadSecret     = errors.New("the secret must be 1 to 128 bytes long")
+	ErrWrongSecret   = errors.New("this name belongs to someone else")
+)
+
+// ValidatePlayerName checks that name can be the ID of a player: it is what
+// others type to whisper, party or trade with them.
+func ValidatePlayerName(name string) error {
+	if len(name) < 3 || len(name) > 16 {
+		return ErrBadPlayerName
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return fmt.Errorf("%q: %w", r, ErrBadPlayerName)
+		}
+	}
+	return nil
+}
+
+// HashSecret returns the SHA-256 of a secret, as kept in SecretHash.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Authenticate checks secret against the one the player first joined with.
+// A player without one, saved before secrets were kept, takes secret as
+// theirs.
+func (p *Player) Authenticate(secret string) error {
+	if secret == "" || len(secret) > 128 {
+		return ErrBadSecret
+	}
+	hash := HashSecret(secret)
+	if p.SecretHash == "" {
+		p.SecretHash = hash
+		return nil
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(p.SecretHash)) != 1 {
+		return ErrWrongSecret
+	}
+	return nil
+}
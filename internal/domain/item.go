@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotEnoughGold = errors.New("not enough gold")
+	ErrItemNotOwned  = errors.New("you do not have that item")
+)
+
+// DefaultPlayerGold is the gold new players start with.
+const DefaultPlayerGold = 20
+
+// Item is something players carry, buy and sell. Items are data: they are
+// loaded from a file and referenced by ID.
+type Item struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Price is what shops sell the item for.
+	Price int `json:"price"`
+}
+
+// Validate checks that the item is usable as defined.
+func (i *Item) Validate() error {
+	if i.ID == "" {
+		return errors.New("item without id")
+	}
+	if i.Name == "" {
+		return fmt.Errorf("item %s has no name", i.ID)
+	}
+	if i.Price <= 0 {
+		return fmt.Errorf("item %s: price must be positive", i.ID)
+	}
+	return nil
+}
+
+// Buy pays price for one item and adds it to the player's inventory.
+func (p *Player) Buy(item *Item, price int) error {
+	if p.Gold < price {
+		return ErrNotEnoughGold
+	}
+	p.Gold -= price
+	if p.Inventory == nil {
+		p.Inventory = make(map[string]int)
+	}
+	p.Inventory[item.ID]++
+	return nil
+}
+
+// Sell removes one item from the player's inventory for price.
+func (p *Player) Sell(item *Item, price int) error {
+	if p.Inventory[item.ID] <= 0 {
+		return ErrItemNotOwned
+	}
+	p.Inventory[item.ID]--
+	if p.Inventory[item.ID] == 0 {
+		delete(p.Inventory, item.ID)
+	}
+	p.Gold += price
+	return nil
+}
+
+// GoldReward is the gold dropped by the mob when it is killed.
+func (m *Mob) GoldReward() int {
+	return (m.Attack + m.Defense) / 3
+}
+
+// mobLoot lists the item dropped by each mob type, if any.
+var mobLoot = map[string]string{
+	"Goblin": "goblin-ear",
+}
+
+// Loot returns the ID of the item the mob drops when it is killed.
+func (m *Mob) Loot() (string, bool) {
+	id, ok := mobLoot[m.Type]
+	return id, ok
+}
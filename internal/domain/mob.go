@@ -1,5 +1,7 @@
 package domain
 
+import "fmt"
+
 type MobStore interface {
 	GetMob(id string) *Mob
 	SaveMob(mob *Mob)
@@ -17,29 +19,30 @@ type Mob struct {
 	Y           int    `json:"y"`
 	Type        string `json:"type"`
 	Health      int    `json:"health"`
+	MaxHealth   int    `json:"maxHealth"`
 	Attack      int    `json:"attack"`
 	Defense     int    `json:"defense"`
 	AttackSpeed int    `json:"attackSpeed"`
 	Symbol      rune   `json:"symbol"`
-}
+	Zone        string `json:"zone,omitempty"`
 
-func (m *Mob) canMove(x, y int, world *World) bool {
-	if y < 0 || y >= len(world.Layout) {
-		return false
-	}
-	if x < 0 || x >= len(world.Layout[y]) {
-		return false
-	}
+	Statuses Statuses `json:"statuses,omitempty"`
 
-	if world.Layout[y][x] == '#' {
-		return false
-	}
+	// State is what the mob's brain is busy with. Home is where it spawned
+	// and returns to, and TargetID the player it is after.
+	State    MobState `json:"state,omitempty"`
+	Home     Point    `json:"-"`
+	TargetID string   `json:"-"`
 
-	if world.Layout[y][x] == '@' {
-		return false
-	}
+	moveDebt int
+	reload   int
+}
 
-	return true
+// canMove keeps mobs on walkable ground and away from hazards and NPCs. Mobs
+// cannot open doors.
+func (m *Mob) canMove(x, y int, world *World) bool {
+	t := world.TileAt(x, y)
+	return t.Walkable && !t.IsHazard() && world.NPCAt(x, y) == nil
 }
 
 var mobDeltas = map[string]struct{ dx, dy int }{
@@ -58,29 +61,151 @@ func (m *Mob) Move(direction string, world *World) error {
 	if !ok {
 		return nil
 	}
+	if m.Stunned() {
+		return nil
+	}
+	if m.moveDebt > 0 {
+		m.moveDebt--
+		return nil
+	}
 	nx, ny := m.X+d.dx, m.Y+d.dy
 	if !m.canMove(nx, ny, world) {
 		return nil
 	}
 	m.X, m.Y = nx, ny
+	m.moveDebt = world.TileAt(nx, ny).MoveCost - 1
+	if m.Statuses.Has(StatusSlow) {
+		m.moveDebt++
+	}
 	return nil
 }
 
+// Provoke makes the mob go after the player who attacked it, unless it is
+// already busy with someone or on its way home.
+func (m *Mob) Provoke(playerID string) {
+	if m.TargetID == "" && m.State != MobReturn {
+		m.TargetID = playerID
+	}
+}
+
+// Engaged reports whether the mob fights the players around it rather than
+// fleeing or heading home.
+func (m *Mob) Engaged() bool {
+	return m.State != MobFlee && m.State != MobReturn
+}
+
+// Stunned reports whether the mob can neither move nor attack.
+func (m *Mob) Stunned() bool {
+	return m.Statuses.Has(StatusStun)
+}
+
+// mobOnHit lists the status effects the attacks of each mob type inflict.
+var mobOnHit = map[string]Status{
+	"Goblin": {Kind: StatusPoison, Ticks: 3, Power: 2},
+}
+
+// OnHit returns the status effect the mob's attacks inflict, if any.
+func (m *Mob) OnHit() (Status, bool) {
+	s, ok := mobOnHit[m.Type]
+	return s, ok
+}
+
+// RangedAttack is how a mob type shoots at players beyond melee reach.
+type RangedAttack struct {
+	Projectile string
+	Range      int
+	// Reload is the number of ticks between two shots.
+	Reload int
+}
+
+// mobRanged lists the mob types that shoot.
+var mobRanged = map[string]RangedAttack{
+	"Archer": {Projectile: "arrow", Range: 7, Reload: 3},
+	"Shaman": {Projectile: "firebolt", Range: 6, Reload: 4},
+}
+
+// Ranged returns the mob's ranged attack, if its type has one.
+func (m *Mob) Ranged() (RangedAttack, bool) {
+	r, ok := mobRanged[m.Type]
+	return r, ok
+}
+
+// Reloaded counts down the mob's reload by one tick and reports whether it
+// is ready to shoot. Shooting is up to the caller, which must then call
+// Shot.
+func (m *Mob) Reloaded() bool {
+	if m.reload > 0 {
+		m.reload--
+	}
+	return m.reload == 0
+}
+
+// Shot starts the mob's reload after it fired its ranged attack.
+func (m *Mob) Shot() {
+	if r, ok := m.Ranged(); ok {
+		m.reload = r.Reload
+	}
+}
+
+// TickStatuses advances the mob's status effects by one tick and returns
+// the damage they dealt.
+func (m *Mob) TickStatuses() int {
+	dot, regen := m.Statuses.Tick()
+	damage := max(0, min(m.Statuses.absorb(dot), m.Health))
+	m.Health -= damage
+	if m.Health > 0 {
+		m.Health = min(m.Health+regen, max(m.Health, m.MaxHealth))
+	}
+	return damage
+}
+
+// Push moves the mob up to distance cells in direction dir, stopping before
+// anything it could not walk onto or an occupied cell. It returns the number
+// of cells moved.
+func (m *Mob) Push(dir string, distance int, world *World, occupied map[string]bool) int {
+	moved := 0
+	for ; moved < distance; moved++ {
+		next, ok := Step(Point{X: m.X, Y: m.Y}, dir)
+		if !ok || !m.canMove(next.X, next.Y, world) || occupied[fmt.Sprintf("%d,%d", next.X, next.Y)] {
+			break
+		}
+		m.X, m.Y = next.X, next.Y
+	}
+	return moved
+}
+
 func (m *Mob) IsAlive() bool {
 	return m.Health > 0
 }
 
-func (m *Mob) TakeDamage(damage int) {
+// TakeDamage reduces the mob's health by damage minus its defense and any
+// shield, and returns the damage actually taken.
+func (m *Mob) TakeDamage(damage int) int {
 	actualDamage := damage - m.Defense
 	if actualDamage < 0 {
 		actualDamage = 0
 	}
+	actualDamage = min(m.Statuses.absorb(actualDamage), m.Health)
 	m.Health -= actualDamage
-	if m.Health < 0 {
-		m.Health = 0
+	return actualDamage
+}
+
+// AttackTarget applies an attack with the given roll to target and returns
+// the damage dealt.
+func (m *Mob) AttackTarget(target *Player, roll AttackRoll) int {
+	if roll == AttackMiss {
+		return 0
 	}
+	return target.TakeDamage(roll.Damage(m.Attack))
+}
+
+// Adjacent reports whether the player is on one of the eight cells around
+// the mob.
+func (m *Mob) Adjacent(p *Player) bool {
+	return m.WorldID == p.WorldID && chebyshev(Point{m.X, m.Y}, Point{p.X, p.Y}) == 1
 }
 
-func (m *Mob) AttackTarget(target *Player) {
-	target.TakeDamage(m.Attack)
+// XPReward is the experience granted for killing the mob.
+func (m *Mob) XPReward() int {
+	return 2 * (m.Attack + m.Defense)
 }
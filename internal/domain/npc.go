@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownNPC  = errors.New("unknown npc")
+	ErrUnknownItem = errors.New("unknown item")
+	ErrNoOneToTalk = errors.New("there is no one to talk to")
+)
+
+// Dialogue actions: ActionShop opens the NPC's shop and ActionQuest gives
+// the player the quest named by the choice.
+const (
+	ActionShop  = "shop"
+	ActionQuest = "quest"
+)
+
+// DialogueChoice is an answer the player can give. It leads to the node
+// Next, or ends the conversation when Next is empty, after running Action,
+// if any.
+type DialogueChoice struct {
+	Text   string `json:"text"`
+	Next   string `json:"next,omitempty"`
+	Action string `json:"action,omitempty"`
+	Quest  string `json:"quest,omitempty"`
+}
+
+// DialogueNode is a line of an NPC followed by the player's choices. A node
+// without choices ends the conversation.
+type DialogueNode struct {
+	Text    string           `json:"text"`
+	Choices []DialogueChoice `json:"choices,omitempty"`
+}
+
+// Shop is what an NPC trades. It sells the items in Sells at their price
+// and buys any item back at BuyRate of it.
+type Shop struct {
+	Sells   []string `json:"sells"`
+	BuyRate float64  `json:"buyRate"`
+}
+
+// SellPrice is what the shop pays for item.
+func (s *Shop) SellPrice(item *Item) int {
+	return max(1, int(float64(item.Price)*s.BuyRate))
+}
+
+// Stocks reports whether the shop sells the item itemID.
+func (s *Shop) Stocks(itemID string) bool {
+	for _, id := range s.Sells {
+		if id == itemID {
+			return true
+		}
+	}
+	return false
+}
+
+// NPC is a non-hostile character archetype. NPCs are data: they are loaded
+// from a file and placed on the maps naming their ID. Talking to one starts
+// at its Start node.
+type NPC struct {
+	ID       string                  `json:"id"`
+	Name     string                  `json:"name"`
+	Symbol   rune                    `json:"-"`
+	Start    string                  `json:"start"`
+	Dialogue map[string]DialogueNode `json:"dialogue"`
+	// Shop is set for the NPCs that trade.
+	Shop *Shop `json:"shop,omitempty"`
+}
+
+// Validate checks that the dialogue tree of the NPC is closed, that it only
+// offers quests and that its shop only sells items.
+func (n *NPC) Validate(items map[string]*Item, quests map[string]*Quest) error {
+	if n.ID == "" {
+		return errors.New("npc without id")
+	}
+	if _, ok := n.Dialogue[n.Start]; !ok {
+		return fmt.Errorf("npc %s: start node %q is not in its dialogue", n.ID, n.Start)
+	}
+	for id, node := range n.Dialogue {
+		for _, c := range node.Choices {
+			if c.Next != "" {
+				if _, ok := n.Dialogue[c.Next]; !ok {
+					return fmt.Errorf("npc %s: node %s leads to unknown node %q", n.ID, id, c.Next)
+				}
+			}
+			switch c.Action {
+			case "":
+			case ActionShop:
+				if n.Shop == nil {
+					return fmt.Errorf("npc %s: node %s opens a shop it does not have", n.ID, id)
+				}
+			case ActionQuest:
+				if quests[c.Quest] == nil {
+					return fmt.Errorf("npc %s: node %s: %w %q", n.ID, id, ErrUnknownQuest, c.Quest)
+				}
+			default:
+				return fmt.Errorf("npc %s: node %s: unknown action %q", n.ID, id, c.Action)
+			}
+		}
+	}
+	if n.Shop == nil {
+		return nil
+	}
+	if n.Shop.BuyRate <= 0 || n.Shop.BuyRate > 1 {
+		return fmt.Errorf("npc %s: buyRate must be in (0, 1]", n.ID)
+	}
+	for _, id := range n.Shop.Sells {
+		if items[id] == nil {
+			return fmt.Errorf("npc %s: shop: %w %q", n.ID, ErrUnknownItem, id)
+		}
+	}
+	return nil
+}
+
+// NPCSpawn places the NPC of the given ID on a cell of a world.
+type NPCSpawn struct {
+	ID  string `json:"id"`
+	NPC string `json:"npc"`
+	X   int    `json:"x"`
+	Y   int    `json:"y"`
+}
+
+// NPCAt returns the NPC standing on (x, y), if any. NPCs never move and
+// block the cell they stand on.
+func (w *World) NPCAt(x, y int) *NPCSpawn {
+	for i := range w.NPCs {
+		if w.NPCs[i].X == x && w.NPCs[i].Y == y {
+			return &w.NPCs[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package domain
+
+import "errors"
+
+var (
+	ErrPartyFull      = errors.New("the party is full")
+	ErrNotPartyLeader = errors.New("only the party leader can do that")
+	ErrInParty        = errors.New("already in a party")
+	ErrNotInParty     = errors.New("not in a party")
+	ErrNoInvite       = errors.New("no pending party invite")
+)
+
+const (
+	// MaxPartySize is the most players a party holds.
+	MaxPartySize = 4
+	// PartyShareRange is how close to a kill, in cells of the same world,
+	// members must be to share its rewards.
+	PartyShareRange = 15
+	// PartyXPBonus is the extra experience a kill grants per additional
+	// member sharing it, as a fraction of the mob's reward.
+	PartyXPBonus = 0.1
+)
+
+// Party is a group of players sharing experience, gold and loot. The first
+// member is the leader.
+type Party struct {
+	ID      string   `json:"id"`
+	Members []string `json:"members"`
+
+	// lootTurn is the index, among the members sharing a kill, of the next
+	// one to receive its loot.
+	lootTurn int
+}
+
+// NewParty creates a party led by leaderID.
+func NewParty(id, leaderID string) *Party {
+	return &Party{ID: id, Members: []string{leaderID}}
+}
+
+// Leader returns the ID of the party leader.
+func (p *Party) Leader() string {
+	return p.Members[0]
+}
+
+// Has reports whether playerID is a member of the party.
+func (p *Party) Has(playerID string) bool {
+	for _, id := range p.Members {
+		if id == playerID {
+			return true
+		}
+	}
+	return false
+}
+
+// Join adds playerID to the party.
+func (p *Party) Join(playerID string) error {
+	if p.Has(playerID) {
+		return ErrInParty
+	}
+	if len(p.Members) >= MaxPartySize {
+		return ErrPartyFull
+	}
+	p.Members = append(p.Members, playerID)
+	return nil
+}
+
+// Leave removes playerID from the party. When the leader leaves, the
+// longest standing member takes over.
+func (p *Party) Leave(playerID string) {
+	for i, id := range p.Members {
+		if id == playerID {
+			p.Members = append(p.Members[:i], p.Members[i+1:]...)
+			return
+		}
+	}
+}
+
+// Split shares xp, with the party bonus, and gold between n members. Each
+// gets an equal part, rounded up.
+func Split(xp, gold, n int) (xpShare, goldShare int) {
+	if n <= 1 {
+		return xp, gold
+	}
+	total := xp + int(float64(xp)*PartyXPBonus*float64(n-1))
+	return (total + n - 1) / n, (gold + n - 1) / n
+}
+
+// NextLooter returns which of n members sharing a kill receives its loot,
+// taking turns.
+func (p *Party) NextLooter(n int) int {
+	i := p.lootTurn % n
+	p.lootTurn++
+	return i
+}
@@ -0,0 +1,132 @@
+package domain
+
+import "container/heap"
+
+// Directions lists the compass directions in a fixed order, so that anything
+// iterating over them behaves the same on every run.
+var Directions = []string{"N", "S", "E", "W", "NE", "NW", "SE", "SW"}
+
+// FindPath returns the cheapest sequence of cells leading from `from` to `to`,
+// excluding `from`, weighted by tile movement cost. passable decides which
+// cells may be entered; the destination itself is always allowed so that a
+// path can end on an occupied cell. It gives up and returns nil after
+// visiting maxNodes cells or when no path exists.
+func FindPath(w *World, from, to Point, maxNodes int, passable func(t Tile, x, y int) bool) []Point {
+	if from == to {
+		return nil
+	}
+
+	cost := map[Point]int{from: 0}
+	came := map[Point]Point{}
+	open := &pathQueue{{p: from, f: chebyshev(from, to)}}
+
+	for visited := 0; open.Len() > 0 && visited < maxNodes; visited++ {
+		cur := heap.Pop(open).(pathNode).p
+		if cur == to {
+			var path []Point
+			for p := to; p != from; p = came[p] {
+				path = append(path, p)
+			}
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path
+		}
+
+		for _, dir := range Directions {
+			d := deltas[dir]
+			next := Point{X: cur.X + d.dx, Y: cur.Y + d.dy}
+			t := w.TileAt(next.X, next.Y)
+			if next != to && (!t.Walkable || !passable(t, next.X, next.Y)) {
+				continue
+			}
+			step := t.MoveCost
+			if step < 1 {
+				step = 1
+			}
+			c := cost[cur] + step
+			if old, seen := cost[next]; seen && old <= c {
+				continue
+			}
+			cost[next] = c
+			came[next] = cur
+			heap.Push(open, pathNode{p: next, f: c + chebyshev(next, to)})
+		}
+	}
+	return nil
+}
+
+// Step returns the cell one move away from p in direction dir.
+func Step(p Point, dir string) (Point, bool) {
+	d, ok := deltas[dir]
+	if !ok {
+		return p, false
+	}
+	return Point{X: p.X + d.dx, Y: p.Y + d.dy}, true
+}
+
+// DirectionTo returns the compass direction of a single step from `from` to
+// an adjacent cell `to`, or "" when they are not adjacent.
+func DirectionTo(from, to Point) string {
+	dx, dy := to.X-from.X, to.Y-from.Y
+	for _, dir := range Directions {
+		if d := deltas[dir]; d.dx == dx && d.dy == dy {
+			return dir
+		}
+	}
+	return ""
+}
+
+// Heading returns the compass direction that best points from from to to,
+// or "" when they are the same cell.
+func Heading(from, to Point) string {
+	return DirectionTo(Point{}, Point{X: sign(to.X - from.X), Y: sign(to.Y - from.Y)})
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// chebyshev is the distance with diagonal moves costing the same as straight
+// ones, which keeps the A* heuristic admissible.
+func chebyshev(a, b Point) int {
+	dx, dy := a.X-b.X, a.Y-b.Y
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	return max(dx, dy)
+}
+
+type pathNode struct {
+	p Point
+	f int
+}
+
+type pathQueue []pathNode
+
+func (q pathQueue) Len() int           { return len(q) }
+func (q pathQueue) Less(i, j int) bool { return q[i].f < q[j].f }
+func (q pathQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x any)        { *q = append(*q, x.(pathNode)) }
+func (q *pathQueue) Pop() any {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
@@ -1,10 +1,26 @@
 package domain
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type PlayerStore interface {
+	// GetPlayer returns a player loaded or saved since the server started.
 	GetPlayer(id string) *Player
+	// LoadPlayer returns the player id wherever the store keeps it, or nil
+	// when it was never saved.
+	LoadPlayer(id string) (*Player, error)
 	SavePlayer(player *Player)
+	// SavePlayers saves the players at once: no other call sees some of
+	// them saved and not the others, and on error none of them is saved.
+	SavePlayers(players ...*Player) error
+}
+
+// DurableStore is implemented by the player stores that keep what they save
+// across restarts. The others lose it with the server.
+type DurableStore interface {
+	Durable() bool
 }
 
 type Player struct {
@@ -16,33 +32,70 @@ type Player struct {
 	Attack  int    `json:"attack"`
 	Defense int    `json:"defense"`
 	Range   int    `json:"range"`
+
+	MaxHealth int `json:"maxHealth"`
+	Level     int `json:"level"`
+	XP        int `json:"xp"`
+	Mana      int `json:"mana"`
+	MaxMana   int `json:"maxMana"`
+	Gold      int `json:"gold"`
+	// PvPKills counts the players killed in PvP zones, and DuelsWon the
+	// duels won.
+	PvPKills int `json:"pvpKills,omitempty"`
+	DuelsWon int `json:"duelsWon,omitempty"`
+	// Role decides which admin commands the player may run.
+	Role Role `json:"role,omitempty"`
+	// SecretHash is what is kept of the secret the player joins with, see
+	// HashSecret.
+	SecretHash string `json:"-"`
+
+	// Inventory counts the items the player carries, by ID.
+	Inventory map[string]int `json:"inventory,omitempty"`
+	// Quests holds the player's progress in the quests they took, by ID.
+	Quests map[string]QuestProgress `json:"-"`
+
+	Statuses Statuses `json:"statuses,omitempty"`
+	// Cooldowns holds when each ability used so far is ready again.
+	Cooldowns map[string]time.Time `json:"cooldowns,omitempty"`
+
+	// Explored is kept server side; clients only ever receive the cells
+	// they have seen.
+	Explored Explored `json:"-"`
+
+	moveDebt int
+	// nextAttack is the earliest time the player may attack again.
+	nextAttack time.Time
 }
 
+const (
+	DefaultPlayerHealth = 100
+	DefaultPlayerMana   = 50
+)
+
 func NewPlayer(id string, x, y int) *Player {
 	return &Player{
 		ID:      id,
 		WorldID: "world1",
 		X:       x,
 		Y:       y,
-		Health:  100,
+		Health:  DefaultPlayerHealth,
 		Attack:  50,
 		Defense: 5,
 		Range:   10,
+
+		MaxHealth: DefaultPlayerHealth,
+		Level:     1,
+		Mana:      DefaultPlayerMana,
+		MaxMana:   DefaultPlayerMana,
+		Gold:      DefaultPlayerGold,
+		Role:      RolePlayer,
+		Inventory: make(map[string]int),
+		Explored:  make(Explored),
 	}
 }
 
 func (p *Player) canMove(x, y int, world *World) bool {
-	if y < 0 || y >= len(world.Layout) {
-		return false
-	}
-	if x < 0 || x >= len(world.Layout[y]) {
-		return false
-	}
-
-	if world.Layout[y][x] == '#' {
-		return false
-	}
-	return true
+	return world.TileAt(x, y).Walkable && world.NPCAt(x, y) == nil
 }
 
 var deltas = map[string]struct{ dx, dy int }{
@@ -61,32 +114,200 @@ func (p *Player) Move(direction string, world *World) error {
 	if !ok {
 		return fmt.Errorf("invalid direction %q", direction)
 	}
+	if p.Statuses.Has(StatusStun) {
+		return ErrStunned
+	}
+	if p.moveDebt > 0 {
+		p.moveDebt--
+		return ErrSlowed
+	}
 	nx, ny := p.X+d.dx, p.Y+d.dy
+	tile := world.TileAt(nx, ny)
+	if tile.Door && !tile.Walkable {
+		if err := world.ToggleDoor(nx, ny); err != nil {
+			return err
+		}
+		return ErrDoorOpened
+	}
 	if !p.canMove(nx, ny, world) {
 		return fmt.Errorf("cannot move %s", direction)
 	}
 	p.X, p.Y = nx, ny
+	p.moveDebt = tile.MoveCost - 1
+	if p.Statuses.Has(StatusSlow) {
+		p.moveDebt++
+	}
 	return nil
 }
 
-func (p *Player) TakeDamage(damage int) {
-	damage -= p.Defense
+// TakeDamage reduces the player's health by damage minus their defense and
+// any shield, and returns the damage actually taken.
+func (p *Player) TakeDamage(damage int) int {
+	damage = max(0, min(p.Statuses.absorb(max(0, damage-p.Defense)), p.Health))
+	p.Health -= damage
+	return damage
+}
+
+// See computes what the player can currently see and remembers it as
+// explored.
+func (p *Player) See(world *World) *FOV {
+	fov := world.ComputeFOV(Point{X: p.X, Y: p.Y}, DefaultSightRadius)
+	if p.Explored == nil {
+		p.Explored = make(Explored)
+	}
+	p.Explored.Reveal(world, fov)
+	return fov
+}
+
+// TakeHazardDamage applies terrain damage, which ignores defense but not
+// shields, and returns the damage actually taken.
+func (p *Player) TakeHazardDamage(damage int) int {
+	damage = max(0, min(p.Statuses.absorb(damage), p.Health))
 	p.Health -= damage
-	if p.Health < 0 {
-		p.Health = 0
+	return damage
+}
+
+// TickStatuses advances the player's status effects by one tick and returns
+// the damage taken and health restored by them.
+func (p *Player) TickStatuses() (damage, healed int) {
+	dot, regen := p.Statuses.Tick()
+	if dot > 0 {
+		damage = p.TakeHazardDamage(dot)
+	}
+	if regen > 0 && p.IsAlive() {
+		healed = p.Heal(regen)
+	}
+	return damage, healed
+}
+
+// Respawn brings a dead player back to life at (x, y).
+func (p *Player) Respawn(x, y int) {
+	p.X, p.Y = x, y
+	p.Health = p.MaxHealth
+	if p.Health <= 0 {
+		p.Health = DefaultPlayerHealth
 	}
+	p.moveDebt = 0
+	p.Statuses = nil
+}
+
+// XPToLevel is the experience needed to advance past the current level.
+func (p *Player) XPToLevel() int {
+	return 100 * max(1, p.Level)
+}
+
+// GainXP adds experience and applies any level ups, which raise the
+// player's stats and heal them fully. It reports whether a level was gained.
+func (p *Player) GainXP(xp int) bool {
+	if p.Level < 1 {
+		p.Level = 1
+	}
+	leveled := false
+	p.XP += xp
+	for p.XP >= p.XPToLevel() {
+		p.XP -= p.XPToLevel()
+		p.Level++
+		p.MaxHealth += 10
+		p.MaxMana += 5
+		p.Attack += 5
+		p.Defense++
+		p.Health = p.MaxHealth
+		p.Mana = p.MaxMana
+		leveled = true
+	}
+	return leveled
+}
+
+// AttackMob applies an attack with the given roll to mob and returns the
+// damage dealt. Attacks are rejected with ErrAttackCooldown until cooldown
+// has passed since the previous one.
+func (p *Player) AttackMob(mob *Mob, roll AttackRoll, now time.Time, cooldown time.Duration) (int, error) {
+	if err := p.startAttack(now, cooldown); err != nil {
+		return 0, err
+	}
+	if roll == AttackMiss {
+		return 0, nil
+	}
+	return mob.TakeDamage(roll.Damage(p.Attack)), nil
+}
+
+// Shoot checks that the player may attack now and starts the attack
+// cooldown for a projectile they fire. Shots share the cooldown of melee
+// attacks.
+func (p *Player) Shoot(now time.Time, cooldown time.Duration) error {
+	return p.startAttack(now, cooldown)
+}
+
+func (p *Player) startAttack(now time.Time, cooldown time.Duration) error {
+	if p.Statuses.Has(StatusStun) {
+		return ErrStunned
+	}
+	if now.Before(p.nextAttack) {
+		return ErrAttackCooldown
+	}
+	p.nextAttack = now.Add(cooldown)
+	return nil
+}
+
+// KnowsAbility reports whether the player has reached the level needed to
+// use a.
+func (p *Player) KnowsAbility(a *Ability) bool {
+	return p.Level >= a.Level
+}
+
+// AbilityCooldownLeft is how long the player must wait before using a again.
+func (p *Player) AbilityCooldownLeft(a *Ability, now time.Time) time.Duration {
+	return max(0, p.Cooldowns[a.ID].Sub(now))
+}
+
+// UseAbility checks that the player may use a now, then spends its mana and
+// starts its cooldown.
+func (p *Player) UseAbility(a *Ability, now time.Time) error {
+	switch {
+	case !p.KnowsAbility(a):
+		return ErrAbilityLocked
+	case p.Statuses.Has(StatusStun):
+		return ErrStunned
+	case p.AbilityCooldownLeft(a, now) > 0:
+		return ErrAbilityCooldown
+	case p.Mana < a.ManaCost:
+		return ErrNotEnoughMana
+	}
+	p.Mana -= a.ManaCost
+	if p.Cooldowns == nil {
+		p.Cooldowns = make(map[string]time.Time)
+	}
+	p.Cooldowns[a.ID] = now.Add(a.Cooldown)
+	return nil
+}
+
+// RegenMana restores up to n mana and reports whether any was restored.
+func (p *Player) RegenMana(n int) bool {
+	if p.Mana >= p.MaxMana {
+		return false
+	}
+	p.Mana = min(p.MaxMana, p.Mana+n)
+	return true
+}
+
+// Heal restores up to n health and returns the amount restored.
+func (p *Player) Heal(n int) int {
+	healed := max(0, min(n, p.MaxHealth-p.Health))
+	p.Health += healed
+	return healed
 }
 
-func (p *Player) AttackMob(mob *Mob) {
-	mob.TakeDamage(p.Attack)
+// CooldownLeft is how long the player must wait before attacking again.
+func (p *Player) CooldownLeft(now time.Time) time.Duration {
+	return max(0, p.nextAttack.Sub(now))
 }
 
 func (p *Player) IsAlive() bool {
 	return p.Health > 0
 }
 
-func (p *Player) SpawnPlayer(w *World, occupiedPositions map[string]bool) error {
-	x, y, err := FindRandomSpawnPosition(w, occupiedPositions)
+func (p *Player) SpawnPlayer(w *World, occupiedPositions map[string]bool, rng RNG) error {
+	x, y, err := FindRandomSpawnPosition(w, occupiedPositions, rng)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	ErrUnknownProjectile = errors.New("unknown projectile")
+	ErrNothingToShoot    = errors.New("nothing to shoot at")
+)
+
+// ProjectileKind describes a type of projectile: how many cells it flies per
+// tick and how far it goes before dropping.
+type ProjectileKind struct {
+	Name string
+	// Symbol is the glyph clients draw, or 0 for one following the heading.
+	Symbol rune
+	Speed  int
+	Range  int
+	// Inflicts is the status effect a hit applies, if its kind is set.
+	Inflicts Status
+}
+
+var projectileKinds = map[string]ProjectileKind{
+	"arrow":    {Name: "arrow", Speed: 3, Range: 10},
+	"firebolt": {Name: "firebolt", Symbol: '*', Speed: 2, Range: 8, Inflicts: Status{Kind: StatusBurning, Ticks: 3, Power: 2}},
+}
+
+// LookupProjectile returns the projectile kind with the given name.
+func LookupProjectile(name string) (ProjectileKind, bool) {
+	k, ok := projectileKinds[name]
+	return k, ok
+}
+
+// Projectile is an arrow or spell in flight. It travels along a straight
+// line on every game tick until it hits a wall or an entity, or runs out of
+// range. Projectiles are transient and never persisted.
+type Projectile struct {
+	ID      string `json:"id"`
+	Kind    string `json:"kind"`
+	WorldID string `json:"worldID"`
+	X       int    `json:"x"`
+	Y       int    `json:"y"`
+	Symbol  rune   `json:"symbol"`
+
+	// OwnerID is the player or mob that fired it and Owner its name.
+	OwnerID string `json:"ownerID"`
+	Owner   string `json:"owner"`
+	// FromMob tells whether the owner is a mob rather than a player.
+	FromMob bool `json:"-"`
+	// Damage is the base damage of a hit, before the attack roll.
+	Damage   int    `json:"-"`
+	Speed    int    `json:"-"`
+	Inflicts Status `json:"-"`
+
+	path []Point
+}
+
+// NewProjectile fires a projectile of the named kind from `from` towards
+// `to`. It flies through `to` and on until it has covered its range.
+func NewProjectile(kind, id, worldID string, from, to Point) (*Projectile, error) {
+	k, ok := projectileKinds[kind]
+	if !ok {
+		return nil, ErrUnknownProjectile
+	}
+	path := Trajectory(from, to, k.Range)
+	if len(path) == 0 {
+		return nil, ErrNothingToShoot
+	}
+	symbol := k.Symbol
+	if symbol == 0 {
+		symbol = headingGlyph(to.X-from.X, to.Y-from.Y)
+	}
+	return &Projectile{
+		ID:       id,
+		Kind:     kind,
+		WorldID:  worldID,
+		X:        from.X,
+		Y:        from.Y,
+		Symbol:   symbol,
+		Speed:    k.Speed,
+		Inflicts: k.Inflicts,
+		path:     path,
+	}, nil
+}
+
+// headingGlyph draws a line heading dx, dy with the closest of - | / \.
+func headingGlyph(dx, dy int) rune {
+	switch {
+	case 2*abs(dy) < abs(dx):
+		return '-'
+	case 2*abs(dx) < abs(dy):
+		return '|'
+	case (dx > 0) == (dy > 0):
+		return '\\'
+	}
+	return '/'
+}
+
+// Trajectory returns the length cells of the straight line leaving from and
+// passing through to, excluding from.
+func Trajectory(from, to Point, length int) []Point {
+	dx, dy := to.X-from.X, to.Y-from.Y
+	steps := max(abs(dx), abs(dy))
+	if steps == 0 {
+		return nil
+	}
+	cells := make([]Point, 0, length)
+	for i := 1; i <= length; i++ {
+		cells = append(cells, Point{
+			X: from.X + int(math.Round(float64(dx*i)/float64(steps))),
+			Y: from.Y + int(math.Round(float64(dy*i)/float64(steps))),
+		})
+	}
+	return cells
+}
+
+// Advance moves the projectile up to Speed cells along its path. It stops
+// on the first cell for which occupied returns true and returns that cell
+// and true. Opaque tiles stop it short of them and spend it.
+func (p *Projectile) Advance(w *World, occupied func(Point) bool) (Point, bool) {
+	for i := 0; i < p.Speed && len(p.path) > 0; i++ {
+		next := p.path[0]
+		if !w.InBounds(next.X, next.Y) || w.TileAt(next.X, next.Y).Opaque {
+			p.path = nil
+			break
+		}
+		p.X, p.Y = next.X, next.Y
+		p.path = p.path[1:]
+		if occupied(next) {
+			return next, true
+		}
+	}
+	return Point{}, false
+}
+
+// Spent reports whether the projectile has nowhere left to fly.
+func (p *Projectile) Spent() bool {
+	return len(p.path) == 0
+}
@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrSafeZone       = errors.New("combat is not allowed in a safe zone")
+	ErrNotHostile     = errors.New("players can only be fought in PvP zones or duels")
+	ErrDueling        = errors.New("already in a duel")
+	ErrDuelNotStarted = errors.New("the duel has not started yet")
+	ErrTooFarToDuel   = errors.New("too far away to duel")
+	ErrTooHurtToDuel  = errors.New("too hurt to duel")
+)
+
+// RegionKind is the combat rule of a region.
+type RegionKind string
+
+const (
+	// RegionPvP lets players attack each other freely.
+	RegionPvP RegionKind = "pvp"
+	// RegionSafe forbids all combat.
+	RegionSafe RegionKind = "safe"
+)
+
+// Region is a rectangle of a world with its own combat rule.
+type Region struct {
+	ID   string     `json:"id"`
+	Kind RegionKind `json:"kind"`
+	MinX int        `json:"minX"`
+	MinY int        `json:"minY"`
+	MaxX int        `json:"maxX"`
+	MaxY int        `json:"maxY"`
+}
+
+// Contains reports whether (x, y) is inside the region.
+func (r Region) Contains(x, y int) bool {
+	return x >= r.MinX && x <= r.MaxX && y >= r.MinY && y <= r.MaxY
+}
+
+// RegionAt returns the combat rule at (x, y), empty outside of any region.
+// Safe regions win over the PvP regions they overlap.
+func (w *World) RegionAt(x, y int) RegionKind {
+	var kind RegionKind
+	for _, r := range w.Regions {
+		if !r.Contains(x, y) {
+			continue
+		}
+		if r.Kind == RegionSafe {
+			return RegionSafe
+		}
+		kind = r.Kind
+	}
+	return kind
+}
+
+const (
+	// DuelCountdown is the time between a duel being accepted and the
+	// duelists being allowed to fight.
+	DuelCountdown = 3 * time.Second
+	// DuelRange is how close, in cells of the same world, duelists must
+	// stay; a duel whose duelists part ends without a winner.
+	DuelRange = 10
+	// DuelYieldPercent is the share of their maximum health at which a
+	// duelist yields, losing the duel.
+	DuelYieldPercent = 10
+)
+
+// Duel is a fight two players agreed to, which ends when one of them
+// yields instead of dying.
+type Duel struct {
+	ID      string
+	Players [2]string
+	// Start is when the countdown ends, and Fighting is set once it did.
+	Start    time.Time
+	Fighting bool
+}
+
+// NewDuel pairs the players a and b, starting the countdown at now.
+func NewDuel(id, a, b string, now time.Time) *Duel {
+	return &Duel{ID: id, Players: [2]string{a, b}, Start: now.Add(DuelCountdown)}
+}
+
+// Opponent returns the other duelist than playerID.
+func (d *Duel) Opponent(playerID string) string {
+	if d.Players[0] == playerID {
+		return d.Players[1]
+	}
+	return d.Players[0]
+}
+
+// YieldHealth is the health at which the player yields a duel.
+func (p *Player) YieldHealth() int {
+	return max(1, p.MaxHealth*DuelYieldPercent/100)
+}
+
+// AttackPlayer attacks target with the given roll and returns the damage
+// dealt. The target's health does not drop below floor, so that duelists
+// yield instead of dying; a target already at or below floor takes no
+// damage and is not healed either.
+func (p *Player) AttackPlayer(target *Player, roll AttackRoll, now time.Time, cooldown time.Duration, floor int) (int, error) {
+	if err := p.startAttack(now, cooldown); err != nil {
+		return 0, err
+	}
+	if roll == AttackMiss {
+		return 0, nil
+	}
+	before := target.Health
+	damage := target.TakeDamage(roll.Damage(p.Attack))
+	if target.Health < floor {
+		target.Health = min(before, floor)
+		damage = before - target.Health
+	}
+	return damage, nil
+}
+
+// PvPXPReward is the experience granted for killing the player in a PvP
+// zone.
+func (p *Player) PvPXPReward() int {
+	return 10 * max(1, p.Level)
+}
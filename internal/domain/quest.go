@@ -0,0 +1,198 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownQuest   = errors.New("unknown quest")
+	ErrQuestTaken     = errors.New("you already took that quest")
+	ErrQuestTooHard   = errors.New("you are not experienced enough for that quest")
+	ErrQuestCompleted = errors.New("you already completed that quest")
+)
+
+// ObjectiveKind is what a quest objective asks for.
+type ObjectiveKind string
+
+const (
+	// ObjectiveKill asks for Count kills of mobs of type Target.
+	ObjectiveKill ObjectiveKind = "kill"
+	// ObjectiveCollect asks for Count items Target, which are handed over
+	// when the objective is met.
+	ObjectiveCollect ObjectiveKind = "collect"
+	// ObjectiveReach asks to come within Radius of (X, Y) in world Target.
+	ObjectiveReach ObjectiveKind = "reach"
+	// ObjectiveTalk asks to talk to the NPC Target.
+	ObjectiveTalk ObjectiveKind = "talk"
+)
+
+// Objective is a step of a quest. Text describes it to players; objectives
+// without one get a generated description.
+type Objective struct {
+	Kind   ObjectiveKind `json:"kind"`
+	Target string        `json:"target"`
+	Count  int           `json:"count,omitempty"`
+	X      int           `json:"x,omitempty"`
+	Y      int           `json:"y,omitempty"`
+	Radius int           `json:"radius,omitempty"`
+	Text   string        `json:"text,omitempty"`
+}
+
+// Needed is how many times the objective must be fulfilled.
+func (o Objective) Needed() int {
+	return max(1, o.Count)
+}
+
+// Reward is what a quest grants once completed.
+type Reward struct {
+	XP    int            `json:"xp,omitempty"`
+	Gold  int            `json:"gold,omitempty"`
+	Items map[string]int `json:"items,omitempty"`
+}
+
+// Quest is a goal players take from an NPC. Its objectives are met in
+// order. Quests are data: they are loaded from a file and offered by the
+// dialogue choices naming their ID.
+type Quest struct {
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Level       int         `json:"level,omitempty"`
+	Objectives  []Objective `json:"objectives"`
+	Reward      Reward      `json:"reward"`
+}
+
+// Validate checks that the quest is usable as defined, with every item it
+// involves in items.
+func (q *Quest) Validate(items map[string]*Item) error {
+	if q.ID == "" {
+		return errors.New("quest without id")
+	}
+	if q.Name == "" {
+		return fmt.Errorf("quest %s has no name", q.ID)
+	}
+	if len(q.Objectives) == 0 {
+		return fmt.Errorf("quest %s has no objectives", q.ID)
+	}
+	for i, o := range q.Objectives {
+		if o.Target == "" {
+			return fmt.Errorf("quest %s: objective %d has no target", q.ID, i+1)
+		}
+		if o.Count < 0 || o.Radius < 0 {
+			return fmt.Errorf("quest %s: objective %d: count and radius must not be negative", q.ID, i+1)
+		}
+		switch o.Kind {
+		case ObjectiveKill, ObjectiveReach, ObjectiveTalk:
+		case ObjectiveCollect:
+			if items[o.Target] == nil {
+				return fmt.Errorf("quest %s: objective %d: %w %q", q.ID, i+1, ErrUnknownItem, o.Target)
+			}
+		default:
+			return fmt.Errorf("quest %s: objective %d: unknown kind %q", q.ID, i+1, o.Kind)
+		}
+	}
+	if q.Reward.XP < 0 || q.Reward.Gold < 0 {
+		return fmt.Errorf("quest %s: rewards must not be negative", q.ID)
+	}
+	for id, n := range q.Reward.Items {
+		if items[id] == nil {
+			return fmt.Errorf("quest %s: reward: %w %q", q.ID, ErrUnknownItem, id)
+		}
+		if n <= 0 {
+			return fmt.Errorf("quest %s: reward: %s count must be positive", q.ID, id)
+		}
+	}
+	return nil
+}
+
+// QuestProgress is how far a player is in a quest: the objective they are
+// on and how many times they fulfilled it.
+type QuestProgress struct {
+	Step      int  `json:"step"`
+	Count     int  `json:"count"`
+	Completed bool `json:"completed"`
+}
+
+// QuestEvent is something a player did that may fulfill objectives of the
+// given kind. Target is the mob type killed, the NPC talked to or the world
+// the player is in at (X, Y). Collect objectives look at the inventory and
+// need no target.
+type QuestEvent struct {
+	Kind   ObjectiveKind
+	Target string
+	X, Y   int
+}
+
+// TakeQuest starts q for the player.
+func (p *Player) TakeQuest(q *Quest) error {
+	if pr, ok := p.Quests[q.ID]; ok {
+		if pr.Completed {
+			return ErrQuestCompleted
+		}
+		return ErrQuestTaken
+	}
+	if p.Level < q.Level {
+		return ErrQuestTooHard
+	}
+	if p.Quests == nil {
+		p.Quests = make(map[string]QuestProgress)
+	}
+	p.Quests[q.ID] = QuestProgress{}
+	return nil
+}
+
+// Advance applies ev to the player's progress in q and reports whether it
+// moved. Met objectives hand over their items and lead to the next, which
+// may already be met too; the quest is completed after the last one, and
+// granting its reward is up to the caller.
+func (p *Player) Advance(q *Quest, ev QuestEvent) bool {
+	pr, ok := p.Quests[q.ID]
+	if !ok || pr.Completed {
+		return false
+	}
+	moved := false
+	for pr.Step < len(q.Objectives) {
+		o := q.Objectives[pr.Step]
+		switch {
+		case o.Kind == ObjectiveCollect:
+			if have := min(p.Inventory[o.Target], o.Needed()); have != pr.Count {
+				pr.Count, moved = have, true
+			}
+		case o.Kind != ev.Kind || o.Target != ev.Target:
+		case o.Kind == ObjectiveReach:
+			if max(abs(ev.X-o.X), abs(ev.Y-o.Y)) <= o.Radius {
+				pr.Count, moved = 1, true
+			}
+		default:
+			pr.Count, moved = pr.Count+1, true
+		}
+		if pr.Count < o.Needed() {
+			break
+		}
+		if o.Kind == ObjectiveCollect {
+			p.Inventory[o.Target] -= o.Needed()
+			if p.Inventory[o.Target] <= 0 {
+				delete(p.Inventory, o.Target)
+			}
+		}
+		pr.Step, pr.Count = pr.Step+1, 0
+		// The event was used up by this objective.
+		ev = QuestEvent{}
+	}
+	pr.Completed = pr.Step == len(q.Objectives)
+	p.Quests[q.ID] = pr
+	return moved
+}
+
+// Grant gives the player the reward and reports whether they levelled up.
+func (p *Player) Grant(r Reward) bool {
+	p.Gold += r.Gold
+	for id, n := range r.Items {
+		if p.Inventory == nil {
+			p.Inventory = make(map[string]int)
+		}
+		p.Inventory[id] += n
+	}
+	return p.GainXP(r.XP)
+}
@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"sync"
+	"time"
+)
+
+// RNG is where the simulation draws its randomness from. *rand.Rand
+// implements it, so a simulation seeded the same way makes the same
+// choices.
+type RNG interface {
+	Intn(n int) int
+	Float64() float64
+}
+
+// Clock tells the time of the simulation, which cooldowns and countdowns
+// are measured against.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the wall clock.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time { return time.Now() }
+
+// ManualClock only moves when told to, so that a simulation can be run step
+// by step.
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewManualClock returns a clock stopped at start.
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock d forward.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,105 @@
+package domain
+
+import "errors"
+
+// ErrStunned is returned for actions attempted while stunned.
+var ErrStunned = errors.New("you are stunned")
+
+// StatusKind names a status effect. Poison and burning deal their power as
+// damage every tick, regen heals it, slow makes every move take an extra
+// tick, stun prevents moving and attacking, and shield absorbs up to its
+// power in damage.
+type StatusKind string
+
+const (
+	StatusPoison  StatusKind = "poison"
+	StatusBurning StatusKind = "burning"
+	StatusSlow    StatusKind = "slow"
+	StatusStun    StatusKind = "stun"
+	StatusRegen   StatusKind = "regen"
+	StatusShield  StatusKind = "shield"
+)
+
+// Beneficial reports whether the status helps whoever carries it.
+func (k StatusKind) Beneficial() bool {
+	return k == StatusRegen || k == StatusShield
+}
+
+func (k StatusKind) valid() bool {
+	switch k {
+	case StatusPoison, StatusBurning, StatusSlow, StatusStun, StatusRegen, StatusShield:
+		return true
+	}
+	return false
+}
+
+// Status is a status effect with the number of ticks it has left. Power is
+// its strength, such as the damage per tick of a poison.
+type Status struct {
+	Kind  StatusKind `json:"kind"`
+	Ticks int        `json:"ticks"`
+	Power int        `json:"power,omitempty"`
+}
+
+// Statuses are the status effects on a player or mob.
+type Statuses []Status
+
+// Apply adds s, refreshing an effect of the same kind instead of stacking
+// it: the longer duration and the stronger power win.
+func (ss *Statuses) Apply(s Status) {
+	for i := range *ss {
+		if (*ss)[i].Kind == s.Kind {
+			(*ss)[i].Ticks = max((*ss)[i].Ticks, s.Ticks)
+			(*ss)[i].Power = max((*ss)[i].Power, s.Power)
+			return
+		}
+	}
+	*ss = append(*ss, s)
+}
+
+// Has reports whether a status of kind is active.
+func (ss Statuses) Has(kind StatusKind) bool {
+	for _, s := range ss {
+		if s.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Tick advances every status by one tick, removing the expired ones, and
+// returns the damage and healing over time they produce this tick.
+func (ss *Statuses) Tick() (damage, heal int) {
+	kept := (*ss)[:0]
+	for _, s := range *ss {
+		switch s.Kind {
+		case StatusPoison, StatusBurning:
+			damage += s.Power
+		case StatusRegen:
+			heal += s.Power
+		}
+		s.Ticks--
+		if s.Ticks > 0 {
+			kept = append(kept, s)
+		}
+	}
+	*ss = kept
+	return damage, heal
+}
+
+// absorb lets an active shield soak up damage, wearing it down, and returns
+// the damage that gets through.
+func (ss *Statuses) absorb(damage int) int {
+	for i, s := range *ss {
+		if s.Kind != StatusShield {
+			continue
+		}
+		soaked := min(s.Power, damage)
+		(*ss)[i].Power -= soaked
+		if (*ss)[i].Power <= 0 {
+			*ss = append((*ss)[:i], (*ss)[i+1:]...)
+		}
+		return damage - soaked
+	}
+	return damage
+}
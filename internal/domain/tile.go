@@ -0,0 +1,139 @@
+package domain
+
+import "errors"
+
+const (
+	TileWall       byte = '#'
+	TileFloor      byte = ' '
+	TileWater      byte = '~'
+	TileDeepWater  byte = '='
+	TileDoor       byte = '+'
+	TileOpenDoor   byte = '\''
+	TileLockedDoor byte = 'D'
+	TileLava       byte = '^'
+	TileGrass      byte = '"'
+	TileStairsDown byte = '>'
+	TileStairsUp   byte = '<'
+)
+
+var (
+	ErrNoDoor     = errors.New("there is no door there")
+	ErrDoorLocked = errors.New("the door is locked")
+	// ErrDoorOpened is returned by Move when the player bumped into a closed
+	// door and opened it instead of moving.
+	ErrDoorOpened = errors.New("you open the door")
+	// ErrSlowed is returned by Move while the player is still crossing
+	// terrain with a movement cost above one.
+	ErrSlowed = errors.New("you struggle through the terrain")
+)
+
+// Tile describes the terrain properties of a layout glyph.
+type Tile struct {
+	Glyph    byte
+	Name     string
+	Walkable bool
+	// MoveCost is the number of moves it takes to enter the tile.
+	MoveCost int
+	// Opaque tiles block line of sight.
+	Opaque bool
+	// Damage is dealt every tick to whoever stands on the tile.
+	Damage int
+	// Inflicts is a status effect applied to whoever stands on the tile,
+	// which lingers for a few ticks after they leave it.
+	Inflicts Status
+	// Door is set on door tiles, which can be toggled between their open
+	// and closed glyphs unless locked.
+	Door   bool
+	Locked bool
+}
+
+func (t Tile) IsHazard() bool {
+	return t.Damage > 0 || t.Inflicts.Kind != ""
+}
+
+var tiles = map[byte]Tile{
+	TileWall:       {Glyph: TileWall, Name: "wall", Opaque: true},
+	TileFloor:      {Glyph: TileFloor, Name: "floor", Walkable: true, MoveCost: 1},
+	TileWater:      {Glyph: TileWater, Name: "water", Walkable: true, MoveCost: 2},
+	TileDeepWater:  {Glyph: TileDeepWater, Name: "deep-water"},
+	TileDoor:       {Glyph: TileDoor, Name: "door", Opaque: true, Door: true},
+	TileOpenDoor:   {Glyph: TileOpenDoor, Name: "open-door", Walkable: true, MoveCost: 1, Door: true},
+	TileLockedDoor: {Glyph: TileLockedDoor, Name: "locked-door", Opaque: true, Door: true, Locked: true},
+	TileLava:       {Glyph: TileLava, Name: "lava", Walkable: true, MoveCost: 1, Damage: 10, Inflicts: Status{Kind: StatusBurning, Ticks: 3, Power: 3}},
+	TileGrass:      {Glyph: TileGrass, Name: "grass", Walkable: true, MoveCost: 1},
+	TileStairsDown: {Glyph: TileStairsDown, Name: "stairs-down", Walkable: true, MoveCost: 1},
+	TileStairsUp:   {Glyph: TileStairsUp, Name: "stairs-up", Walkable: true, MoveCost: 1},
+}
+
+// LookupTile returns the tile drawn with glyph g.
+func LookupTile(g byte) (Tile, bool) {
+	t, ok := tiles[g]
+	return t, ok
+}
+
+// TileByName returns the tile used in map file legends under name.
+func TileByName(name string) (Tile, bool) {
+	for _, t := range tiles {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Tile{}, false
+}
+
+// TileAt returns the tile at (x, y). Cells outside the layout are walls.
+func (w *World) TileAt(x, y int) Tile {
+	w.layoutMu.RLock()
+	defer w.layoutMu.RUnlock()
+	return w.tileAt(x, y)
+}
+
+func (w *World) tileAt(x, y int) Tile {
+	if !w.InBounds(x, y) {
+		return tiles[TileWall]
+	}
+	t, ok := tiles[w.Layout[y][x]]
+	if !ok {
+		return tiles[TileWall]
+	}
+	return t
+}
+
+// Glyph returns the glyph of the cell at (x, y), or zero outside the
+// layout.
+func (w *World) Glyph(x, y int) byte {
+	w.layoutMu.RLock()
+	defer w.layoutMu.RUnlock()
+	if !w.InBounds(x, y) {
+		return 0
+	}
+	return w.Layout[y][x]
+}
+
+// SetGlyph changes the cell at (x, y), which must be inside the layout, to
+// glyph and returns the glyph it had.
+func (w *World) SetGlyph(x, y int, glyph byte) byte {
+	w.layoutMu.Lock()
+	defer w.layoutMu.Unlock()
+	old := w.Layout[y][x]
+	w.Layout[y][x] = glyph
+	return old
+}
+
+// ToggleDoor opens a closed door or closes an open one at (x, y).
+func (w *World) ToggleDoor(x, y int) error {
+	w.layoutMu.Lock()
+	defer w.layoutMu.Unlock()
+	t := w.tileAt(x, y)
+	switch {
+	case !t.Door:
+		return ErrNoDoor
+	case t.Locked:
+		return ErrDoorLocked
+	case t.Walkable:
+		w.Layout[y][x] = TileDoor
+	default:
+		w.Layout[y][x] = TileOpenDoor
+	}
+	return nil
+}
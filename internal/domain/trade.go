@@ -0,0 +1,181 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrTrading         = errors.New("already trading")
+	ErrNotTrading      = errors.New("not trading")
+	ErrTooFarToTrade   = errors.New("too far away to trade")
+	ErrTradeNotOffered = errors.New("nothing offered by either side")
+)
+
+// TradeRange is how close, in cells of the same world, two players must
+// stand to trade.
+const TradeRange = 3
+
+// Offer is what one side of a trade puts up.
+type Offer struct {
+	Items map[string]int `json:"items,omitempty"`
+	Gold  int            `json:"gold,omitempty"`
+}
+
+func (o Offer) empty() bool {
+	return o.Gold == 0 && len(o.Items) == 0
+}
+
+// coveredBy checks that the player owns everything offered.
+func (o Offer) coveredBy(p *Player) error {
+	if p.Gold < o.Gold {
+		return fmt.Errorf("%s: %w", p.ID, ErrNotEnoughGold)
+	}
+	for id, n := range o.Items {
+		if p.Inventory[id] < n {
+			return fmt.Errorf("%s: %w", p.ID, ErrItemNotOwned)
+		}
+	}
+	return nil
+}
+
+// Trade is an exchange between two players. Both put up an offer, then
+// both confirm it; changing either offer withdraws the confirmations, so
+// each side only ever agrees to what they saw.
+type Trade struct {
+	ID        string
+	Players   [2]string
+	Offers    [2]Offer
+	Confirmed [2]bool
+}
+
+// NewTrade opens a trade between the players a and b with empty offers.
+func NewTrade(id, a, b string) *Trade {
+	return &Trade{ID: id, Players: [2]string{a, b}}
+}
+
+// side returns the index of playerID in the trade, or -1.
+func (t *Trade) side(playerID string) int {
+	for i, id := range t.Players {
+		if id == playerID {
+			return i
+		}
+	}
+	return -1
+}
+
+// Partner returns the ID of the other side of playerID.
+func (t *Trade) Partner(playerID string) string {
+	return t.Players[1-t.side(playerID)]
+}
+
+// Offer returns what playerID puts up.
+func (t *Trade) Offer(playerID string) Offer {
+	return t.Offers[t.side(playerID)]
+}
+
+// SetItems puts up count items itemID of p in place of what they offered of
+// it before; zero takes the item back.
+func (t *Trade) SetItems(p *Player, itemID string, count int) error {
+	i := t.side(p.ID)
+	if i < 0 {
+		return ErrNotTrading
+	}
+	if count < 0 || p.Inventory[itemID] < count {
+		return ErrItemNotOwned
+	}
+	offer := &t.Offers[i]
+	if count == 0 {
+		delete(offer.Items, itemID)
+	} else {
+		if offer.Items == nil {
+			offer.Items = make(map[string]int)
+		}
+		offer.Items[itemID] = count
+	}
+	t.Confirmed = [2]bool{}
+	return nil
+}
+
+// SetGold puts up gold of p in place of what they offered before.
+func (t *Trade) SetGold(p *Player, gold int) error {
+	i := t.side(p.ID)
+	if i < 0 {
+		return ErrNotTrading
+	}
+	if gold < 0 || p.Gold < gold {
+		return ErrNotEnoughGold
+	}
+	t.Offers[i].Gold = gold
+	t.Confirmed = [2]bool{}
+	return nil
+}
+
+// Confirm agrees to the trade as it stands on behalf of playerID and
+// reports whether both sides did.
+func (t *Trade) Confirm(playerID string) (bool, error) {
+	i := t.side(playerID)
+	if i < 0 {
+		return false, ErrNotTrading
+	}
+	if t.Offers[0].empty() && t.Offers[1].empty() {
+		return false, ErrTradeNotOffered
+	}
+	t.Confirmed[i] = true
+	return t.Confirmed[0] && t.Confirmed[1], nil
+}
+
+// Exchange swaps the offers between a and b, the players of the trade in
+// order. Nothing changes hands unless both still own all they offered. The
+// caller keeps anything else from changing the gold and inventory of both
+// players until it returns.
+func (t *Trade) Exchange(a, b *Player) error {
+	players := [2]*Player{a, b}
+	for i, p := range players {
+		if p.ID != t.Players[i] {
+			return fmt.Errorf("%s is not part of trade %s", p.ID, t.ID)
+		}
+		if err := t.Offers[i].coveredBy(p); err != nil {
+			return err
+		}
+	}
+	for i, p := range players {
+		to := players[1-i]
+		offer := t.Offers[i]
+		p.Gold -= offer.Gold
+		to.Gold += offer.Gold
+		for id, n := range offer.Items {
+			p.Inventory[id] -= n
+			if p.Inventory[id] == 0 {
+				delete(p.Inventory, id)
+			}
+			if to.Inventory == nil {
+				to.Inventory = make(map[string]int)
+			}
+			to.Inventory[id] += n
+		}
+	}
+	return nil
+}
+
+// Undo gives back what Exchange swapped between a and b, for an exchange
+// that could not be saved. The caller holds the same locks as for Exchange.
+func (t *Trade) Undo(a, b *Player) {
+	players := [2]*Player{a, b}
+	for i, p := range players {
+		from := players[1-i]
+		offer := t.Offers[i]
+		from.Gold -= offer.Gold
+		p.Gold += offer.Gold
+		for id, n := range offer.Items {
+			from.Inventory[id] -= n
+			if from.Inventory[id] == 0 {
+				delete(from.Inventory, id)
+			}
+			if p.Inventory == nil {
+				p.Inventory = make(map[string]int)
+			}
+			p.Inventory[id] += n
+		}
+	}
+}
@@ -2,39 +2,10 @@ package domain
 
 import (
 	"fmt"
-	"math/rand"
 	"strings"
+	"sync"
 )
 
-var Raw string = `#####################################################
-#                                                   #
-#                                                   #
-#                                                   #
-#                                                   #
-#               ####                  ####          #
-#               #  #                ###             #
-#               #  #                #               #
-#               ####               ##               #
-#                                                   #
-#                                                   #
-#                                                   #
-#                                                   #
-#                                                   #
-#          #####                                    #
-#        ###   #                                    #
-#       #                                           #
-#       #                         #                 #
-#       #                        ##                 #
-#                             ####                  #
-#                          ###                      #
-#                                                   #
-#                                               ##  #
-#                                             ###   #
-#                                            ##     #
-#                                             #     #
-#                                                   #
-#####################################################`
-
 type WorldStore interface {
 	GetWorld(id string) *World
 }
@@ -52,12 +23,59 @@ func ConvertLayout(layout string) Layout {
 	return result
 }
 
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Portal moves a player stepping on (X, Y) to (ToX, ToY) in the world ToWorld.
+type Portal struct {
+	X       int    `json:"x"`
+	Y       int    `json:"y"`
+	ToWorld string `json:"toWorld"`
+	ToX     int    `json:"toX"`
+	ToY     int    `json:"toY"`
+}
+
+// SpawnZone is a rectangle, bounds included, that the game loop keeps
+// populated with up to Cap mobs of MobType.
+type SpawnZone struct {
+	ID      string `json:"id"`
+	MobType string `json:"mobType"`
+	MinX    int    `json:"minX"`
+	MinY    int    `json:"minY"`
+	MaxX    int    `json:"maxX"`
+	MaxY    int    `json:"maxY"`
+	Cap     int    `json:"cap"`
+}
+
+func (l Layout) String() string {
+	rows := make([]string, len(l))
+	for i, row := range l {
+		rows[i] = string(row)
+	}
+	return strings.Join(rows, "\n")
+}
+
 type World struct {
 	ID     string `json:"id"`
+	Name   string `json:"name"`
 	Width  int    `json:"width"`
 	Height int    `json:"height"`
 
 	Layout Layout `json:"layout"`
+	// layoutMu guards the cells of Layout, which doors and arena locks
+	// change while the game loop and the connections read them. Once the
+	// world is shared, cells are read and written through TileAt, Glyph,
+	// SetGlyph and ToggleDoor.
+	layoutMu sync.RWMutex
+
+	SpawnPoints []Point     `json:"spawnPoints,omitempty"`
+	Portals     []Portal    `json:"portals,omitempty"`
+	SpawnZones  []SpawnZone `json:"spawnZones,omitempty"`
+	Arenas      []Arena     `json:"arenas,omitempty"`
+	NPCs        []NPCSpawn  `json:"npcs,omitempty"`
+	Regions     []Region    `json:"regions,omitempty"`
 }
 
 func NewWorld(id string, width, height int, layout Layout) *World {
@@ -69,13 +87,49 @@ func NewWorld(id string, width, height int, layout Layout) *World {
 	}
 }
 
-func FindRandomSpawnPosition(w *World, occupiedPositions map[string]bool) (int, int, error) {
+func (w *World) InBounds(x, y int) bool {
+	return y >= 0 && y < len(w.Layout) && x >= 0 && x < len(w.Layout[y])
+}
+
+func (w *World) PortalAt(x, y int) *Portal {
+	for i := range w.Portals {
+		if w.Portals[i].X == x && w.Portals[i].Y == y {
+			return &w.Portals[i]
+		}
+	}
+	return nil
+}
+
+// FindPlayerSpawnPosition picks a free spawn point of the world, falling back
+// to a random position when the world defines none or all are occupied.
+func (w *World) FindPlayerSpawnPosition(occupiedPositions map[string]bool, rng RNG) (int, int, error) {
+	var free []Point
+	for _, p := range w.SpawnPoints {
+		if !occupiedPositions[fmt.Sprintf("%d,%d", p.X, p.Y)] {
+			free = append(free, p)
+		}
+	}
+	if len(free) > 0 {
+		p := free[rng.Intn(len(free))]
+		return p.X, p.Y, nil
+	}
+	return FindRandomSpawnPosition(w, occupiedPositions, rng)
+}
+
+func FindRandomSpawnPosition(w *World, occupiedPositions map[string]bool, rng RNG) (int, int, error) {
+	return findRandomPositionIn(w, 0, 0, w.Width-1, w.Height-1, occupiedPositions, rng)
+}
+
+func findRandomPositionIn(w *World, minX, minY, maxX, maxY int, occupiedPositions map[string]bool, rng RNG) (int, int, error) {
 	maxAttempts := 100
 	for i := 0; i < maxAttempts; i++ {
-		x := rand.Intn(w.Width)
-		y := rand.Intn(w.Height)
+		x := minX + rng.Intn(maxX-minX+1)
+		y := minY + rng.Intn(maxY-minY+1)
+		if !w.InBounds(x, y) {
+			continue
+		}
 
-		if w.Layout[y][x] != '#' && w.Layout[y][x] != '@' {
+		if t := w.TileAt(x, y); t.Walkable && !t.IsHazard() && w.NPCAt(x, y) == nil {
 			key := fmt.Sprintf("%d,%d", x, y)
 			if !occupiedPositions[key] {
 				return x, y, nil
@@ -85,24 +139,40 @@ func FindRandomSpawnPosition(w *World, occupiedPositions map[string]bool) (int,
 	return 0, 0, fmt.Errorf("could not find valid spawn position after %d attempts", maxAttempts)
 }
 
-func (w *World) SpawnMob(mobType, name, mobID string, occupiedPositions map[string]bool) (*Mob, error) {
-	x, y, err := FindRandomSpawnPosition(w, occupiedPositions)
+func (w *World) SpawnMob(mobType, name, mobID string, occupiedPositions map[string]bool, rng RNG) (*Mob, error) {
+	x, y, err := FindRandomSpawnPosition(w, occupiedPositions, rng)
 	if err != nil {
 		return nil, err
 	}
+	return w.newMob(mobType, name, mobID, x, y), nil
+}
+
+func (w *World) SpawnMobInZone(zone SpawnZone, name, mobID string, occupiedPositions map[string]bool, rng RNG) (*Mob, error) {
+	x, y, err := findRandomPositionIn(w, zone.MinX, zone.MinY, zone.MaxX, zone.MaxY, occupiedPositions, rng)
+	if err != nil {
+		return nil, err
+	}
+	mob := w.newMob(zone.MobType, name, mobID, x, y)
+	mob.Zone = zone.ID
+	return mob, nil
+}
 
+func (w *World) newMob(mobType, name, mobID string, x, y int) *Mob {
 	mob := &Mob{
-		ID:      mobID,
-		Name:    name,
-		Type:    mobType,
-		WorldID: w.ID,
-		X:       x,
-		Y:       y,
-		Health:  100,
-		Attack:  10,
-		Defense: 5,
-		Symbol:  'M',
+		ID:        mobID,
+		Name:      name,
+		Type:      mobType,
+		WorldID:   w.ID,
+		X:         x,
+		Y:         y,
+		Health:    100,
+		MaxHealth: 100,
+		Attack:    10,
+		Defense:   5,
+		Symbol:    'M',
+		State:     MobIdle,
+		Home:      Point{X: x, Y: y},
 	}
 
-	return mob, nil
+	return mob
 }
@@ -0,0 +1,260 @@
+// Package adminapi serves the HTTP API operators use to inspect and manage
+// a running game server without a game client.
+package adminapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"time"
+
+	"github.com/LealKevin/terminus/internal/app"
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+// MaxSpawnCount is the most mobs a single spawn request brings in.
+const MaxSpawnCount = 50
+
+type Server struct {
+	Addr    string
+	Token   string
+	Handler *app.Handler
+	// Pprof serves the net/http/pprof profiles on /debug/pprof/, behind
+	// the bearer token like the rest of the API.
+	Pprof bool
+	// Log is the logger of the admin subsystem.
+	Log *slog.Logger
+}
+
+func NewServer(addr, token string, handler *app.Handler) *Server {
+	return &Server{Addr: addr, Token: token, Handler: handler, Log: slog.Default().With("subsystem", "admin")}
+}
+
+// Start serves the API until ctx is done.
+func (s *Server) Start(ctx context.Context) {
+	srv := &http.Server{
+		Addr:              s.Addr,
+		Handler:           s.Routes(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	s.Log.Info("admin API listening", "addr", s.Addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.Log.Error("admin API stopped", "err", err)
+	}
+}
+
+// Routes returns the API behind the bearer token check.
+func (s *Server) Routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/worlds", s.listWorlds)
+	mux.HandleFunc("GET /api/worlds/{id}/mobs", s.listMobs)
+	mux.HandleFunc("POST /api/worlds/{id}/mobs", s.spawnMobs)
+	mux.HandleFunc("DELETE /api/mobs/{id}", s.despawnMob)
+	mux.HandleFunc("GET /api/players", s.listPlayers)
+	mux.HandleFunc("POST /api/players/{id}/kick", s.kickPlayer)
+	mux.HandleFunc("GET /api/connections", s.listConnections)
+	mux.HandleFunc("GET /api/ticks", s.tickStats)
+	mux.HandleFunc("POST /api/announce", s.announce)
+	mux.HandleFunc("POST /api/save", s.save)
+	if s.Pprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	return s.authorize(mux)
+}
+
+// authorize rejects the requests without the bearer token.
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || s.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="terminus"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or wrong bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) listWorlds(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Handler.WorldStats())
+}
+
+func (s *Server) listMobs(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if s.Handler.Worlds.GetWorld(id) == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no world %q", id))
+		return
+	}
+	mobs := s.Handler.Mobs.GetMobsByWorld(id)
+	if mobs == nil {
+		mobs = []*domain.Mob{}
+	}
+	writeJSON(w, http.StatusOK, mobs)
+}
+
+type spawnRequest struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func (s *Server) spawnMobs(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if s.Handler.Worlds.GetWorld(id) == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no world %q", id))
+		return
+	}
+	var req spawnRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if !domain.KnownMobType(req.Type) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown mob type %q", req.Type))
+		return
+	}
+	if req.Count == 0 {
+		req.Count = 1
+	}
+	if req.Count < 0 || req.Count > MaxSpawnCount {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("count must be between 1 and %d", MaxSpawnCount))
+		return
+	}
+	if req.Name == "" {
+		req.Name = req.Type
+	}
+
+	mobs := []*domain.Mob{}
+	for i := 0; i < req.Count; i++ {
+		mob, err := s.Handler.HandleMobSpawn(r.Context(), id, req.Type, req.Name)
+		if err != nil {
+			break
+		}
+		mobs = append(mobs, mob)
+	}
+	s.audit(r, fmt.Sprintf("spawned %d %s", len(mobs), req.Type))
+	if len(mobs) == 0 {
+		writeError(w, http.StatusConflict, fmt.Errorf("no room to spawn %s in %s", req.Type, id))
+		return
+	}
+	writeJSON(w, http.StatusCreated, mobs)
+}
+
+func (s *Server) despawnMob(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.Handler.HandleMobDespawn(r.Context(), id); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	s.audit(r, "despawned "+id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listPlayers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Handler.OnlinePlayers())
+}
+
+type kickRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (s *Server) kickPlayer(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var req kickRequest
+	if r.ContentLength != 0 && !readJSON(w, r, &req) {
+		return
+	}
+	why := "You were kicked by an operator"
+	if req.Reason != "" {
+		why += ": " + req.Reason
+	}
+	if err := s.Handler.Kick(id, why); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	s.audit(r, "kicked "+id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listConnections(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Handler.Connections())
+}
+
+func (s *Server) tickStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Handler.TickStats())
+}
+
+type announceRequest struct {
+	Message string `json:"message"`
+}
+
+func (s *Server) announce(w http.ResponseWriter, r *http.Request) {
+	var req announceRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if strings.TrimSpace(req.Message) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("message is required"))
+		return
+	}
+	n := s.Handler.Announce(req.Message)
+	s.audit(r, fmt.Sprintf("announced %q", req.Message))
+	writeJSON(w, http.StatusOK, map[string]int{"recipients": n})
+}
+
+func (s *Server) save(w http.ResponseWriter, r *http.Request) {
+	n, err := s.Handler.SaveState()
+	if errors.Is(err, app.ErrNotDurable) {
+		writeError(w, http.StatusNotImplemented, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.audit(r, fmt.Sprintf("saved %d players", n))
+	writeJSON(w, http.StatusOK, map[string]int{"saved": n})
+}
+
+// audit records a change made through the API in the admin audit log.
+func (s *Server) audit(r *http.Request, outcome string) {
+	s.Handler.Audit.Printf("api %s %s %s: %s", r.RemoteAddr, r.Method, r.URL.Path, outcome)
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Default().With("subsystem", "admin").Error("error writing admin API response", "err", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
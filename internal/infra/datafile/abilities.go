@@ -0,0 +1,59 @@
+package datafile
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+const AbilitiesFile = "abilities.json"
+
+// ability is an ability as written in the data file, with its cooldown as a
+// duration string like "2.5s".
+type ability struct {
+	*domain.Ability
+	Cooldown string `json:"cooldown"`
+}
+
+// ParseAbilities reads a JSON array of abilities, keeping their order.
+func ParseAbilities(r io.Reader) ([]*domain.Ability, error) {
+	var raw []ability
+	if err := decode(r, &raw); err != nil {
+		return nil, err
+	}
+	return convertAbilities(raw)
+}
+
+// convertAbilities parses the cooldowns of raw and validates the abilities.
+func convertAbilities(raw []ability) ([]*domain.Ability, error) {
+	abilities := make([]*domain.Ability, 0, len(raw))
+	seen := make(map[string]bool)
+	for i, a := range raw {
+		if a.Ability == nil {
+			return nil, fmt.Errorf("ability %d is empty", i)
+		}
+		if a.Cooldown != "" {
+			d, err := time.ParseDuration(a.Cooldown)
+			if err != nil {
+				return nil, fmt.Errorf("ability %s: cooldown: %w", a.ID, err)
+			}
+			a.Ability.Cooldown = d
+		}
+		if err := a.Validate(); err != nil {
+			return nil, err
+		}
+		if seen[a.ID] {
+			return nil, fmt.Errorf("ability %s is defined twice", a.ID)
+		}
+		seen[a.ID] = true
+		abilities = append(abilities, a.Ability)
+	}
+	return abilities, nil
+}
+
+// LoadAbilities reads the abilities file of the data directory dir.
+func LoadAbilities(dir string) ([]*domain.Ability, error) {
+	return load(dir, AbilitiesFile, ParseAbilities)
+}
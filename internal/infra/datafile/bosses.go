@@ -0,0 +1,71 @@
+package datafile
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+const BossesFile = "bosses.json"
+
+// bossesFile holds the boss archetypes along with the abilities only they
+// cast, which players never learn.
+type bossesFile struct {
+	Abilities []ability `json:"abilities"`
+	Bosses    []boss    `json:"bosses"`
+}
+
+// boss is a boss as written in the data file, with its symbol as a one
+// character string.
+type boss struct {
+	*domain.Boss
+	Symbol string `json:"symbol"`
+}
+
+// ParseBosses reads the boss archetypes and resolves the abilities their
+// phases cast.
+func ParseBosses(r io.Reader) ([]*domain.Boss, error) {
+	var raw bossesFile
+	if err := decode(r, &raw); err != nil {
+		return nil, err
+	}
+	abilities, err := convertAbilities(raw.Abilities)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*domain.Ability, len(abilities))
+	for _, a := range abilities {
+		byID[a.ID] = a
+	}
+
+	bosses := make([]*domain.Boss, 0, len(raw.Bosses))
+	seen := make(map[string]bool)
+	for i, b := range raw.Bosses {
+		if b.Boss == nil {
+			return nil, fmt.Errorf("boss %d is empty", i)
+		}
+		if b.Symbol != "" {
+			if utf8.RuneCountInString(b.Symbol) != 1 {
+				return nil, fmt.Errorf("boss %s: symbol must be a single character", b.Type)
+			}
+			b.Boss.Symbol, _ = utf8.DecodeRuneInString(b.Symbol)
+		}
+		b.Boss.Abilities = byID
+		if err := b.Validate(); err != nil {
+			return nil, err
+		}
+		if seen[b.Type] {
+			return nil, fmt.Errorf("boss %s is defined twice", b.Type)
+		}
+		seen[b.Type] = true
+		bosses = append(bosses, b.Boss)
+	}
+	return bosses, nil
+}
+
+// LoadBosses reads the bosses file of the data directory dir.
+func LoadBosses(dir string) ([]*domain.Boss, error) {
+	return load(dir, BossesFile, ParseBosses)
+}
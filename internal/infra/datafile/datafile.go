@@ -0,0 +1,36 @@
+// Package datafile reads the game data definitions, such as abilities,
+// stored as JSON files in the data directory.
+package datafile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// decode reads a single JSON value from r into v, rejecting unknown fields
+// so that typos in data files do not go unnoticed.
+func decode(r io.Reader, v any) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// load opens name in dir and parses it with parse.
+func load[T any](dir, name string, parse func(io.Reader) (T, error)) (T, error) {
+	path := filepath.Join(dir, name)
+	f, err := os.Open(path)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer f.Close()
+
+	v, err := parse(f)
+	if err != nil {
+		return v, fmt.Errorf("%s: %w", path, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,37 @@
+package datafile
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+const ItemsFile = "items.json"
+
+// ParseItems reads a JSON array of items, keeping their order.
+func ParseItems(r io.Reader) ([]*domain.Item, error) {
+	var raw []*domain.Item
+	if err := decode(r, &raw); err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for i, item := range raw {
+		if item == nil {
+			return nil, fmt.Errorf("item %d is empty", i)
+		}
+		if err := item.Validate(); err != nil {
+			return nil, err
+		}
+		if seen[item.ID] {
+			return nil, fmt.Errorf("item %s is defined twice", item.ID)
+		}
+		seen[item.ID] = true
+	}
+	return raw, nil
+}
+
+// LoadItems reads the items file of the data directory dir.
+func LoadItems(dir string) ([]*domain.Item, error) {
+	return load(dir, ItemsFile, ParseItems)
+}
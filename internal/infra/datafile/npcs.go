@@ -0,0 +1,66 @@
+package datafile
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+const NPCsFile = "npcs.json"
+
+// npc is an NPC as written in the data file, with its symbol as a one
+// character string.
+type npc struct {
+	*domain.NPC
+	Symbol string `json:"symbol"`
+}
+
+// ParseNPCs reads a JSON array of NPCs whose shops trade in items and who
+// offer quests.
+func ParseNPCs(r io.Reader, items []*domain.Item, quests []*domain.Quest) ([]*domain.NPC, error) {
+	var raw []npc
+	if err := decode(r, &raw); err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*domain.Item, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+	questsByID := make(map[string]*domain.Quest, len(quests))
+	for _, q := range quests {
+		questsByID[q.ID] = q
+	}
+
+	npcs := make([]*domain.NPC, 0, len(raw))
+	seen := make(map[string]bool)
+	for i, n := range raw {
+		if n.NPC == nil {
+			return nil, fmt.Errorf("npc %d is empty", i)
+		}
+		n.NPC.Symbol = '&'
+		if n.Symbol != "" {
+			if utf8.RuneCountInString(n.Symbol) != 1 {
+				return nil, fmt.Errorf("npc %s: symbol must be a single character", n.ID)
+			}
+			n.NPC.Symbol, _ = utf8.DecodeRuneInString(n.Symbol)
+		}
+		if err := n.Validate(byID, questsByID); err != nil {
+			return nil, err
+		}
+		if seen[n.ID] {
+			return nil, fmt.Errorf("npc %s is defined twice", n.ID)
+		}
+		seen[n.ID] = true
+		npcs = append(npcs, n.NPC)
+	}
+	return npcs, nil
+}
+
+// LoadNPCs reads the NPCs file of the data directory dir.
+func LoadNPCs(dir string, items []*domain.Item, quests []*domain.Quest) ([]*domain.NPC, error) {
+	return load(dir, NPCsFile, func(r io.Reader) ([]*domain.NPC, error) {
+		return ParseNPCs(r, items, quests)
+	})
+}
@@ -0,0 +1,45 @@
+package datafile
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+const QuestsFile = "quests.json"
+
+// ParseQuests reads a JSON array of quests involving items, keeping their
+// order.
+func ParseQuests(r io.Reader, items []*domain.Item) ([]*domain.Quest, error) {
+	var raw []*domain.Quest
+	if err := decode(r, &raw); err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*domain.Item, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+
+	seen := make(map[string]bool)
+	for i, q := range raw {
+		if q == nil {
+			return nil, fmt.Errorf("quest %d is empty", i)
+		}
+		if err := q.Validate(byID); err != nil {
+			return nil, err
+		}
+		if seen[q.ID] {
+			return nil, fmt.Errorf("quest %s is defined twice", q.ID)
+		}
+		seen[q.ID] = true
+	}
+	return raw, nil
+}
+
+// LoadQuests reads the quests file of the data directory dir.
+func LoadQuests(dir string, items []*domain.Item) ([]*domain.Quest, error) {
+	return load(dir, QuestsFile, func(r io.Reader) ([]*domain.Quest, error) {
+		return ParseQuests(r, items)
+	})
+}
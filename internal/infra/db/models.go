@@ -25,15 +25,53 @@ type Mob struct {
 }
 
 type Player struct {
-	ID        pgtype.UUID
-	WorldID   pgtype.UUID
-	X         int32
-	Y         int32
-	Health    int32
-	Attack    int32
-	Defense   int32
-	Range     int32
-	CreatedAt pgtype.Timestamptz
+	ID         string
+	WorldID    string
+	X          int32
+	Y          int32
+	Health     int32
+	Attack     int32
+	Defense    int32
+	Range      int32
+	MaxHealth  int32
+	Level      int32
+	Xp         int32
+	Mana       int32
+	MaxMana    int32
+	Gold       int32
+	PvpKills   int32
+	DuelsWon   int32
+	Role       string
+	SecretHash string
+	CreatedAt  pgtype.Timestamptz
+	UpdatedAt  pgtype.Timestamptz
+}
+
+type PlayerAbilityCooldown struct {
+	PlayerID  string
+	AbilityID string
+	ReadyAt   pgtype.Timestamptz
+}
+
+type PlayerExplored struct {
+	PlayerID  string
+	WorldID   string
+	Cells     []byte
+	UpdatedAt pgtype.Timestamptz
+}
+
+type PlayerItem struct {
+	PlayerID string
+	ItemID   string
+	Count    int32
+}
+
+type PlayerQuest struct {
+	PlayerID  string
+	QuestID   string
+	Step      int32
+	Count     int32
+	Completed bool
 	UpdatedAt pgtype.Timestamptz
 }
 
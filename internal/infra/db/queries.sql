@@ -26,23 +26,75 @@ DELETE FROM worlds
 WHERE id = $1;
 
 -- name: CreatePlayer :one
-INSERT INTO players (id, world_id, x, y, health, attack, defense, range)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, world_id, x, y, health, attack, defense, range, created_at, updated_at;
+INSERT INTO players (id, world_id, x, y, health, attack, defense, range, max_health, level, xp, mana, max_mana, gold, pvp_kills, duels_won, role, secret_hash)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+RETURNING id, world_id, x, y, health, attack, defense, range, max_health, level, xp, mana, max_mana, gold, pvp_kills, duels_won, role, secret_hash, created_at, updated_at;
 
 -- name: GetPlayerByID :one
-SELECT id, world_id, x, y, health, attack, defense, range, created_at, updated_at
+SELECT id, world_id, x, y, health, attack, defense, range, max_health, level, xp, mana, max_mana, gold, pvp_kills, duels_won, role, secret_hash, created_at, updated_at
 FROM players
 WHERE id = $1;
 
 -- name: UpdatePlayer :one
 UPDATE players
-SET world_id = $8, x = $2, y = $3, health = $4, attack = $5, defense = $6, range = $7, updated_at = CURRENT_TIMESTAMP
+SET world_id = $8, x = $2, y = $3, health = $4, attack = $5, defense = $6, range = $7, max_health = $9, level = $10, xp = $11, mana = $12, max_mana = $13, gold = $14, pvp_kills = $15, duels_won = $16, role = $17, secret_hash = $18, updated_at = CURRENT_TIMESTAMP
 WHERE id = $1
-RETURNING id, world_id, x, y, health, attack, defense, range, created_at, updated_at;
+RETURNING id, world_id, x, y, health, attack, defense, range, max_health, level, xp, mana, max_mana, gold, pvp_kills, duels_won, role, secret_hash, created_at, updated_at;
+
+-- name: UpsertPlayer :exec
+INSERT INTO players (id, world_id, x, y, health, attack, defense, range, max_health, level, xp, mana, max_mana, gold, pvp_kills, duels_won, role, secret_hash)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+ON CONFLICT (id)
+DO UPDATE SET world_id = EXCLUDED.world_id, x = EXCLUDED.x, y = EXCLUDED.y, health = EXCLUDED.health, attack = EXCLUDED.attack, defense = EXCLUDED.defense, range = EXCLUDED.range, max_health = EXCLUDED.max_health, level = EXCLUDED.level, xp = EXCLUDED.xp, mana = EXCLUDED.mana, max_mana = EXCLUDED.max_mana, gold = EXCLUDED.gold, pvp_kills = EXCLUDED.pvp_kills, duels_won = EXCLUDED.duels_won, role = EXCLUDED.role, secret_hash = EXCLUDED.secret_hash, updated_at = CURRENT_TIMESTAMP;
 
 -- name: DeletePlayer :exec
 DELETE FROM players
 WHERE id = $1;
 
+-- name: GetPlayerExplored :many
+SELECT player_id, world_id, cells, updated_at
+FROM player_explored
+WHERE player_id = $1;
+
+-- name: UpsertPlayerExplored :exec
+INSERT INTO player_explored (player_id, world_id, cells)
+VALUES ($1, $2, $3)
+ON CONFLICT (player_id, world_id)
+DO UPDATE SET cells = EXCLUDED.cells, updated_at = CURRENT_TIMESTAMP;
+
+-- name: GetPlayerQuests :many
+SELECT player_id, quest_id, step, count, completed, updated_at
+FROM player_quests
+WHERE player_id = $1;
+
+-- name: UpsertPlayerQuest :exec
+INSERT INTO player_quests (player_id, quest_id, step, count, completed)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (player_id, quest_id)
+DO UPDATE SET step = EXCLUDED.step, count = EXCLUDED.count, completed = EXCLUDED.completed, updated_at = CURRENT_TIMESTAMP;
+
+-- name: GetPlayerCooldowns :many
+SELECT player_id, ability_id, ready_at
+FROM player_ability_cooldowns
+WHERE player_id = $1;
+
+-- name: UpsertPlayerCooldown :exec
+INSERT INTO player_ability_cooldowns (player_id, ability_id, ready_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (player_id, ability_id)
+DO UPDATE SET ready_at = EXCLUDED.ready_at;
+
+-- name: GetPlayerItems :many
+SELECT player_id, item_id, count
+FROM player_items
+WHERE player_id = $1;
+
+-- name: UpsertPlayerItem :exec
+INSERT INTO player_items (player_id, item_id, count)
+VALUES ($1, $2, $3)
+ON CONFLICT (player_id, item_id)
+DO UPDATE SET count = EXCLUDED.count;
 
+-- name: DeletePlayerItem :exec
+DELETE FROM player_items
+WHERE player_id = $1 AND item_id = $2;
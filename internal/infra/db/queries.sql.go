@@ -12,20 +12,30 @@ import (
 )
 
 const createPlayer = `-- name: CreatePlayer :one
-INSERT INTO players (id, world_id, x, y, health, attack, defense, range)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, world_id, x, y, health, attack, defense, range, created_at, updated_at
+INSERT INTO players (id, world_id, x, y, health, attack, defense, range, max_health, level, xp, mana, max_mana, gold, pvp_kills, duels_won, role, secret_hash)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+RETURNING id, world_id, x, y, health, attack, defense, range, max_health, level, xp, mana, max_mana, gold, pvp_kills, duels_won, role, secret_hash, created_at, updated_at
 `
 
 type CreatePlayerParams struct {
-	ID      pgtype.UUID
-	WorldID pgtype.UUID
-	X       int32
-	Y       int32
-	Health  int32
-	Attack  int32
-	Defense int32
-	Range   int32
+	ID         string
+	WorldID    string
+	X          int32
+	Y          int32
+	Health     int32
+	Attack     int32
+	Defense    int32
+	Range      int32
+	MaxHealth  int32
+	Level      int32
+	Xp         int32
+	Mana       int32
+	MaxMana    int32
+	Gold       int32
+	PvpKills   int32
+	DuelsWon   int32
+	Role       string
+	SecretHash string
 }
 
 func (q *Queries) CreatePlayer(ctx context.Context, arg CreatePlayerParams) (Player, error) {
@@ -38,6 +48,16 @@ func (q *Queries) CreatePlayer(ctx context.Context, arg CreatePlayerParams) (Pla
 		arg.Attack,
 		arg.Defense,
 		arg.Range,
+		arg.MaxHealth,
+		arg.Level,
+		arg.Xp,
+		arg.Mana,
+		arg.MaxMana,
+		arg.Gold,
+		arg.PvpKills,
+		arg.DuelsWon,
+		arg.Role,
+		arg.SecretHash,
 	)
 	var i Player
 	err := row.Scan(
@@ -49,6 +69,16 @@ func (q *Queries) CreatePlayer(ctx context.Context, arg CreatePlayerParams) (Pla
 		&i.Attack,
 		&i.Defense,
 		&i.Range,
+		&i.MaxHealth,
+		&i.Level,
+		&i.Xp,
+		&i.Mana,
+		&i.MaxMana,
+		&i.Gold,
+		&i.PvpKills,
+		&i.DuelsWon,
+		&i.Role,
+		&i.SecretHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -92,11 +122,26 @@ DELETE FROM players
 WHERE id = $1
 `
 
-func (q *Queries) DeletePlayer(ctx context.Context, id pgtype.UUID) error {
+func (q *Queries) DeletePlayer(ctx context.Context, id string) error {
 	_, err := q.db.Exec(ctx, deletePlayer, id)
 	return err
 }
 
+const deletePlayerItem = `-- name: DeletePlayerItem :exec
+DELETE FROM player_items
+WHERE player_id = $1 AND item_id = $2
+`
+
+type DeletePlayerItemParams struct {
+	PlayerID string
+	ItemID   string
+}
+
+func (q *Queries) DeletePlayerItem(ctx context.Context, arg DeletePlayerItemParams) error {
+	_, err := q.db.Exec(ctx, deletePlayerItem, arg.PlayerID, arg.ItemID)
+	return err
+}
+
 const deleteWorld = `-- name: DeleteWorld :exec
 DELETE FROM worlds
 WHERE id = $1
@@ -108,12 +153,12 @@ func (q *Queries) DeleteWorld(ctx context.Context, id pgtype.UUID) error {
 }
 
 const getPlayerByID = `-- name: GetPlayerByID :one
-SELECT id, world_id, x, y, health, attack, defense, range, created_at, updated_at
+SELECT id, world_id, x, y, health, attack, defense, range, max_health, level, xp, mana, max_mana, gold, pvp_kills, duels_won, role, secret_hash, created_at, updated_at
 FROM players
 WHERE id = $1
 `
 
-func (q *Queries) GetPlayerByID(ctx context.Context, id pgtype.UUID) (Player, error) {
+func (q *Queries) GetPlayerByID(ctx context.Context, id string) (Player, error) {
 	row := q.db.QueryRow(ctx, getPlayerByID, id)
 	var i Player
 	err := row.Scan(
@@ -125,12 +170,138 @@ func (q *Queries) GetPlayerByID(ctx context.Context, id pgtype.UUID) (Player, er
 		&i.Attack,
 		&i.Defense,
 		&i.Range,
+		&i.MaxHealth,
+		&i.Level,
+		&i.Xp,
+		&i.Mana,
+		&i.MaxMana,
+		&i.Gold,
+		&i.PvpKills,
+		&i.DuelsWon,
+		&i.Role,
+		&i.SecretHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
+const getPlayerCooldowns = `-- name: GetPlayerCooldowns :many
+SELECT player_id, ability_id, ready_at
+FROM player_ability_cooldowns
+WHERE player_id = $1
+`
+
+func (q *Queries) GetPlayerCooldowns(ctx context.Context, playerID string) ([]PlayerAbilityCooldown, error) {
+	rows, err := q.db.Query(ctx, getPlayerCooldowns, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PlayerAbilityCooldown
+	for rows.Next() {
+		var i PlayerAbilityCooldown
+		if err := rows.Scan(&i.PlayerID, &i.AbilityID, &i.ReadyAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPlayerExplored = `-- name: GetPlayerExplored :many
+SELECT player_id, world_id, cells, updated_at
+FROM player_explored
+WHERE player_id = $1
+`
+
+func (q *Queries) GetPlayerExplored(ctx context.Context, playerID string) ([]PlayerExplored, error) {
+	rows, err := q.db.Query(ctx, getPlayerExplored, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PlayerExplored
+	for rows.Next() {
+		var i PlayerExplored
+		if err := rows.Scan(
+			&i.PlayerID,
+			&i.WorldID,
+			&i.Cells,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPlayerItems = `-- name: GetPlayerItems :many
+SELECT player_id, item_id, count
+FROM player_items
+WHERE player_id = $1
+`
+
+func (q *Queries) GetPlayerItems(ctx context.Context, playerID string) ([]PlayerItem, error) {
+	rows, err := q.db.Query(ctx, getPlayerItems, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PlayerItem
+	for rows.Next() {
+		var i PlayerItem
+		if err := rows.Scan(&i.PlayerID, &i.ItemID, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPlayerQuests = `-- name: GetPlayerQuests :many
+SELECT player_id, quest_id, step, count, completed, updated_at
+FROM player_quests
+WHERE player_id = $1
+`
+
+func (q *Queries) GetPlayerQuests(ctx context.Context, playerID string) ([]PlayerQuest, error) {
+	rows, err := q.db.Query(ctx, getPlayerQuests, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PlayerQuest
+	for rows.Next() {
+		var i PlayerQuest
+		if err := rows.Scan(
+			&i.PlayerID,
+			&i.QuestID,
+			&i.Step,
+			&i.Count,
+			&i.Completed,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getWorldByID = `-- name: GetWorldByID :one
 SELECT id, width, height, layout, created_at, updated_at
 FROM worlds
@@ -192,20 +363,30 @@ func (q *Queries) ListWorlds(ctx context.Context, arg ListWorldsParams) ([]World
 
 const updatePlayer = `-- name: UpdatePlayer :one
 UPDATE players
-SET world_id = $8, x = $2, y = $3, health = $4, attack = $5, defense = $6, range = $7, updated_at = CURRENT_TIMESTAMP
+SET world_id = $8, x = $2, y = $3, health = $4, attack = $5, defense = $6, range = $7, max_health = $9, level = $10, xp = $11, mana = $12, max_mana = $13, gold = $14, pvp_kills = $15, duels_won = $16, role = $17, secret_hash = $18, updated_at = CURRENT_TIMESTAMP
 WHERE id = $1
-RETURNING id, world_id, x, y, health, attack, defense, range, created_at, updated_at
+RETURNING id, world_id, x, y, health, attack, defense, range, max_health, level, xp, mana, max_mana, gold, pvp_kills, duels_won, role, secret_hash, created_at, updated_at
 `
 
 type UpdatePlayerParams struct {
-	ID      pgtype.UUID
-	X       int32
-	Y       int32
-	Health  int32
-	Attack  int32
-	Defense int32
-	Range   int32
-	WorldID pgtype.UUID
+	ID         string
+	X          int32
+	Y          int32
+	Health     int32
+	Attack     int32
+	Defense    int32
+	Range      int32
+	WorldID    string
+	MaxHealth  int32
+	Level      int32
+	Xp         int32
+	Mana       int32
+	MaxMana    int32
+	Gold       int32
+	PvpKills   int32
+	DuelsWon   int32
+	Role       string
+	SecretHash string
 }
 
 func (q *Queries) UpdatePlayer(ctx context.Context, arg UpdatePlayerParams) (Player, error) {
@@ -218,6 +399,16 @@ func (q *Queries) UpdatePlayer(ctx context.Context, arg UpdatePlayerParams) (Pla
 		arg.Defense,
 		arg.Range,
 		arg.WorldID,
+		arg.MaxHealth,
+		arg.Level,
+		arg.Xp,
+		arg.Mana,
+		arg.MaxMana,
+		arg.Gold,
+		arg.PvpKills,
+		arg.DuelsWon,
+		arg.Role,
+		arg.SecretHash,
 	)
 	var i Player
 	err := row.Scan(
@@ -229,6 +420,16 @@ func (q *Queries) UpdatePlayer(ctx context.Context, arg UpdatePlayerParams) (Pla
 		&i.Attack,
 		&i.Defense,
 		&i.Range,
+		&i.MaxHealth,
+		&i.Level,
+		&i.Xp,
+		&i.Mana,
+		&i.MaxMana,
+		&i.Gold,
+		&i.PvpKills,
+		&i.DuelsWon,
+		&i.Role,
+		&i.SecretHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -267,3 +468,135 @@ func (q *Queries) UpdateWorld(ctx context.Context, arg UpdateWorldParams) (World
 	)
 	return i, err
 }
+
+const upsertPlayer = `-- name: UpsertPlayer :exec
+INSERT INTO players (id, world_id, x, y, health, attack, defense, range, max_health, level, xp, mana, max_mana, gold, pvp_kills, duels_won, role, secret_hash)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+ON CONFLICT (id)
+DO UPDATE SET world_id = EXCLUDED.world_id, x = EXCLUDED.x, y = EXCLUDED.y, health = EXCLUDED.health, attack = EXCLUDED.attack, defense = EXCLUDED.defense, range = EXCLUDED.range, max_health = EXCLUDED.max_health, level = EXCLUDED.level, xp = EXCLUDED.xp, mana = EXCLUDED.mana, max_mana = EXCLUDED.max_mana, gold = EXCLUDED.gold, pvp_kills = EXCLUDED.pvp_kills, duels_won = EXCLUDED.duels_won, role = EXCLUDED.role, secret_hash = EXCLUDED.secret_hash, updated_at = CURRENT_TIMESTAMP
+`
+
+type UpsertPlayerParams struct {
+	ID         string
+	WorldID    string
+	X          int32
+	Y          int32
+	Health     int32
+	Attack     int32
+	Defense    int32
+	Range      int32
+	MaxHealth  int32
+	Level      int32
+	Xp         int32
+	Mana       int32
+	MaxMana    int32
+	Gold       int32
+	PvpKills   int32
+	DuelsWon   int32
+	Role       string
+	SecretHash string
+}
+
+func (q *Queries) UpsertPlayer(ctx context.Context, arg UpsertPlayerParams) error {
+	_, err := q.db.Exec(ctx, upsertPlayer,
+		arg.ID,
+		arg.WorldID,
+		arg.X,
+		arg.Y,
+		arg.Health,
+		arg.Attack,
+		arg.Defense,
+		arg.Range,
+		arg.MaxHealth,
+		arg.Level,
+		arg.Xp,
+		arg.Mana,
+		arg.MaxMana,
+		arg.Gold,
+		arg.PvpKills,
+		arg.DuelsWon,
+		arg.Role,
+		arg.SecretHash,
+	)
+	return err
+}
+
+const upsertPlayerCooldown = `-- name: UpsertPlayerCooldown :exec
+INSERT INTO player_ability_cooldowns (player_id, ability_id, ready_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (player_id, ability_id)
+DO UPDATE SET ready_at = EXCLUDED.ready_at
+`
+
+type UpsertPlayerCooldownParams struct {
+	PlayerID  string
+	AbilityID string
+	ReadyAt   pgtype.Timestamptz
+}
+
+func (q *Queries) UpsertPlayerCooldown(ctx context.Context, arg UpsertPlayerCooldownParams) error {
+	_, err := q.db.Exec(ctx, upsertPlayerCooldown, arg.PlayerID, arg.AbilityID, arg.ReadyAt)
+	return err
+}
+
+const upsertPlayerExplored = `-- name: UpsertPlayerExplored :exec
+INSERT INTO player_explored (player_id, world_id, cells)
+VALUES ($1, $2, $3)
+ON CONFLICT (player_id, world_id)
+DO UPDATE SET cells = EXCLUDED.cells, updated_at = CURRENT_TIMESTAMP
+`
+
+type UpsertPlayerExploredParams struct {
+	PlayerID string
+	WorldID  string
+	Cells    []byte
+}
+
+func (q *Queries) UpsertPlayerExplored(ctx context.Context, arg UpsertPlayerExploredParams) error {
+	_, err := q.db.Exec(ctx, upsertPlayerExplored, arg.PlayerID, arg.WorldID, arg.Cells)
+	return err
+}
+
+const upsertPlayerItem = `-- name: UpsertPlayerItem :exec
+INSERT INTO player_items (player_id, item_id, count)
+VALUES ($1, $2, $3)
+ON CONFLICT (player_id, item_id)
+DO UPDATE SET count = EXCLUDED.count
+`
+
+type UpsertPlayerItemParams struct {
+	PlayerID string
+	ItemID   string
+	Count    int32
+}
+
+func (q *Queries) UpsertPlayerItem(ctx context.Context, arg UpsertPlayerItemParams) error {
+	_, err := q.db.Exec(ctx, upsertPlayerItem, arg.PlayerID, arg.ItemID, arg.Count)
+	return err
+}
+
+const upsertPlayerQuest = `-- name: UpsertPlayerQuest :exec
+INSERT INTO player_quests (player_id, quest_id, step, count, completed)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (player_id, quest_id)
+DO UPDATE SET step = EXCLUDED.step, count = EXCLUDED.count, completed = EXCLUDED.completed, updated_at = CURRENT_TIMESTAMP
+`
+
+type UpsertPlayerQuestParams struct {
+	PlayerID  string
+	QuestID   string
+	Step      int32
+	Count     int32
+	Completed bool
+}
+
+func (q *Queries) UpsertPlayerQuest(ctx context.Context, arg UpsertPlayerQuestParams) error {
+	_, err := q.db.Exec(ctx, upsertPlayerQuest,
+		arg.PlayerID,
+		arg.QuestID,
+		arg.Step,
+		arg.Count,
+		arg.Completed,
+	)
+	return err
+}
@@ -7,19 +7,64 @@ CREATE TABLE worlds (
   updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Players are keyed by the ids the game gives them, and world_id is the id
+-- of a world loaded from the map files.
 CREATE TABLE players (
-  id UUID PRIMARY KEY,
-  world_id UUID REFERENCES worlds(id) ON DELETE CASCADE,
+  id TEXT PRIMARY KEY,
+  world_id TEXT NOT NULL,
   x INT NOT NULL,
   y INT NOT NULL,
   health INT NOT NULL,
   attack INT NOT NULL,
   defense INT NOT NULL,
   range INT NOT NULL,
+  max_health INT NOT NULL DEFAULT 100,
+  level INT NOT NULL DEFAULT 1,
+  xp INT NOT NULL DEFAULT 0,
+  mana INT NOT NULL DEFAULT 50,
+  max_mana INT NOT NULL DEFAULT 50,
+  gold INT NOT NULL DEFAULT 0,
+  pvp_kills INT NOT NULL DEFAULT 0,
+  duels_won INT NOT NULL DEFAULT 0,
+  role TEXT NOT NULL DEFAULT 'player',
+  -- secret_hash is the SHA-256 of the secret the player joins with.
+  secret_hash TEXT NOT NULL DEFAULT '',
   created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE TABLE player_ability_cooldowns (
+  player_id TEXT REFERENCES players(id) ON DELETE CASCADE,
+  ability_id TEXT NOT NULL,
+  ready_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  PRIMARY KEY (player_id, ability_id)
+);
+
+CREATE TABLE player_explored (
+  player_id TEXT REFERENCES players(id) ON DELETE CASCADE,
+  world_id TEXT NOT NULL,
+  cells BYTEA NOT NULL,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (player_id, world_id)
+);
+
+CREATE TABLE player_items (
+  player_id TEXT REFERENCES players(id) ON DELETE CASCADE,
+  item_id TEXT NOT NULL,
+  count INT NOT NULL CHECK (count > 0),
+  PRIMARY KEY (player_id, item_id)
+);
+
+CREATE TABLE player_quests (
+  player_id TEXT REFERENCES players(id) ON DELETE CASCADE,
+  quest_id TEXT NOT NULL,
+  step INT NOT NULL DEFAULT 0,
+  count INT NOT NULL DEFAULT 0,
+  completed BOOLEAN NOT NULL DEFAULT FALSE,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (player_id, quest_id)
+);
+
 CREATE TABLE mobs (
   id UUID PRIMARY KEY,
   name TEXT NOT NULL,
@@ -35,4 +80,3 @@ CREATE TABLE mobs (
   created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
-
@@ -0,0 +1,430 @@
+// Package mapfile reads and writes worlds stored as text map files.
+//
+// A map file is a metadata header followed by a "---" separator line and
+// the ASCII layout:
+//
+//	name: Green Meadow
+//	spawn: 2,2
+//	portal: 51,1 -> world2 2,2
+//	zone: Goblin 10,10 20,15 3
+//	arena: GoblinKing 30,2 40,9 35,5
+//	npc: merchant 4,2
+//	region: safe 1,1 5,3
+//	legend: . floor
+//	---
+//	#######
+//	#.....#
+//	#######
+//
+// The world id is the file name without its ".map" extension. Width and
+// height are derived from the layout, which must be rectangular.
+package mapfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+const (
+	Ext       = ".map"
+	separator = "---"
+)
+
+// Parse reads a single map file. Portal targets are not checked since they
+// may point at worlds defined in other files; see ValidatePortals.
+func Parse(id string, r io.Reader) (*domain.World, error) {
+	world := &domain.World{ID: id, Name: id}
+	legend := make(map[byte]byte)
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	inLayout := false
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimRight(sc.Text(), "\r")
+
+		if inLayout {
+			world.Layout = append(world.Layout, []byte(line))
+			continue
+		}
+		if strings.TrimSpace(line) == separator {
+			inLayout = true
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if err := parseHeader(world, legend, line); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", id, lineNo, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if !inLayout {
+		return nil, fmt.Errorf("%s: missing %q line before the layout", id, separator)
+	}
+
+	// A trailing newline at the end of the file is not an extra row.
+	for len(world.Layout) > 0 && len(world.Layout[len(world.Layout)-1]) == 0 {
+		world.Layout = world.Layout[:len(world.Layout)-1]
+	}
+	if err := applyLegend(world, legend); err != nil {
+		return nil, fmt.Errorf("%s: %w", id, err)
+	}
+	if err := validate(world); err != nil {
+		return nil, fmt.Errorf("%s: %w", id, err)
+	}
+	return world, nil
+}
+
+func parseHeader(world *domain.World, legend map[byte]byte, line string) error {
+	key, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return fmt.Errorf("expected \"key: value\", got %q", line)
+	}
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+
+	switch key {
+	case "name":
+		world.Name = value
+
+	case "spawn":
+		p, err := parsePoint(value)
+		if err != nil {
+			return fmt.Errorf("spawn: %w", err)
+		}
+		world.SpawnPoints = append(world.SpawnPoints, p)
+
+	case "portal":
+		from, to, ok := strings.Cut(value, "->")
+		if !ok {
+			return fmt.Errorf("portal: expected \"x,y -> world x,y\"")
+		}
+		p, err := parsePoint(strings.TrimSpace(from))
+		if err != nil {
+			return fmt.Errorf("portal: %w", err)
+		}
+		fields := strings.Fields(to)
+		if len(fields) != 2 {
+			return fmt.Errorf("portal: expected \"x,y -> world x,y\"")
+		}
+		target, err := parsePoint(fields[1])
+		if err != nil {
+			return fmt.Errorf("portal: %w", err)
+		}
+		world.Portals = append(world.Portals, domain.Portal{
+			X: p.X, Y: p.Y, ToWorld: fields[0], ToX: target.X, ToY: target.Y,
+		})
+
+	case "zone":
+		fields := strings.Fields(value)
+		if len(fields) != 4 {
+			return fmt.Errorf("zone: expected \"mobType x1,y1 x2,y2 cap\"")
+		}
+		lo, err := parsePoint(fields[1])
+		if err != nil {
+			return fmt.Errorf("zone: %w", err)
+		}
+		hi, err := parsePoint(fields[2])
+		if err != nil {
+			return fmt.Errorf("zone: %w", err)
+		}
+		limit, err := strconv.Atoi(fields[3])
+		if err != nil || limit < 0 {
+			return fmt.Errorf("zone: invalid cap %q", fields[3])
+		}
+		world.SpawnZones = append(world.SpawnZones, domain.SpawnZone{
+			ID:      fmt.Sprintf("%s-zone-%d", world.ID, len(world.SpawnZones)+1),
+			MobType: fields[0],
+			MinX:    lo.X,
+			MinY:    lo.Y,
+			MaxX:    hi.X,
+			MaxY:    hi.Y,
+			Cap:     limit,
+		})
+
+	case "arena":
+		fields := strings.Fields(value)
+		if len(fields) != 4 {
+			return fmt.Errorf("arena: expected \"bossType x1,y1 x2,y2 x,y\"")
+		}
+		var pts [3]domain.Point
+		for i, f := range fields[1:] {
+			p, err := parsePoint(f)
+			if err != nil {
+				return fmt.Errorf("arena: %w", err)
+			}
+			pts[i] = p
+		}
+		world.Arenas = append(world.Arenas, domain.Arena{
+			ID:    fmt.Sprintf("%s-arena-%d", world.ID, len(world.Arenas)+1),
+			Boss:  fields[0],
+			MinX:  pts[0].X,
+			MinY:  pts[0].Y,
+			MaxX:  pts[1].X,
+			MaxY:  pts[1].Y,
+			Spawn: pts[2],
+		})
+
+	case "npc":
+		fields := strings.Fields(value)
+		if len(fields) != 2 {
+			return fmt.Errorf("npc: expected \"npc x,y\"")
+		}
+		p, err := parsePoint(fields[1])
+		if err != nil {
+			return fmt.Errorf("npc: %w", err)
+		}
+		world.NPCs = append(world.NPCs, domain.NPCSpawn{
+			ID:  fmt.Sprintf("%s-npc-%d", world.ID, len(world.NPCs)+1),
+			NPC: fields[0],
+			X:   p.X,
+			Y:   p.Y,
+		})
+
+	case "region":
+		fields := strings.Fields(value)
+		if len(fields) != 3 {
+			return fmt.Errorf("region: expected \"pvp|safe x1,y1 x2,y2\"")
+		}
+		kind := domain.RegionKind(fields[0])
+		if kind != domain.RegionPvP && kind != domain.RegionSafe {
+			return fmt.Errorf("region: unknown kind %q", fields[0])
+		}
+		lo, err := parsePoint(fields[1])
+		if err != nil {
+			return fmt.Errorf("region: %w", err)
+		}
+		hi, err := parsePoint(fields[2])
+		if err != nil {
+			return fmt.Errorf("region: %w", err)
+		}
+		world.Regions = append(world.Regions, domain.Region{
+			ID:   fmt.Sprintf("%s-region-%d", world.ID, len(world.Regions)+1),
+			Kind: kind,
+			MinX: lo.X,
+			MinY: lo.Y,
+			MaxX: hi.X,
+			MaxY: hi.Y,
+		})
+
+	case "legend":
+		// The glyph may itself be a space, so only the single space after
+		// the colon is skipped.
+		rest := strings.TrimPrefix(line[strings.Index(line, ":")+1:], " ")
+		if len(rest) < 3 || rest[1] != ' ' {
+			return fmt.Errorf("legend: expected \"glyph tile\"")
+		}
+		name := strings.TrimSpace(rest[2:])
+		t, ok := domain.TileByName(name)
+		if !ok {
+			return fmt.Errorf("legend: unknown tile %q", name)
+		}
+		legend[rest[0]] = t.Glyph
+
+	default:
+		return fmt.Errorf("unknown header key %q", key)
+	}
+	return nil
+}
+
+func parsePoint(s string) (domain.Point, error) {
+	xs, ys, ok := strings.Cut(s, ",")
+	if !ok {
+		return domain.Point{}, fmt.Errorf("invalid point %q", s)
+	}
+	x, err := strconv.Atoi(strings.TrimSpace(xs))
+	if err != nil {
+		return domain.Point{}, fmt.Errorf("invalid point %q", s)
+	}
+	y, err := strconv.Atoi(strings.TrimSpace(ys))
+	if err != nil {
+		return domain.Point{}, fmt.Errorf("invalid point %q", s)
+	}
+	return domain.Point{X: x, Y: y}, nil
+}
+
+func applyLegend(world *domain.World, legend map[byte]byte) error {
+	for y, row := range world.Layout {
+		for x, g := range row {
+			if t, ok := legend[g]; ok {
+				row[x] = t
+				continue
+			}
+			if _, ok := domain.LookupTile(g); !ok {
+				return fmt.Errorf("unknown glyph %q at %d,%d", g, x, y)
+			}
+		}
+	}
+	return nil
+}
+
+func validate(world *domain.World) error {
+	if len(world.Layout) == 0 {
+		return fmt.Errorf("layout is empty")
+	}
+	width := len(world.Layout[0])
+	if width == 0 {
+		return fmt.Errorf("layout row 1 is empty")
+	}
+	for y, row := range world.Layout {
+		if len(row) != width {
+			return fmt.Errorf("layout row %d has %d columns, expected %d", y+1, len(row), width)
+		}
+	}
+	world.Width = width
+	world.Height = len(world.Layout)
+
+	walkable := func(what string, x, y int) error {
+		if !world.InBounds(x, y) {
+			return fmt.Errorf("%s %d,%d is outside the %dx%d layout", what, x, y, world.Width, world.Height)
+		}
+		if !world.TileAt(x, y).Walkable {
+			return fmt.Errorf("%s %d,%d is not walkable", what, x, y)
+		}
+		return nil
+	}
+	for _, p := range world.SpawnPoints {
+		if err := walkable("spawn", p.X, p.Y); err != nil {
+			return err
+		}
+	}
+	for _, p := range world.Portals {
+		if err := walkable("portal", p.X, p.Y); err != nil {
+			return err
+		}
+	}
+	for _, z := range world.SpawnZones {
+		if z.MinX > z.MaxX || z.MinY > z.MaxY {
+			return fmt.Errorf("zone %s has its corners swapped", z.ID)
+		}
+		if !world.InBounds(z.MinX, z.MinY) || !world.InBounds(z.MaxX, z.MaxY) {
+			return fmt.Errorf("zone %s is outside the %dx%d layout", z.ID, world.Width, world.Height)
+		}
+	}
+	for _, a := range world.Arenas {
+		if a.MinX > a.MaxX || a.MinY > a.MaxY {
+			return fmt.Errorf("arena %s has its corners swapped", a.ID)
+		}
+		if !world.InBounds(a.MinX, a.MinY) || !world.InBounds(a.MaxX, a.MaxY) {
+			return fmt.Errorf("arena %s is outside the %dx%d layout", a.ID, world.Width, world.Height)
+		}
+		if !a.Contains(a.Spawn.X, a.Spawn.Y) {
+			return fmt.Errorf("arena %s spawns its boss outside of it", a.ID)
+		}
+		if err := walkable("arena spawn", a.Spawn.X, a.Spawn.Y); err != nil {
+			return err
+		}
+	}
+	for _, r := range world.Regions {
+		if r.MinX > r.MaxX || r.MinY > r.MaxY {
+			return fmt.Errorf("region %s has its corners swapped", r.ID)
+		}
+		if !world.InBounds(r.MinX, r.MinY) || !world.InBounds(r.MaxX, r.MaxY) {
+			return fmt.Errorf("region %s is outside the %dx%d layout", r.ID, world.Width, world.Height)
+		}
+	}
+	for i, n := range world.NPCs {
+		if err := walkable("npc", n.X, n.Y); err != nil {
+			return err
+		}
+		for _, other := range world.NPCs[:i] {
+			if other.X == n.X && other.Y == n.Y {
+				return fmt.Errorf("npcs %s and %s stand on the same cell", other.ID, n.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidatePortals checks that every portal leads to a walkable cell of a
+// known world.
+func ValidatePortals(worlds map[string]*domain.World) error {
+	for _, w := range worlds {
+		for _, p := range w.Portals {
+			target, ok := worlds[p.ToWorld]
+			if !ok {
+				return fmt.Errorf("%s: portal %d,%d leads to unknown world %q", w.ID, p.X, p.Y, p.ToWorld)
+			}
+			if !target.TileAt(p.ToX, p.ToY).Walkable {
+				return fmt.Errorf("%s: portal %d,%d leads to a blocked cell %d,%d of %s", w.ID, p.X, p.Y, p.ToX, p.ToY, p.ToWorld)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadFile parses the map file at path.
+func LoadFile(path string) (*domain.World, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(strings.TrimSuffix(filepath.Base(path), Ext), f)
+}
+
+// LoadDir parses every map file in dir and validates the portals between them.
+func LoadDir(dir string) (map[string]*domain.World, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+Ext))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	worlds := make(map[string]*domain.World)
+	for _, path := range paths {
+		w, err := LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		worlds[w.ID] = w
+	}
+	if err := ValidatePortals(worlds); err != nil {
+		return nil, err
+	}
+	return worlds, nil
+}
+
+// Write encodes world in the map file format.
+func Write(w io.Writer, world *domain.World) error {
+	bw := bufio.NewWriter(w)
+	if world.Name != "" {
+		fmt.Fprintf(bw, "name: %s\n", world.Name)
+	}
+	for _, p := range world.SpawnPoints {
+		fmt.Fprintf(bw, "spawn: %d,%d\n", p.X, p.Y)
+	}
+	for _, p := range world.Portals {
+		fmt.Fprintf(bw, "portal: %d,%d -> %s %d,%d\n", p.X, p.Y, p.ToWorld, p.ToX, p.ToY)
+	}
+	for _, z := range world.SpawnZones {
+		fmt.Fprintf(bw, "zone: %s %d,%d %d,%d %d\n", z.MobType, z.MinX, z.MinY, z.MaxX, z.MaxY, z.Cap)
+	}
+	for _, a := range world.Arenas {
+		fmt.Fprintf(bw, "arena: %s %d,%d %d,%d %d,%d\n", a.Boss, a.MinX, a.MinY, a.MaxX, a.MaxY, a.Spawn.X, a.Spawn.Y)
+	}
+	for _, n := range world.NPCs {
+		fmt.Fprintf(bw, "npc: %s %d,%d\n", n.NPC, n.X, n.Y)
+	}
+	for _, r := range world.Regions {
+		fmt.Fprintf(bw, "region: %s %d,%d %d,%d\n", r.Kind, r.MinX, r.MinY, r.MaxX, r.MaxY)
+	}
+	fmt.Fprintln(bw, separator)
+	for _, row := range world.Layout {
+		bw.Write(row)
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
@@ -2,9 +2,9 @@ package server
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"log/slog"
 	"net"
+	"os"
 	"sync"
 )
 
@@ -15,22 +15,26 @@ type ConnHandler interface {
 type Server struct {
 	Port    string
 	Handler ConnHandler
+	// Log is the logger of the server subsystem.
+	Log *slog.Logger
 }
 
 func NewServer(port string, handler ConnHandler) *Server {
 	return &Server{
 		Port:    port,
 		Handler: handler,
+		Log:     slog.Default().With("subsystem", "server"),
 	}
 }
 
 func (s *Server) Start(ctx context.Context) {
 	ln, err := net.Listen("tcp", s.Port)
 	if err != nil {
-		log.Fatalf("unable to start server")
+		s.Log.Error("unable to start server", "addr", s.Port, "err", err)
+		os.Exit(1)
 	}
 
-	fmt.Printf("listenner started on port %s \n", s.Port)
+	s.Log.Info("listener started", "addr", s.Port)
 
 	var wg sync.WaitGroup
 
@@ -38,7 +42,7 @@ func (s *Server) Start(ctx context.Context) {
 		for {
 			conn, err := ln.Accept()
 			if err != nil {
-				log.Printf("error accepting connection: %v", err)
+				s.Log.Error("error accepting connection", "err", err)
 				return
 			}
 
@@ -52,7 +56,7 @@ func (s *Server) Start(ctx context.Context) {
 
 	<-ctx.Done()
 
-	fmt.Println("\nShutting down the server...")
+	s.Log.Info("shutting down the server")
 	wg.Wait()
-	fmt.Print("Server gracefully stopped")
+	s.Log.Info("server gracefully stopped")
 }
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+// PlayerBackend keeps players across restarts, such as PlayerPgStore.
+type PlayerBackend interface {
+	// GetPlayer returns the player id, or nil when it was never saved.
+	GetPlayer(ctx context.Context, id string) (*domain.Player, error)
+	SavePlayers(ctx context.Context, players ...*domain.Player) error
+}
+
+// CachedPlayerStore is a durable player store: the game plays with the
+// players held in memory, which are loaded from the backend when they join
+// and written back to it by SavePlayers only.
+type CachedPlayerStore struct {
+	backend PlayerBackend
+	// Timeout bounds each call to the backend.
+	Timeout time.Duration
+
+	players map[string]*domain.Player
+	mu      sync.RWMutex
+}
+
+func NewCachedPlayerStore(backend PlayerBackend) *CachedPlayerStore {
+	return &CachedPlayerStore{
+		backend: backend,
+		Timeout: 5 * time.Second,
+		players: make(map[string]*domain.Player),
+	}
+}
+
+// Durable reports true: saved players are kept by the backend.
+func (cs *CachedPlayerStore) Durable() bool { return true }
+
+func (cs *CachedPlayerStore) GetPlayer(id string) *domain.Player {
+	defer observe("cached", "GetPlayer", time.Now(), nil)
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.players[id]
+}
+
+// LoadPlayer returns the player id from memory, or else from the backend.
+func (cs *CachedPlayerStore) LoadPlayer(id string) (*domain.Player, error) {
+	if p := cs.GetPlayer(id); p != nil {
+		return p, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cs.Timeout)
+	defer cancel()
+	p, err := cs.backend.GetPlayer(ctx, id)
+	if err != nil || p == nil {
+		return nil, err
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	// Another connection may have loaded it meanwhile: keep theirs.
+	if loaded := cs.players[id]; loaded != nil {
+		return loaded, nil
+	}
+	cs.players[id] = p
+	return p, nil
+}
+
+// SavePlayer keeps the player in memory until the next SavePlayers.
+func (cs *CachedPlayerStore) SavePlayer(player *domain.Player) {
+	defer observe("cached", "SavePlayer", time.Now(), nil)
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.players[player.ID] = player
+}
+
+// SavePlayers writes the players through to the backend.
+func (cs *CachedPlayerStore) SavePlayers(players ...*domain.Player) error {
+	cs.mu.Lock()
+	for _, player := range players {
+		cs.players[player.ID] = player
+	}
+	cs.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cs.Timeout)
+	defer cancel()
+	return cs.backend.SavePlayers(ctx, players...)
+}
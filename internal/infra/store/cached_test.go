@@ -0,0 +1,62 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/LealKevin/terminus/internal/domain"
+	"github.com/LealKevin/terminus/internal/infra/store"
+)
+
+// fakeBackend keeps copies of the players saved, as a database would.
+type fakeBackend struct {
+	players map[string]domain.Player
+	saves   int
+}
+
+func (b *fakeBackend) GetPlayer(_ context.Context, id string) (*domain.Player, error) {
+	p, ok := b.players[id]
+	if !ok {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+func (b *fakeBackend) SavePlayers(_ context.Context, players ...*domain.Player) error {
+	b.saves++
+	for _, p := range players {
+		b.players[p.ID] = *p
+	}
+	return nil
+}
+
+func TestCachedPlayerStoreWritesThroughOnSavePlayers(t *testing.T) {
+	backend := &fakeBackend{players: map[string]domain.Player{
+		"alice": {ID: "alice", WorldID: "world1", Gold: 30},
+	}}
+	cs := store.NewCachedPlayerStore(backend)
+
+	if p := cs.GetPlayer("alice"); p != nil {
+		t.Fatalf("GetPlayer returned %+v before alice was loaded", p)
+	}
+	alice, err := cs.LoadPlayer("alice")
+	if err != nil || alice == nil || alice.Gold != 30 {
+		t.Fatalf("LoadPlayer(alice) = %+v, %v, want alice with 30 gold", alice, err)
+	}
+	if again, _ := cs.LoadPlayer("alice"); again != alice {
+		t.Error("loading alice twice returned two players")
+	}
+	if bob, err := cs.LoadPlayer("bob"); bob != nil || err != nil {
+		t.Errorf("LoadPlayer(bob) = %+v, %v, want nil, nil", bob, err)
+	}
+
+	alice.Gold = 50
+	cs.SavePlayer(alice)
+	if backend.saves != 0 || backend.players["alice"].Gold != 30 {
+		t.Fatalf("SavePlayer wrote to the backend")
+	}
+	cs.SavePlayers(alice)
+	if backend.players["alice"].Gold != 50 {
+		t.Errorf("backend gold = %d after SavePlayers, want 50", backend.players["alice"].Gold)
+	}
+}
@@ -1,8 +1,9 @@
 package store
 
 import (
-	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/LealKevin/terminus/internal/domain"
 )
@@ -25,16 +26,14 @@ type MobMemoryStore struct {
 func NewPlayerMemoryStore() *PlayerMemoryStore {
 	return &PlayerMemoryStore{
 		players: map[string]*domain.Player{
-			"1": {ID: "1", WorldID: "world1", X: 2, Y: 2, Health: 100, Attack: 50, Defense: 5, Range: 1},
+			"1": {ID: "1", WorldID: "world1", X: 2, Y: 2, Health: 100, MaxHealth: 100, Level: 1, Mana: 50, MaxMana: 50, Attack: 50, Defense: 5, Range: 1},
 		},
 	}
 }
 
 func NewWorldMemoryStore() *WorldMemoryStore {
 	return &WorldMemoryStore{
-		worlds: map[string]*domain.World{
-			"world1": {ID: "world1", Width: 53, Height: 25, Layout: domain.ConvertLayout(domain.Raw)},
-		},
+		worlds: make(map[string]*domain.World),
 	}
 }
 
@@ -45,6 +44,7 @@ func NewMobMemoryStore() *MobMemoryStore {
 }
 
 func (ms *WorldMemoryStore) NewWorld(id string, width, height int, layout domain.Layout) *domain.World {
+	defer observe("memory", "NewWorld", time.Now(), nil)
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 	world := domain.NewWorld(id, width, height, layout)
@@ -52,49 +52,79 @@ func (ms *WorldMemoryStore) NewWorld(id string, width, height int, layout domain
 	return world
 }
 
+func (ms *WorldMemoryStore) SaveWorld(world *domain.World) {
+	defer observe("memory", "SaveWorld", time.Now(), nil)
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.worlds[world.ID] = world
+}
+
 func (ms *WorldMemoryStore) GetWorld(id string) *domain.World {
+	defer observe("memory", "GetWorld", time.Now(), nil)
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 	return ms.worlds[id]
 }
 
 func (ms *PlayerMemoryStore) GetPlayer(id string) *domain.Player {
+	defer observe("memory", "GetPlayer", time.Now(), nil)
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 	return ms.players[id]
 }
 
+// LoadPlayer returns the player id, which is only ever kept in memory.
+func (ms *PlayerMemoryStore) LoadPlayer(id string) (*domain.Player, error) {
+	return ms.GetPlayer(id), nil
+}
+
 func (ms *PlayerMemoryStore) SavePlayer(player *domain.Player) {
+	defer observe("memory", "SavePlayer", time.Now(), nil)
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 	ms.players[player.ID] = player
 }
 
+func (ms *PlayerMemoryStore) SavePlayers(players ...*domain.Player) error {
+	defer observe("memory", "SavePlayers", time.Now(), nil)
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for _, player := range players {
+		ms.players[player.ID] = player
+	}
+	return nil
+}
+
 func (ms *MobMemoryStore) GetMob(id string) *domain.Mob {
+	defer observe("memory", "GetMob", time.Now(), nil)
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 	return ms.mobs[id]
 }
 
 func (ms *MobMemoryStore) SaveMob(mob *domain.Mob) {
+	defer observe("memory", "SaveMob", time.Now(), nil)
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 	ms.mobs[mob.ID] = mob
 }
 
 func (ms *MobMemoryStore) CreateMob(mob *domain.Mob) {
+	defer observe("memory", "CreateMob", time.Now(), nil)
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 	ms.mobs[mob.ID] = mob
 }
 
 func (ms *MobMemoryStore) DeleteMob(id string) {
+	defer observe("memory", "DeleteMob", time.Now(), nil)
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 	delete(ms.mobs, id)
 }
 
 func (ms *MobMemoryStore) CountMobsInWorld(worldID string) int {
+	defer observe("memory", "CountMobsInWorld", time.Now(), nil)
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 	count := 0
@@ -106,7 +136,10 @@ func (ms *MobMemoryStore) CountMobsInWorld(worldID string) int {
 	return count
 }
 
+// GetMobsByWorld returns the mobs in the world ordered by ID, so that the
+// game loop handles them in the same order on every run.
 func (ms *MobMemoryStore) GetMobsByWorld(worldID string) []*domain.Mob {
+	defer observe("memory", "GetMobsByWorld", time.Now(), nil)
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 	var mobs []*domain.Mob
@@ -115,6 +148,6 @@ func (ms *MobMemoryStore) GetMobsByWorld(worldID string) []*domain.Mob {
 			mobs = append(mobs, mob)
 		}
 	}
-	fmt.Printf("Mobs in world %s: %+v\n", worldID, mobs)
+	sort.Slice(mobs, func(i, j int) bool { return mobs[i].ID < mobs[j].ID })
 	return mobs
 }
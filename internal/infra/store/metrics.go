@@ -0,0 +1,24 @@
+package store
+
+import (
+	"time"
+
+	"github.com/LealKevin/terminus/internal/metrics"
+)
+
+var (
+	opDuration = metrics.NewHistogramVec("terminus_store_operation_duration_seconds",
+		"Time taken by store operations, by backend and operation.",
+		metrics.ExponentialBuckets(.00001, 4, 9), "backend", "op")
+	opErrors = metrics.NewCounterVec("terminus_store_errors_total",
+		"Store operations that failed, by backend and operation.", "backend", "op")
+)
+
+// observe records a store operation begun at start. It is deferred by the
+// operations, with the address of their error result when they have one.
+func observe(backend, op string, start time.Time, err *error) {
+	opDuration.With(backend, op).Since(start)
+	if err != nil && *err != nil {
+		opErrors.With(backend, op).Inc()
+	}
+}
@@ -2,17 +2,26 @@ package store
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"log/slog"
+	"time"
 
 	"github.com/LealKevin/terminus/internal/domain"
 	"github.com/LealKevin/terminus/internal/infra/db"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+// TxBeginner starts transactions, like *pgx.Conn and *pgxpool.Pool.
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
 type PlayerPgStore struct {
 	db *db.Queries
+	// conn runs the transactions of SavePlayers.
+	conn TxBeginner
 }
 
 type WorldPgStore struct {
@@ -23,9 +32,12 @@ type MobPgStore struct {
 	db *db.Queries
 }
 
-func NewPlayerPgStore(q *db.Queries) *PlayerPgStore {
+// NewPlayerPgStore returns a store running q, and its transactions on conn,
+// usually the connection q was made from.
+func NewPlayerPgStore(q *db.Queries, conn TxBeginner) *PlayerPgStore {
 	return &PlayerPgStore{
-		db: q,
+		db:   q,
+		conn: conn,
 	}
 }
 
@@ -41,8 +53,19 @@ func NewMobPgStore(q *db.Queries) *MobPgStore {
 	}
 }
 
-func (ms *WorldPgStore) CreateWorld(ctx context.Context, id string, width, height int, layout string) (*domain.World, error) {
+// CreateWorld stores a new world. Worlds are keyed by UUID in Postgres, so an
+// id that is not a UUID is replaced by a fresh one.
+func (ms *WorldPgStore) CreateWorld(ctx context.Context, id string, width, height int, layout string) (_ *domain.World, err error) {
+	defer observe("postgres", "CreateWorld", time.Now(), &err)
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		uid = uuid.New()
+	}
 	world, err := ms.db.CreateWorld(ctx, db.CreateWorldParams{
+		ID: pgtype.UUID{
+			Bytes: uid,
+			Valid: true,
+		},
 		Width:  int32(width),
 		Height: int32(height),
 		Layout: layout,
@@ -59,14 +82,15 @@ func (ms *WorldPgStore) CreateWorld(ctx context.Context, id string, width, heigh
 	}, nil
 }
 
-func (ms *WorldPgStore) GetWorld(ctx context.Context, id string) (*domain.World, error) {
+func (ms *WorldPgStore) GetWorld(ctx context.Context, id string) (_ *domain.World, err error) {
+	defer observe("postgres", "GetWorld", time.Now(), &err)
 	uid, err := uuid.Parse(id)
 	world, err := ms.db.GetWorldByID(ctx, pgtype.UUID{
 		Bytes: uid,
 		Valid: true,
 	})
 	if err != nil {
-		slog.Error("error getting world", "err", err)
+		slog.Default().With("subsystem", "store").Error("error getting world", "world", id, "err", err)
 		return nil, err
 	}
 	return &domain.World{
@@ -77,112 +101,244 @@ func (ms *WorldPgStore) GetWorld(ctx context.Context, id string) (*domain.World,
 	}, nil
 }
 
-func (ms *PlayerPgStore) GetPlayer(ctx context.Context, id string) (*domain.Player, error) {
-	uid, err := uuid.Parse(id)
+// GetPlayer returns the player id, or nil when it was never saved.
+func (ms *PlayerPgStore) GetPlayer(ctx context.Context, id string) (_ *domain.Player, err error) {
+	defer observe("postgres", "GetPlayer", time.Now(), &err)
+	row, err := ms.db.GetPlayerByID(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	player, err := ms.db.GetPlayerByID(ctx, pgtype.UUID{
-		Bytes: uid,
-		Valid: true,
-	})
+	inventory, err := ms.getInventory(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	return &domain.Player{
-		ID:      player.ID.String(),
-		WorldID: player.WorldID.String(),
-		X:       int(player.X),
-		Y:       int(player.Y),
-	}, nil
-}
-
-func (ms *PlayerPgStore) SavePlayer(ctx context.Context, player *domain.Player) (*domain.Player, error) {
-	uid, err := uuid.Parse(player.ID)
+	explored, err := ms.getExplored(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	quests, err := ms.getQuests(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	worldUID, err := uuid.Parse(player.WorldID)
+	cooldowns, err := ms.getCooldowns(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	return &domain.Player{
+		ID:      row.ID,
+		WorldID: row.WorldID,
+		X:       int(row.X),
+		Y:       int(row.Y),
+		Health:  int(row.Health),
+		Attack:  int(row.Attack),
+		Defense: int(row.Defense),
+		Range:   int(row.Range),
 
-	data, err := ms.db.UpdatePlayer(ctx, db.UpdatePlayerParams{
-		WorldID: pgtype.UUID{
-			Bytes: worldUID,
-			Valid: true,
-		},
-		X: int32(player.X),
-		Y: int32(player.Y),
-		ID: pgtype.UUID{
-			Bytes: uid,
-			Valid: true,
-		},
-		Attack:  int32(player.Attack),
+		MaxHealth: int(row.MaxHealth),
+		Level:     int(row.Level),
+		XP:        int(row.Xp),
+		Mana:      int(row.Mana),
+		MaxMana:   int(row.MaxMana),
+		Gold:      int(row.Gold),
+		PvPKills:  int(row.PvpKills),
+		DuelsWon:  int(row.DuelsWon),
+		Role:      domain.Role(row.Role),
+		Inventory: inventory,
+		Quests:    quests,
+		Cooldowns: cooldowns,
+		Explored:  explored,
+
+		SecretHash: row.SecretHash,
+	}, nil
+}
+
+// SavePlayer stores the player, creating it on first save, with their items,
+// quest progress, ability cooldowns and what they explored.
+func (ms *PlayerPgStore) SavePlayer(ctx context.Context, player *domain.Player) (err error) {
+	defer observe("postgres", "SavePlayer", time.Now(), &err)
+	err = ms.db.UpsertPlayer(ctx, db.UpsertPlayerParams{
+		ID:      player.ID,
+		WorldID: player.WorldID,
+		X:       int32(player.X),
+		Y:       int32(player.Y),
 		Health:  int32(player.Health),
+		Attack:  int32(player.Attack),
 		Defense: int32(player.Defense),
 		Range:   int32(player.Range),
+
+		MaxHealth: int32(player.MaxHealth),
+		Level:     int32(player.Level),
+		Xp:        int32(player.XP),
+		Mana:      int32(player.Mana),
+		MaxMana:   int32(player.MaxMana),
+		Gold:      int32(player.Gold),
+		PvpKills:  int32(player.PvPKills),
+		DuelsWon:  int32(player.DuelsWon),
+		Role:      string(player.Role),
+
+		SecretHash: player.SecretHash,
 	})
+	if err != nil {
+		return err
+	}
+	if err := ms.saveInventory(ctx, player.ID, player.Inventory); err != nil {
+		return err
+	}
+	if err := ms.saveQuests(ctx, player.ID, player.Quests); err != nil {
+		return err
+	}
+	if err := ms.saveCooldowns(ctx, player.ID, player.Cooldowns); err != nil {
+		return err
+	}
+	return ms.saveExplored(ctx, player.ID, player.Explored)
+}
+
+// getInventory returns how many of each item the player carries.
+func (ms *PlayerPgStore) getInventory(ctx context.Context, playerID string) (map[string]int, error) {
+	rows, err := ms.db.GetPlayerItems(ctx, playerID)
 	if err != nil {
 		return nil, err
 	}
-	return &domain.Player{
-		ID:      data.ID.String(),
-		WorldID: data.WorldID.String(),
-		X:       int(data.X),
-		Y:       int(data.Y),
-		Attack:  int(data.Attack),
-		Health:  int(data.Health),
-		Defense: int(data.Defense),
-		Range:   int(data.Range),
-	}, nil
+	inventory := make(map[string]int, len(rows))
+	for _, row := range rows {
+		inventory[row.ItemID] = int(row.Count)
+	}
+	return inventory, nil
 }
 
-func (ms *MobMemoryStore) GetMob(id string) *domain.Mob {
-	ms.mu.RLock()
-	defer ms.mu.RUnlock()
-	return ms.mobs[id]
+// saveInventory stores the player's items, removing the stored ones they no
+// longer carry.
+func (ms *PlayerPgStore) saveInventory(ctx context.Context, playerID string, inventory map[string]int) error {
+	stored, err := ms.db.GetPlayerItems(ctx, playerID)
+	if err != nil {
+		return err
+	}
+	for _, row := range stored {
+		if inventory[row.ItemID] > 0 {
+			continue
+		}
+		err = ms.db.DeletePlayerItem(ctx, db.DeletePlayerItemParams{PlayerID: playerID, ItemID: row.ItemID})
+		if err != nil {
+			return err
+		}
+	}
+	for itemID, count := range inventory {
+		if count <= 0 {
+			continue
+		}
+		err = ms.db.UpsertPlayerItem(ctx, db.UpsertPlayerItemParams{PlayerID: playerID, ItemID: itemID, Count: int32(count)})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (ms *MobMemoryStore) SaveMob(mob *domain.Mob) {
-	ms.mu.Lock()
-	defer ms.mu.Unlock()
-	ms.mobs[mob.ID] = mob
+// getCooldowns returns when each ability the player used is ready again.
+func (ms *PlayerPgStore) getCooldowns(ctx context.Context, playerID string) (map[string]time.Time, error) {
+	rows, err := ms.db.GetPlayerCooldowns(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	cooldowns := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		cooldowns[row.AbilityID] = row.ReadyAt.Time
+	}
+	return cooldowns, nil
 }
 
-func (ms *MobMemoryStore) CreateMob(mob *domain.Mob) {
-	ms.mu.Lock()
-	defer ms.mu.Unlock()
-	ms.mobs[mob.ID] = mob
+func (ms *PlayerPgStore) saveCooldowns(ctx context.Context, playerID string, cooldowns map[string]time.Time) error {
+	for abilityID, readyAt := range cooldowns {
+		err := ms.db.UpsertPlayerCooldown(ctx, db.UpsertPlayerCooldownParams{
+			PlayerID:  playerID,
+			AbilityID: abilityID,
+			ReadyAt:   pgtype.Timestamptz{Time: readyAt, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (ms *MobMemoryStore) DeleteMob(id string) {
-	ms.mu.Lock()
-	defer ms.mu.Unlock()
-	delete(ms.mobs, id)
+// getQuests returns the player's progress in the quests they took.
+func (ms *PlayerPgStore) getQuests(ctx context.Context, playerID string) (map[string]domain.QuestProgress, error) {
+	rows, err := ms.db.GetPlayerQuests(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	quests := make(map[string]domain.QuestProgress, len(rows))
+	for _, row := range rows {
+		quests[row.QuestID] = domain.QuestProgress{
+			Step:      int(row.Step),
+			Count:     int(row.Count),
+			Completed: row.Completed,
+		}
+	}
+	return quests, nil
 }
 
-func (ms *MobMemoryStore) CountMobsInWorld(worldID string) int {
-	ms.mu.RLock()
-	defer ms.mu.RUnlock()
-	count := 0
-	for _, mob := range ms.mobs {
-		if mob.WorldID == worldID {
-			count++
+func (ms *PlayerPgStore) saveQuests(ctx context.Context, playerID string, quests map[string]domain.QuestProgress) error {
+	for questID, pr := range quests {
+		err := ms.db.UpsertPlayerQuest(ctx, db.UpsertPlayerQuestParams{
+			PlayerID:  playerID,
+			QuestID:   questID,
+			Step:      int32(pr.Step),
+			Count:     int32(pr.Count),
+			Completed: pr.Completed,
+		})
+		if err != nil {
+			return err
 		}
 	}
-	return count
+	return nil
 }
 
-func (ms *MobMemoryStore) GetMobsByWorld(worldID string) []*domain.Mob {
-	ms.mu.RLock()
-	defer ms.mu.RUnlock()
-	var mobs []*domain.Mob
-	for _, mob := range ms.mobs {
-		if mob.WorldID == worldID {
-			mobs = append(mobs, mob)
+// getExplored returns the cells the player explored in each world.
+func (ms *PlayerPgStore) getExplored(ctx context.Context, playerID string) (domain.Explored, error) {
+	rows, err := ms.db.GetPlayerExplored(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	explored := make(domain.Explored, len(rows))
+	for _, row := range rows {
+		explored[row.WorldID] = row.Cells
+	}
+	return explored, nil
+}
+
+func (ms *PlayerPgStore) saveExplored(ctx context.Context, playerID string, explored domain.Explored) error {
+	for worldID, cells := range explored {
+		err := ms.db.UpsertPlayerExplored(ctx, db.UpsertPlayerExploredParams{
+			PlayerID: playerID,
+			WorldID:  worldID,
+			Cells:    cells,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SavePlayers stores the players in a single transaction, so that a trade
+// is saved for both sides or not at all.
+func (ms *PlayerPgStore) SavePlayers(ctx context.Context, players ...*domain.Player) (err error) {
+	defer observe("postgres", "SavePlayers", time.Now(), &err)
+	tx, err := ms.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	txStore := &PlayerPgStore{db: ms.db.WithTx(tx)}
+	for _, player := range players {
+		if err := txStore.SavePlayer(ctx, player); err != nil {
+			return err
 		}
 	}
-	fmt.Printf("Mobs in world %s: %+v\n", worldID, mobs)
-	return mobs
+	return tx.Commit(ctx)
 }
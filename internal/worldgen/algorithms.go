@@ -0,0 +1,131 @@
+package worldgen
+
+import (
+	"math/rand"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+// generateRooms scatters non-overlapping rooms and links each one to the
+// previous with a corridor.
+func generateRooms(g *grid, rng *rand.Rand) {
+	maxRooms := g.w * g.h / 80
+	if maxRooms < 2 {
+		maxRooms = 2
+	}
+
+	var rooms []rect
+	for attempt := 0; attempt < maxRooms*5 && len(rooms) < maxRooms; attempt++ {
+		w := 3 + rng.Intn(min(10, g.w/3))
+		h := 3 + rng.Intn(min(6, g.h/3))
+		if w > g.w-2 || h > g.h-2 {
+			continue
+		}
+		r := rect{x: 1 + rng.Intn(g.w-w-1), y: 1 + rng.Intn(g.h-h-1), w: w, h: h}
+
+		overlapping := false
+		for _, other := range rooms {
+			if r.overlaps(other, 1) {
+				overlapping = true
+				break
+			}
+		}
+		if overlapping {
+			continue
+		}
+
+		g.carveRect(r)
+		if len(rooms) > 0 {
+			g.carveCorridor(rooms[len(rooms)-1].center(), r.center(), rng)
+		}
+		rooms = append(rooms, r)
+	}
+}
+
+// generateCaves seeds the map with noise and smooths it with a few rounds of
+// the 4-5 cellular automaton rule.
+func generateCaves(g *grid, rng *rand.Rand) {
+	const (
+		wallChance = 0.45
+		iterations = 5
+	)
+
+	for y := 0; y < g.h; y++ {
+		for x := 0; x < g.w; x++ {
+			if rng.Float64() >= wallChance {
+				g.carve(x, y)
+			}
+		}
+	}
+
+	for i := 0; i < iterations; i++ {
+		next := newGrid(g.w, g.h)
+		for y := 0; y < g.h; y++ {
+			for x := 0; x < g.w; x++ {
+				if g.wallNeighbours(x, y) < 5 {
+					next.carve(x, y)
+				}
+			}
+		}
+		g.cells = next.cells
+	}
+}
+
+// wallNeighbours counts the walls in the 3x3 block around (x, y), the cell
+// itself included. Cells outside the map count as walls.
+func (g *grid) wallNeighbours(x, y int) int {
+	n := 0
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			if !g.isFloor(x+dx, y+dy) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+const bspMinLeaf = 8
+
+// generateBSP recursively splits the map into leaves, places a room in each
+// leaf and joins sibling subtrees with corridors.
+func generateBSP(g *grid, rng *rand.Rand) {
+	g.splitBSP(rect{x: 1, y: 1, w: g.w - 2, h: g.h - 2}, rng)
+}
+
+// splitBSP carves the rooms of area and returns the center of one of them so
+// the caller can connect it to its sibling.
+func (g *grid) splitBSP(area rect, rng *rand.Rand) domain.Point {
+	splitH := area.w < area.h
+	if area.w >= 2*bspMinLeaf && area.h >= 2*bspMinLeaf {
+		splitH = rng.Intn(2) == 0
+	}
+
+	switch {
+	case splitH && area.h >= 2*bspMinLeaf:
+		cut := bspMinLeaf + rng.Intn(area.h-2*bspMinLeaf+1)
+		a := g.splitBSP(rect{x: area.x, y: area.y, w: area.w, h: cut}, rng)
+		b := g.splitBSP(rect{x: area.x, y: area.y + cut, w: area.w, h: area.h - cut}, rng)
+		g.carveCorridor(a, b, rng)
+		return a
+	case !splitH && area.w >= 2*bspMinLeaf:
+		cut := bspMinLeaf + rng.Intn(area.w-2*bspMinLeaf+1)
+		a := g.splitBSP(rect{x: area.x, y: area.y, w: cut, h: area.h}, rng)
+		b := g.splitBSP(rect{x: area.x + cut, y: area.y, w: area.w - cut, h: area.h}, rng)
+		g.carveCorridor(a, b, rng)
+		return a
+	}
+
+	// Leaf: a room leaving at least one wall cell on each side.
+	w := max(3, area.w-2-rng.Intn(max(1, area.w/3)))
+	h := max(3, area.h-2-rng.Intn(max(1, area.h/3)))
+	w, h = min(w, area.w-2), min(h, area.h-2)
+	room := rect{
+		x: area.x + 1 + rng.Intn(area.w-w-1),
+		y: area.y + 1 + rng.Intn(area.h-h-1),
+		w: w,
+		h: h,
+	}
+	g.carveRect(room)
+	return room.center()
+}
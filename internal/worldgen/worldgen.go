@@ -0,0 +1,232 @@
+// Package worldgen builds world layouts procedurally. Generation is fully
+// determined by Options, so the same seed always yields the same world.
+package worldgen
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/LealKevin/terminus/internal/domain"
+)
+
+const (
+	AlgorithmRooms = "rooms"
+	AlgorithmCaves = "caves"
+	AlgorithmBSP   = "bsp"
+)
+
+var Algorithms = []string{AlgorithmRooms, AlgorithmCaves, AlgorithmBSP}
+
+type Options struct {
+	ID        string
+	Name      string
+	Algorithm string
+	Width     int
+	Height    int
+	Seed      int64
+
+	// SpawnPoints is the number of player spawn points to place.
+	SpawnPoints int
+	// Portals are placed on random floor cells; their X and Y are ignored.
+	Portals []domain.Portal
+}
+
+type generator func(g *grid, rng *rand.Rand)
+
+var generators = map[string]generator{
+	AlgorithmRooms: generateRooms,
+	AlgorithmCaves: generateCaves,
+	AlgorithmBSP:   generateBSP,
+}
+
+// Generate builds a world whose walkable cells form a single connected region.
+func Generate(opts Options) (*domain.World, error) {
+	gen, ok := generators[opts.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unknown algorithm %q", opts.Algorithm)
+	}
+	if opts.Width < 10 || opts.Height < 10 {
+		return nil, fmt.Errorf("world must be at least 10x10, got %dx%d", opts.Width, opts.Height)
+	}
+	if opts.ID == "" {
+		return nil, fmt.Errorf("world id is required")
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	g := newGrid(opts.Width, opts.Height)
+	gen(g, rng)
+	g.keepLargestRegion()
+
+	floor := g.floorCells()
+	needed := opts.SpawnPoints + len(opts.Portals)
+	if len(floor) < needed+1 {
+		return nil, fmt.Errorf("generated world has %d floor cells, need at least %d", len(floor), needed+1)
+	}
+	rng.Shuffle(len(floor), func(i, j int) { floor[i], floor[j] = floor[j], floor[i] })
+
+	world := domain.NewWorld(opts.ID, opts.Width, opts.Height, g.cells)
+	world.Name = opts.Name
+	if world.Name == "" {
+		world.Name = opts.ID
+	}
+	for i := 0; i < opts.SpawnPoints; i++ {
+		world.SpawnPoints = append(world.SpawnPoints, floor[i])
+	}
+	for i, p := range opts.Portals {
+		cell := floor[opts.SpawnPoints+i]
+		p.X, p.Y = cell.X, cell.Y
+		world.Portals = append(world.Portals, p)
+	}
+	return world, nil
+}
+
+type grid struct {
+	w, h  int
+	cells domain.Layout
+}
+
+func newGrid(w, h int) *grid {
+	cells := make(domain.Layout, h)
+	for y := range cells {
+		cells[y] = make([]byte, w)
+		for x := range cells[y] {
+			cells[y][x] = domain.TileWall
+		}
+	}
+	return &grid{w: w, h: h, cells: cells}
+}
+
+// interior reports whether (x, y) is inside the map, excluding the border
+// which always stays solid.
+func (g *grid) interior(x, y int) bool {
+	return x > 0 && y > 0 && x < g.w-1 && y < g.h-1
+}
+
+func (g *grid) carve(x, y int) {
+	if g.interior(x, y) {
+		g.cells[y][x] = domain.TileFloor
+	}
+}
+
+func (g *grid) isFloor(x, y int) bool {
+	return g.interior(x, y) && g.cells[y][x] == domain.TileFloor
+}
+
+func (g *grid) floorCells() []domain.Point {
+	var cells []domain.Point
+	for y := 0; y < g.h; y++ {
+		for x := 0; x < g.w; x++ {
+			if g.isFloor(x, y) {
+				cells = append(cells, domain.Point{X: x, Y: y})
+			}
+		}
+	}
+	return cells
+}
+
+// keepLargestRegion flood fills every walkable region and walls off all but
+// the largest one, so that every floor cell can reach every other.
+func (g *grid) keepLargestRegion() {
+	region := make([][]int, g.h)
+	for y := range region {
+		region[y] = make([]int, g.w)
+	}
+
+	largest, largestSize := 0, 0
+	next := 1
+	for y := 0; y < g.h; y++ {
+		for x := 0; x < g.w; x++ {
+			if !g.isFloor(x, y) || region[y][x] != 0 {
+				continue
+			}
+			size := g.flood(x, y, next, region)
+			if size > largestSize {
+				largest, largestSize = next, size
+			}
+			next++
+		}
+	}
+
+	for y := 0; y < g.h; y++ {
+		for x := 0; x < g.w; x++ {
+			if region[y][x] != largest {
+				g.cells[y][x] = domain.TileWall
+			}
+		}
+	}
+}
+
+// flood marks the 8-connected region containing (x, y), matching the
+// directions players and mobs can move in, and returns its size.
+func (g *grid) flood(x, y, id int, region [][]int) int {
+	stack := []domain.Point{{X: x, Y: y}}
+	region[y][x] = id
+	size := 0
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		size++
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				nx, ny := p.X+dx, p.Y+dy
+				if g.isFloor(nx, ny) && region[ny][nx] == 0 {
+					region[ny][nx] = id
+					stack = append(stack, domain.Point{X: nx, Y: ny})
+				}
+			}
+		}
+	}
+	return size
+}
+
+type rect struct {
+	x, y, w, h int
+}
+
+func (r rect) center() domain.Point {
+	return domain.Point{X: r.x + r.w/2, Y: r.y + r.h/2}
+}
+
+func (r rect) overlaps(o rect, margin int) bool {
+	return r.x-margin < o.x+o.w && o.x-margin < r.x+r.w &&
+		r.y-margin < o.y+o.h && o.y-margin < r.y+r.h
+}
+
+func (g *grid) carveRect(r rect) {
+	for y := r.y; y < r.y+r.h; y++ {
+		for x := r.x; x < r.x+r.w; x++ {
+			g.carve(x, y)
+		}
+	}
+}
+
+// carveCorridor digs an L-shaped corridor between a and b, randomly choosing
+// whether to go horizontally or vertically first.
+func (g *grid) carveCorridor(a, b domain.Point, rng *rand.Rand) {
+	corner := domain.Point{X: b.X, Y: a.Y}
+	if rng.Intn(2) == 0 {
+		corner = domain.Point{X: a.X, Y: b.Y}
+	}
+	g.carveLine(a, corner)
+	g.carveLine(corner, b)
+}
+
+func (g *grid) carveLine(a, b domain.Point) {
+	dx, dy := sign(b.X-a.X), sign(b.Y-a.Y)
+	for x, y := a.X, a.Y; ; x, y = x+dx, y+dy {
+		g.carve(x, y)
+		if x == b.X && y == b.Y {
+			return
+		}
+	}
+}
+
+func sign(v int) int {
+	switch {
+	case v < 0:
+		return -1
+	case v > 0:
+		return 1
+	}
+	return 0
+}